@@ -2,13 +2,21 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"os"
+	"path/filepath"
 	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/iyashjayesh/monigo/common"
 	"github.com/iyashjayesh/monigo/core"
+	"github.com/iyashjayesh/monigo/exporters"
+	"github.com/iyashjayesh/monigo/internal/promql"
 	"github.com/iyashjayesh/monigo/models"
 	"github.com/iyashjayesh/monigo/timeseries"
 )
@@ -43,7 +51,7 @@ func GetServiceStatistics(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(core.GetServiceStats(r.Context())); err != nil {
+	if err := json.NewEncoder(w).Encode(core.CachedServiceStats(r.Context())); err != nil {
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 	}
 }
@@ -111,12 +119,24 @@ func GetServiceMetricsFromStorage(w http.ResponseWriter, r *http.Request) {
 		startTime = serviceStartTime
 	}
 
+	step, err := resolveStep(req.Step, req.TimeFrame)
+	if err != nil {
+		http.Error(w, "Invalid step or time frame", http.StatusBadRequest)
+		return
+	}
+
+	agg, err := timeseries.ParseAggregation(req.Aggregation)
+	if err != nil {
+		http.Error(w, "Invalid aggregation", http.StatusBadRequest)
+		return
+	}
+
 	hostLabel := timeseries.GetHostLabel()
 
 	dataByTimestamp := make(map[int64]map[string]float64)
 
 	for _, fieldName := range req.FieldName {
-		datapoints, err := timeseries.GetDataPoints(fieldName, []timeseries.Label{hostLabel}, startTime.Unix(), endTime.Unix())
+		datapoints, err := timeseries.QueryRange(fieldName, []timeseries.Label{hostLabel}, startTime.Unix(), endTime.Unix(), step, agg)
 		if err != nil {
 			http.Error(w, "Failed to get data points", http.StatusInternalServerError)
 			return
@@ -202,11 +222,23 @@ func GetReportData(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	step, err := resolveStep(reqObj.Step, reqObj.TimeFrame)
+	if err != nil {
+		http.Error(w, "Invalid step or time frame", http.StatusBadRequest)
+		return
+	}
+
+	agg, err := timeseries.ParseAggregation(reqObj.Aggregation)
+	if err != nil {
+		http.Error(w, "Invalid aggregation", http.StatusBadRequest)
+		return
+	}
+
 	hostLabel := timeseries.GetHostLabel()
 
 	dataByTimestamp := make(map[int64]map[string]float64)
 	for _, fieldName := range fieldNameList {
-		datapoints, err := timeseries.GetDataPoints(fieldName, []timeseries.Label{hostLabel}, startTime.Unix(), endTime.Unix())
+		datapoints, err := timeseries.QueryRange(fieldName, []timeseries.Label{hostLabel}, startTime.Unix(), endTime.Unix(), step, agg)
 		if err != nil {
 			http.Error(w, "Failed to get data points", http.StatusInternalServerError)
 			return
@@ -239,6 +271,135 @@ func GetReportData(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// resolveStep returns the downsampling step in seconds. An explicit step wins; otherwise the
+// time frame (e.g. "1h", "7d") is split into timeseries.DefaultMaxPoints buckets.
+// Zero means no downsampling.
+func resolveStep(step, timeFrame string) (int64, error) {
+	if step != "" {
+		d, err := common.ParseDuration(step)
+		if err != nil {
+			return 0, err
+		}
+		if d < time.Second {
+			return 0, fmt.Errorf("step must be at least 1s, got %s", step)
+		}
+		return int64(d / time.Second), nil
+	}
+	if timeFrame != "" {
+		d, err := common.ParseDuration(timeFrame)
+		if err != nil {
+			return 0, err
+		}
+		if d <= 0 {
+			return 0, fmt.Errorf("time frame must be positive, got %s", timeFrame)
+		}
+		return timeseries.StepForRange(0, int64(d/time.Second), timeseries.DefaultMaxPoints), nil
+	}
+	return 0, nil
+}
+
+// QueryMetrics evaluates a PromQL-style expression over stored metrics and returns a
+// Prometheus-compatible matrix. It accepts a JSON body or form-encoded parameters
+// (query, start, end, step) as sent by Prometheus clients.
+func QueryMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req models.QueryRequest
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeQueryError(w, "Failed to decode request")
+			return
+		}
+	} else {
+		if err := r.ParseForm(); err != nil {
+			writeQueryError(w, "Failed to decode request")
+			return
+		}
+		req = models.QueryRequest{
+			Query: r.Form.Get("query"),
+			Start: r.Form.Get("start"),
+			End:   r.Form.Get("end"),
+			Step:  r.Form.Get("step"),
+		}
+	}
+
+	if strings.TrimSpace(req.Query) == "" {
+		writeQueryError(w, "query is required")
+		return
+	}
+
+	end, err := parseQueryTime(req.End, time.Now())
+	if err != nil {
+		writeQueryError(w, "Invalid end time")
+		return
+	}
+	start, err := parseQueryTime(req.Start, end.Add(-time.Hour))
+	if err != nil {
+		writeQueryError(w, "Invalid start time")
+		return
+	}
+
+	step := timeseries.StepForRange(start.Unix(), end.Unix(), timeseries.DefaultMaxPoints)
+	if req.Step != "" {
+		if step, err = parseQueryStep(req.Step); err != nil {
+			writeQueryError(w, "Invalid step")
+			return
+		}
+	}
+
+	series, err := promql.NewEngine().QueryRange(req.Query, start.Unix(), end.Unix(), step)
+	if err != nil {
+		writeQueryError(w, err.Error())
+		return
+	}
+
+	data := &models.QueryData{ResultType: "matrix", Result: make([]models.QuerySeries, 0, len(series))}
+	for _, s := range series {
+		values := make([]models.QuerySample, len(s.Points))
+		for i, p := range s.Points {
+			values[i] = models.QuerySample{Timestamp: p.Timestamp, Value: p.Value}
+		}
+		data.Result = append(data.Result, models.QuerySeries{Metric: s.Metric, Values: values})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(models.QueryResponse{Status: "success", Data: data}); err != nil {
+		http.Error(w, "Failed to encode query result", http.StatusInternalServerError)
+	}
+}
+
+// writeQueryError writes a Prometheus-style error envelope with status 400.
+func writeQueryError(w http.ResponseWriter, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	_ = json.NewEncoder(w).Encode(models.QueryResponse{Status: "error", ErrorType: "bad_data", Error: msg})
+}
+
+// parseQueryTime parses an RFC3339 timestamp or Unix seconds, returning def when empty.
+func parseQueryTime(value string, def time.Time) (time.Time, error) {
+	if value == "" {
+		return def, nil
+	}
+	if secs, err := strconv.ParseFloat(value, 64); err == nil {
+		return time.Unix(int64(secs), 0), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// parseQueryStep parses a duration ("30s", "1m") or a number of seconds.
+func parseQueryStep(value string) (int64, error) {
+	if secs, err := strconv.ParseFloat(value, 64); err == nil {
+		if secs < 1 {
+			return 0, fmt.Errorf("step must be at least 1s, got %s", value)
+		}
+		return int64(secs), nil
+	}
+	return resolveStep(value, "")
+}
+
 // GetFunctionTraceDetails returns the function trace details
 func GetFunctionTraceDetails(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -251,8 +412,78 @@ func GetFunctionTraceDetails(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// ViewFunctionMetrics returns detailed function metrics for a specific function
-// GET /monigo/api/v1/function-details?name=FunctionName&reportType=text
+// GetCustomMetrics returns the current value of every custom metric series, with the
+// query charting its stored history.
+func GetCustomMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	host := timeseries.GetHostLabel()
+	metrics := core.CustomMetrics().GetAll()
+	result := make([]models.CustomMetric, 0, len(metrics))
+	for _, m := range metrics {
+		cm := models.CustomMetric{
+			Name:      m.Name,
+			Type:      m.Type.String(),
+			Labels:    m.Labels,
+			Value:     m.Value,
+			UpdatedAt: m.Timestamp,
+		}
+		labels := exporters.StorageLabels(m.Labels, host)
+		if m.Histogram != nil {
+			cm.Count, cm.Sum = m.Histogram.Count, m.Histogram.Sum
+			cm.Query = selector(m.Name+"_sum", labels) + " / " + selector(m.Name+"_count", labels)
+		} else {
+			cm.Query = selector(m.Name, labels)
+		}
+		result = append(result, cm)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Name != result[j].Name {
+			return result[i].Name < result[j].Name
+		}
+		return result[i].Query < result[j].Query
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// selector returns a PromQL selector matching exactly the stored series. The name goes
+// in a __name__ matcher since custom metric names need not be valid identifiers.
+func selector(name string, labels []timeseries.Label) string {
+	quote := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	var b strings.Builder
+	fmt.Fprintf(&b, `{__name__="%s"`, quote.Replace(name))
+	for _, l := range labels {
+		fmt.Fprintf(&b, `,%s="%s"`, l.Name, quote.Replace(l.Value))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// GetStoredProfiles lists the profiles kept for sampled calls, newest first, optionally
+// of a single function.
+// GET /monigo/api/v1/profiles[?name=FunctionName]
+func GetStoredProfiles(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(core.StoredProfiles(r.URL.Query().Get("name"))); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// ViewFunctionMetrics returns detailed function metrics for a specific function, from the
+// profiles of its last profiled call or of the stored call given by profile.
+// GET /monigo/api/v1/function-details?name=FunctionName&reportType=text[&profile=ID]
 func ViewFunctionMetrics(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -277,8 +508,285 @@ func ViewFunctionMetrics(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if id := r.URL.Query().Get("profile"); id != "" {
+		rec, ok := core.StoredProfile(id)
+		if !ok || rec.FunctionName != name {
+			http.Error(w, "Profile not found", http.StatusNotFound)
+			return
+		}
+		metrics.CPUProfileFilePath, metrics.MemProfileFilePath = rec.CPUProfileFilePath, rec.MemProfileFilePath
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(core.ViewFunctionMetrics(name, reportType, metrics)); err != nil {
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 	}
 }
+
+// GetFlameGraph returns a flame graph of the CPU or heap profile of a traced function's
+// last or stored profiled call or, without a name, of a captured process profile or of the
+// continuous profiles between start and end. It never profiles the process itself: capture
+// a process profile with POST /process-profiles first. format is d3 (the default),
+// speedscope or collapsed.
+// GET /monigo/api/v1/flamegraph?name=FunctionName&type=cpu|mem[&profile=ID][&sample_type=T][&format=F]
+// GET /monigo/api/v1/flamegraph?profile=ID[&sample_type=T][&format=F]
+// GET /monigo/api/v1/flamegraph?type=cpu|heap&start=T&end=T[&sample_type=T][&format=F]
+func GetFlameGraph(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	name, kind, format := q.Get("name"), q.Get("type"), q.Get("format")
+	if !core.IsFlameGraphFormat(format) {
+		http.Error(w, "Unknown flame graph format", http.StatusBadRequest)
+		return
+	}
+	if name == "" && q.Get("profile") == "" && (q.Has("start") || q.Has("end")) {
+		getContinuousFlameGraph(w, r, kind, format)
+		return
+	}
+
+	var (
+		data  []byte
+		err   error
+		focus string
+		title = name
+	)
+	if name != "" {
+		metrics := core.FunctionTraceDetails()[name]
+		if metrics == nil {
+			http.Error(w, "Function not found", http.StatusNotFound)
+			return
+		}
+		cpuPath, memPath := metrics.CPUProfileFilePath, metrics.MemProfileFilePath
+		if id := q.Get("profile"); id != "" {
+			rec, ok := core.StoredProfile(id)
+			if !ok || rec.FunctionName != name {
+				http.Error(w, "Profile not found", http.StatusNotFound)
+				return
+			}
+			cpuPath, memPath = rec.CPUProfileFilePath, rec.MemProfileFilePath
+		}
+
+		var path string
+		switch kind {
+		case "cpu":
+			// The CPU profile may be shared with other functions.
+			path, focus = cpuPath, name
+		case "mem":
+			path = memPath
+		default:
+			http.Error(w, "type must be cpu or mem", http.StatusBadRequest)
+			return
+		}
+		if path == "" {
+			http.Error(w, "No profile recorded for the function", http.StatusNotFound)
+			return
+		}
+		if data, err = os.ReadFile(path); err != nil {
+			http.Error(w, "Profile not found", http.StatusNotFound)
+			return
+		}
+	} else if id := q.Get("profile"); id != "" {
+		rec, ok := core.StoredProcessProfile(id)
+		if !ok {
+			http.Error(w, "Profile not found", http.StatusNotFound)
+			return
+		}
+		if rec.Kind == core.ProcessProfileTrace {
+			http.Error(w, "Execution traces have no flame graph", http.StatusBadRequest)
+			return
+		}
+		title = rec.Kind
+		if data, err = os.ReadFile(rec.FilePath); err != nil {
+			http.Error(w, "Profile not found", http.StatusNotFound)
+			return
+		}
+	} else {
+		http.Error(w, "A function name, a profile ID or a start and end is required", http.StatusBadRequest)
+		return
+	}
+
+	out, err := core.FlameGraph(data, title, focus, q.Get("sample_type"), format)
+	writeFlameGraph(w, out, err, format)
+}
+
+// getContinuousFlameGraph serves the flame graph of the continuous CPU or heap profiles
+// between start and end, the last hour by default.
+func getContinuousFlameGraph(w http.ResponseWriter, r *http.Request, kind, format string) {
+	if kind != core.ProcessProfileCPU && kind != core.ProcessProfileHeap {
+		http.Error(w, "Unknown profile type", http.StatusBadRequest)
+		return
+	}
+	start, end, ok := parseContinuousWindow(w, r)
+	if !ok {
+		return
+	}
+	out, err := core.ContinuousFlameGraph(kind, start, end, r.URL.Query().Get("sample_type"), format)
+	writeFlameGraph(w, out, err, format)
+}
+
+func writeFlameGraph(w http.ResponseWriter, out []byte, err error, format string) {
+	switch {
+	case errors.Is(err, core.ErrNoSampleType):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, core.ErrNoContinuousProfiles):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, "Failed to build flame graph: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if format == core.FlameGraphCollapsed {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	_, _ = w.Write(out)
+}
+
+// parseContinuousWindow reads the start and end parameters, RFC3339 or Unix seconds,
+// defaulting to the last hour. It answers 400 and returns false when they're invalid.
+func parseContinuousWindow(w http.ResponseWriter, r *http.Request) (start, end time.Time, ok bool) {
+	q := r.URL.Query()
+	end, err := parseQueryTime(q.Get("end"), time.Now())
+	if err == nil {
+		start, err = parseQueryTime(q.Get("start"), end.Add(-time.Hour))
+	}
+	if err != nil || start.After(end) {
+		http.Error(w, "Invalid time window", http.StatusBadRequest)
+		return start, end, false
+	}
+	return start, end, true
+}
+
+// ContinuousProfiles lists the history of the continuous profiler between start and end,
+// the last hour by default, oldest first: the CPU cycles and the heap profiles taken at
+// their end.
+// GET /monigo/api/v1/continuous-profiles[?start=T&end=T]
+func ContinuousProfiles(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	start, end, ok := parseContinuousWindow(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(core.ContinuousProfiles(start, end)); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// defaultProfileSeconds is how long the CPU is profiled, or the execution traced, when no
+// seconds are given.
+const defaultProfileSeconds = 10
+
+func isProcessProfileKind(kind string) bool {
+	switch kind {
+	case core.ProcessProfileCPU, core.ProcessProfileHeap, core.ProcessProfileAllocs, core.ProcessProfileGoroutine,
+		core.ProcessProfileBlock, core.ProcessProfileMutex, core.ProcessProfileTrace:
+		return true
+	}
+	return false
+}
+
+// processProfileDuration returns how long to capture a process profile of the given kind
+// for the seconds parameter. Without one, CPU profiles and traces last
+// defaultProfileSeconds, and the other kinds are snapshots.
+func processProfileDuration(kind, seconds string) (time.Duration, error) {
+	if seconds == "" {
+		if kind == core.ProcessProfileCPU || kind == core.ProcessProfileTrace {
+			return defaultProfileSeconds * time.Second, nil
+		}
+		return 0, nil
+	}
+	n, err := strconv.Atoi(seconds)
+	d := time.Duration(n) * time.Second
+	if err != nil || n <= 0 || d > core.MaxProcessProfileDuration {
+		return 0, fmt.Errorf("seconds must be between 1 and %d", int(core.MaxProcessProfileDuration.Seconds()))
+	}
+	return d, nil
+}
+
+func writeProfileError(w http.ResponseWriter, err error) {
+	if errors.Is(err, core.ErrProfilerBusy) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	http.Error(w, "Failed to profile the process: "+err.Error(), http.StatusInternalServerError)
+}
+
+// ProcessProfiles lists the captured process profiles, newest first, or captures one: a
+// CPU profile or execution trace over seconds, the block or mutex events recorded over
+// seconds (or so far without them), or a heap, allocs or goroutine snapshot.
+// GET /monigo/api/v1/process-profiles
+// POST /monigo/api/v1/process-profiles?type=cpu|heap|allocs|goroutine|block|mutex|trace[&seconds=N]
+func ProcessProfiles(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(core.ProcessProfiles()); err != nil {
+			http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		}
+		return
+	case http.MethodPost:
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	kind := r.URL.Query().Get("type")
+	if !isProcessProfileKind(kind) {
+		http.Error(w, "Unknown profile type", http.StatusBadRequest)
+		return
+	}
+	d, err := processProfileDuration(kind, r.URL.Query().Get("seconds"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rec, err := core.CaptureProcessProfile(r.Context(), kind, d)
+	if err != nil {
+		writeProfileError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(rec); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// DownloadProcessProfile serves the file of a captured process profile, for go tool pprof
+// or, for execution traces, go tool trace.
+// GET /monigo/api/v1/process-profiles/download?id=ID
+func DownloadProcessProfile(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	rec, ok := core.StoredProcessProfile(r.URL.Query().Get("id"))
+	if !ok {
+		http.Error(w, "Profile not found", http.StatusNotFound)
+		return
+	}
+	f, err := os.Open(rec.FilePath)
+	if err != nil {
+		http.Error(w, "Profile not found", http.StatusNotFound)
+		return
+	}
+	defer f.Close()
+
+	name := filepath.Base(rec.FilePath)
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	http.ServeContent(w, r, name, rec.CapturedAt, f)
+}
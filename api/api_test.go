@@ -2,16 +2,24 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"runtime"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/iyashjayesh/monigo/common"
 	"github.com/iyashjayesh/monigo/core"
+	"github.com/iyashjayesh/monigo/exporters"
+	"github.com/iyashjayesh/monigo/internal/prompb"
 	"github.com/iyashjayesh/monigo/models"
+	"github.com/iyashjayesh/monigo/timeseries"
+	"github.com/klauspost/compress/snappy"
 )
 
 func init() {
@@ -110,6 +118,40 @@ func TestGetFunctionTraceDetails(t *testing.T) {
 	}
 }
 
+func TestGetFunctionTraceDetailsErrorRate(t *testing.T) {
+	fn := func(fail bool) error {
+		if fail {
+			return errors.New("timeout")
+		}
+		return nil
+	}
+	for _, fail := range []bool{true, false, false, false} {
+		core.TraceFunctionWithReturns(context.Background(), fn, fail)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/monigo/api/v1/function", nil)
+	w := httptest.NewRecorder()
+	GetFunctionTraceDetails(w, req)
+
+	var details map[string]models.FunctionMetrics
+	if err := json.NewDecoder(w.Body).Decode(&details); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	var found bool
+	for name, m := range details {
+		if !strings.Contains(name, "TestGetFunctionTraceDetailsErrorRate") {
+			continue
+		}
+		found = true
+		if m.ErrorCount != 1 || m.ErrorRate != 0.25 || m.LastError != "timeout" {
+			t.Errorf("expected 1 error in 4 calls, got %+v", m)
+		}
+	}
+	if !found {
+		t.Error("expected the traced function in the response")
+	}
+}
+
 func TestGetFunctionTraceDetails_WrongMethod(t *testing.T) {
 	req := httptest.NewRequest(http.MethodPost, "/monigo/api/v1/function", nil)
 	w := httptest.NewRecorder()
@@ -190,3 +232,497 @@ func TestGetReportData_InvalidBody(t *testing.T) {
 		t.Errorf("expected 400, got %d", w.Code)
 	}
 }
+
+func TestGetServiceMetricsFromStorage_InvalidStep(t *testing.T) {
+	body := `{"field_name":["goroutines"],"start_time":"2026-01-01T00:00:00Z","end_time":"2026-01-02T00:00:00Z","step":"soon"}`
+	req := httptest.NewRequest(http.MethodPost, "/monigo/api/v1/service-metrics", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+	GetServiceMetricsFromStorage(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid step, got %d", w.Code)
+	}
+}
+
+func TestGetReportData_InvalidAggregation(t *testing.T) {
+	body := `{"topic":"LoadStatistics","start_time":"2026-01-01T00:00:00Z","end_time":"2026-01-02T00:00:00Z","aggregation":"median"}`
+	req := httptest.NewRequest(http.MethodPost, "/monigo/api/v1/reports", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+	GetReportData(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid aggregation, got %d", w.Code)
+	}
+}
+
+func TestResolveStep(t *testing.T) {
+	tests := []struct {
+		step, timeFrame string
+		want            int64
+		wantErr         bool
+	}{
+		{"", "", 0, false},
+		{"1m", "", 60, false},
+		{"1m", "7d", 60, false},
+		{"", "1h", 12, false},
+		{"", "7d", 2016, false},
+		{"500ms", "", 0, true},
+		{"", "forever", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := resolveStep(tt.step, tt.timeFrame)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("resolveStep(%q, %q) error = %v, wantErr %v", tt.step, tt.timeFrame, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("resolveStep(%q, %q) = %d, want %d", tt.step, tt.timeFrame, got, tt.want)
+		}
+	}
+}
+
+func TestQueryMetrics(t *testing.T) {
+	timeseries.SetStorageType("memory")
+	sto, err := timeseries.GetStorageInstance()
+	if err != nil {
+		t.Fatalf("GetStorageInstance() error = %v", err)
+	}
+	var rows []timeseries.Row
+	for _, host := range []string{"a", "b"} {
+		for ts := int64(1000); ts <= 1120; ts += 10 {
+			rows = append(rows, timeseries.Row{
+				Metric:    "query_test_requests",
+				Labels:    []timeseries.Label{{Name: "host", Value: host}},
+				DataPoint: timeseries.DataPoint{Timestamp: ts, Value: float64(ts - 1000)},
+			})
+		}
+	}
+	if err := sto.InsertRows(rows); err != nil {
+		t.Fatalf("InsertRows() error = %v", err)
+	}
+
+	body := `{"query":"sum(rate(query_test_requests[1m]))","start":"1060","end":"1120","step":"30s"}`
+	req := httptest.NewRequest(http.MethodPost, "/monigo/api/v1/query", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	QueryMetrics(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Status string `json:"status"`
+		Data   struct {
+			ResultType string `json:"resultType"`
+			Result     []struct {
+				Metric map[string]string `json:"metric"`
+				Values [][2]interface{}  `json:"values"`
+			} `json:"result"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Status != "success" || resp.Data.ResultType != "matrix" {
+		t.Fatalf("unexpected envelope %+v", resp)
+	}
+	if len(resp.Data.Result) != 1 || len(resp.Data.Result[0].Values) != 3 {
+		t.Fatalf("expected 1 series with 3 values, got %+v", resp.Data.Result)
+	}
+	last := resp.Data.Result[0].Values[2]
+	if last[0] != float64(1120) || last[1] != "2" {
+		t.Errorf("expected [1120, \"2\"], got %v", last)
+	}
+}
+
+func TestQueryMetrics_FormEncodedError(t *testing.T) {
+	form := url.Values{"query": {"rate(query_test_requests)"}}
+	req := httptest.NewRequest(http.MethodPost, "/monigo/api/v1/query", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	QueryMetrics(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Code)
+	}
+	var resp models.QueryResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Status != "error" || resp.ErrorType != "bad_data" || resp.Error == "" {
+		t.Errorf("unexpected error envelope %+v", resp)
+	}
+}
+
+func TestQueryMetrics_WrongMethod(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/monigo/api/v1/query", nil)
+	w := httptest.NewRecorder()
+	QueryMetrics(w, req)
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", w.Code)
+	}
+}
+
+func remoteReadRequest(t *testing.T, req prompb.ReadRequest) *httptest.ResponseRecorder {
+	t.Helper()
+	body := snappy.Encode(nil, req.Marshal())
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/read", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	RemoteReadHandler(w, r)
+	return w
+}
+
+func TestRemoteReadHandler(t *testing.T) {
+	timeseries.SetStorageType("memory")
+	sto, err := timeseries.GetStorageInstance()
+	if err != nil {
+		t.Fatalf("GetStorageInstance() error = %v", err)
+	}
+	err = sto.InsertRows([]timeseries.Row{
+		{Metric: "remote_read_cpu", Labels: []timeseries.Label{{Name: "host", Value: "web-1"}}, DataPoint: timeseries.DataPoint{Timestamp: 100, Value: 1}},
+		{Metric: "remote_read_cpu", Labels: []timeseries.Label{{Name: "host", Value: "web-1"}}, DataPoint: timeseries.DataPoint{Timestamp: 110, Value: 2}},
+		{Metric: "remote_read_cpu", Labels: []timeseries.Label{{Name: "host", Value: "db-1"}}, DataPoint: timeseries.DataPoint{Timestamp: 110, Value: 3}},
+	})
+	if err != nil {
+		t.Fatalf("InsertRows() error = %v", err)
+	}
+
+	w := remoteReadRequest(t, prompb.ReadRequest{
+		Queries: []prompb.Query{{
+			StartTimestampMs: 100000,
+			EndTimestampMs:   120000,
+			Matchers: []prompb.LabelMatcher{
+				{Type: prompb.MatchEqual, Name: "__name__", Value: "remote_read_cpu"},
+				{Type: prompb.MatchRegexp, Name: "host", Value: "web-.*"},
+			},
+		}},
+		AcceptedResponseTypes: []int32{prompb.ResponseTypeSamples},
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Content-Encoding") != "snappy" {
+		t.Errorf("expected snappy content encoding, got %q", w.Header().Get("Content-Encoding"))
+	}
+
+	body, err := snappy.Decode(nil, w.Body.Bytes())
+	if err != nil {
+		t.Fatalf("failed to decompress response: %v", err)
+	}
+	var resp prompb.ReadResponse
+	if err := resp.Unmarshal(body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Results) != 1 || len(resp.Results[0].Timeseries) != 1 {
+		t.Fatalf("expected one series, got %+v", resp)
+	}
+	ts := resp.Results[0].Timeseries[0]
+	wantLabels := []prompb.Label{{Name: "__name__", Value: "remote_read_cpu"}, {Name: "host", Value: "web-1"}}
+	if len(ts.Labels) != 2 || ts.Labels[0] != wantLabels[0] || ts.Labels[1] != wantLabels[1] {
+		t.Errorf("unexpected labels %v", ts.Labels)
+	}
+	if len(ts.Samples) != 2 || ts.Samples[1].Timestamp != 110000 || ts.Samples[1].Value != 2 {
+		t.Errorf("unexpected samples %v", ts.Samples)
+	}
+}
+
+func TestRemoteReadHandler_BadRequests(t *testing.T) {
+	noName := prompb.ReadRequest{Queries: []prompb.Query{{EndTimestampMs: 1000}}}
+	if w := remoteReadRequest(t, noName); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 without __name__ matcher, got %d", w.Code)
+	}
+
+	streamedOnly := prompb.ReadRequest{AcceptedResponseTypes: []int32{prompb.ResponseTypeStreamedXORChunks}}
+	if w := remoteReadRequest(t, streamedOnly); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for streamed-only clients, got %d", w.Code)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/read", bytes.NewBufferString("not snappy"))
+	w := httptest.NewRecorder()
+	RemoteReadHandler(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid body, got %d", w.Code)
+	}
+}
+
+func TestGetCustomMetrics(t *testing.T) {
+	timeseries.SetStorageType("memory")
+	reg := core.CustomMetrics()
+	reg.IncrementCounter("api_test.orders", 3, map[string]string{"region": `eu "west"`})
+	reg.RecordHistogram("api_test_latency", 2, nil)
+	reg.RecordHistogram("api_test_latency", 4, nil)
+	defer reg.Delete("api_test.orders")
+	defer reg.Delete("api_test_latency")
+	if err := exporters.NewStorageExporter().Export(context.Background(), reg.GetAll()); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/monigo/api/v1/custom-metrics", nil)
+	w := httptest.NewRecorder()
+	GetCustomMetrics(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var metrics []models.CustomMetric
+	if err := json.NewDecoder(w.Body).Decode(&metrics); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(metrics) != 2 || metrics[0].Name != "api_test.orders" || metrics[1].Name != "api_test_latency" {
+		t.Fatalf("unexpected metrics %+v", metrics)
+	}
+	if metrics[0].Type != "counter" || metrics[0].Value != 3 {
+		t.Errorf("unexpected counter %+v", metrics[0])
+	}
+	if metrics[1].Type != "histogram" || metrics[1].Count != 2 || metrics[1].Sum != 6 {
+		t.Errorf("unexpected histogram %+v", metrics[1])
+	}
+
+	// The returned queries chart the stored series.
+	want := []string{"3", "3"}
+	for i, m := range metrics {
+		body, _ := json.Marshal(models.QueryRequest{Query: m.Query, Step: "1m"})
+		req := httptest.NewRequest(http.MethodPost, "/monigo/api/v1/query", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		QueryMetrics(w, req)
+
+		var resp struct {
+			Data struct {
+				Result []struct {
+					Values [][2]interface{} `json:"values"`
+				} `json:"result"`
+			} `json:"data"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode query response: %v", err)
+		}
+		if len(resp.Data.Result) != 1 || len(resp.Data.Result[0].Values) == 0 {
+			t.Fatalf("query %q returned %+v", m.Query, resp.Data.Result)
+		}
+		values := resp.Data.Result[0].Values
+		if got := values[len(values)-1][1]; got != want[i] {
+			t.Errorf("query %q = %v, want %s", m.Query, got, want[i])
+		}
+	}
+}
+
+func TestGetCustomMetrics_WrongMethod(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/monigo/api/v1/custom-metrics", nil)
+	w := httptest.NewRecorder()
+	GetCustomMetrics(w, req)
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", w.Code)
+	}
+}
+
+func TestGetStoredProfiles(t *testing.T) {
+	core.SetSamplingRate(1)
+	for range 2 {
+		core.TraceNamed(context.Background(), "api.stored", func(context.Context) error { return nil })
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/monigo/api/v1/profiles?name=api.stored", nil)
+	w := httptest.NewRecorder()
+	GetStoredProfiles(w, req)
+
+	var records []models.ProfileRecord
+	if err := json.NewDecoder(w.Body).Decode(&records); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(records) < 2 || records[0].FunctionName != "api.stored" || records[0].MemProfileFilePath == "" {
+		t.Fatalf("expected the stored profiles of the function, got %+v", records)
+	}
+
+	// A stored profile can only be viewed under its own function.
+	core.TraceNamed(context.Background(), "api.other", func(context.Context) error { return nil })
+	req = httptest.NewRequest(http.MethodGet, "/monigo/api/v1/function-details?name=api.other&profile="+records[0].ID, nil)
+	w = httptest.NewRecorder()
+	ViewFunctionMetrics(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for the profile of another function, got %d", w.Code)
+	}
+}
+
+func TestGetStoredProfiles_WrongMethod(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/monigo/api/v1/profiles", nil)
+	w := httptest.NewRecorder()
+	GetStoredProfiles(w, req)
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", w.Code)
+	}
+}
+
+func TestGetFlameGraph(t *testing.T) {
+	core.SetSamplingRate(1)
+	core.TraceNamed(context.Background(), "api.flame", func(context.Context) error { return nil })
+
+	req := httptest.NewRequest(http.MethodGet, "/monigo/api/v1/flamegraph?name=api.flame&type=mem&sample_type=alloc_space&format=collapsed", nil)
+	w := httptest.NewRecorder()
+	GetFlameGraph(w, req)
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain") {
+		t.Fatalf("expected collapsed stacks, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// The process is never profiled on demand: without a name, a profile ID or a window,
+	// the request is rejected at once.
+	for query, code := range map[string]int{
+		"":                                   http.StatusBadRequest,
+		"type=heap":                          http.StatusBadRequest,
+		"type=cpu&seconds=10":                http.StatusBadRequest,
+		"name=api.missing&type=cpu":          http.StatusNotFound,
+		"name=api.flame":                     http.StatusBadRequest,
+		"name=api.flame&type=block":          http.StatusBadRequest,
+		"name=api.flame&type=mem&format=svg": http.StatusBadRequest,
+		"name=api.flame&type=mem&sample_type=cpu": http.StatusBadRequest,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/monigo/api/v1/flamegraph?"+query, nil)
+		w := httptest.NewRecorder()
+		GetFlameGraph(w, req)
+		if w.Code != code {
+			t.Errorf("%s: expected %d, got %d", query, code, w.Code)
+		}
+	}
+}
+
+func TestGetFlameGraph_WrongMethod(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/monigo/api/v1/flamegraph", nil)
+	w := httptest.NewRecorder()
+	GetFlameGraph(w, req)
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", w.Code)
+	}
+}
+
+func TestProcessProfiles(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/monigo/api/v1/process-profiles?type=heap", nil)
+	w := httptest.NewRecorder()
+	ProcessProfiles(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var rec models.ProcessProfileRecord
+	if err := json.NewDecoder(w.Body).Decode(&rec); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if rec.ID == "" || rec.Kind != "heap" || rec.SizeBytes == 0 {
+		t.Fatalf("unexpected record %+v", rec)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/monigo/api/v1/process-profiles", nil)
+	w = httptest.NewRecorder()
+	ProcessProfiles(w, req)
+	var records []models.ProcessProfileRecord
+	if err := json.NewDecoder(w.Body).Decode(&records); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(records) == 0 || records[0].ID != rec.ID {
+		t.Errorf("expected the capture listed first, got %+v", records)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/monigo/api/v1/process-profiles/download?id="+rec.ID, nil)
+	w = httptest.NewRecorder()
+	DownloadProcessProfile(w, req)
+	if w.Code != http.StatusOK || int64(w.Body.Len()) != rec.SizeBytes || !strings.Contains(w.Header().Get("Content-Disposition"), rec.ID) {
+		t.Errorf("expected the profile as an attachment, got %d with %d bytes", w.Code, w.Body.Len())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/monigo/api/v1/flamegraph?profile="+rec.ID, nil)
+	w = httptest.NewRecorder()
+	GetFlameGraph(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected a flame graph of the capture, got %d: %s", w.Code, w.Body.String())
+	}
+
+	for query, code := range map[string]int{
+		"type=threads":            http.StatusBadRequest,
+		"type=cpu&seconds=0":      http.StatusBadRequest,
+		"type=trace&seconds=3600": http.StatusBadRequest,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/monigo/api/v1/process-profiles?"+query, nil)
+		w := httptest.NewRecorder()
+		ProcessProfiles(w, req)
+		if w.Code != code {
+			t.Errorf("%s: expected %d, got %d", query, code, w.Code)
+		}
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/monigo/api/v1/process-profiles/download?id=missing", nil)
+	w = httptest.NewRecorder()
+	DownloadProcessProfile(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown profile, got %d", w.Code)
+	}
+}
+
+func TestContinuousProfiles(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/monigo/api/v1/continuous-profiles?start=0&end=60", nil)
+	w := httptest.NewRecorder()
+	ContinuousProfiles(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var records []models.ProcessProfileRecord
+	if err := json.NewDecoder(w.Body).Decode(&records); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if records == nil || len(records) != 0 {
+		t.Errorf("expected an empty history, got %+v", records)
+	}
+
+	for query, code := range map[string]int{
+		"type=heap&start=0&end=60":      http.StatusNotFound,
+		"type=goroutine&start=0&end=60": http.StatusBadRequest,
+		"type=cpu&start=60&end=0":       http.StatusBadRequest,
+		"type=cpu&start=yesterday":      http.StatusBadRequest,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/monigo/api/v1/flamegraph?"+query, nil)
+		w := httptest.NewRecorder()
+		GetFlameGraph(w, req)
+		if w.Code != code {
+			t.Errorf("%s: expected %d, got %d", query, code, w.Code)
+		}
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/monigo/api/v1/continuous-profiles", nil)
+	w = httptest.NewRecorder()
+	ContinuousProfiles(w, req)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", w.Code)
+	}
+}
+
+func TestProcessProfiles_WrongMethod(t *testing.T) {
+	req := httptest.NewRequest(http.MethodDelete, "/monigo/api/v1/process-profiles", nil)
+	w := httptest.NewRecorder()
+	ProcessProfiles(w, req)
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", w.Code)
+	}
+}
+
+func TestPrometheusMetricsHandlerSkipsInconsistentMetrics(t *testing.T) {
+	// Registered below the public API, which rejects such series.
+	core.CustomMetrics().SetGauge("test_inconsistent", 1, map[string]string{"a": "1"})
+	core.CustomMetrics().SetGauge("test_inconsistent", 2, map[string]string{"b": "2"})
+	core.CustomMetrics().SetGauge("monigo_cpu_cores", 3, nil)
+	defer core.CustomMetrics().Delete("test_inconsistent")
+	defer core.CustomMetrics().Delete("monigo_cpu_cores")
+	core.CustomMetrics().SetGauge("test_consistent", 4, nil)
+	defer core.CustomMetrics().Delete("test_consistent")
+
+	w := httptest.NewRecorder()
+	PrometheusMetricsHandler(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), "test_consistent 4") {
+		t.Error("expected the valid custom metric served")
+	}
+}
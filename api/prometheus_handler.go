@@ -1,22 +1,44 @@
 package api
 
 import (
+	"fmt"
 	"net/http"
 
+	"github.com/iyashjayesh/monigo/core"
 	"github.com/iyashjayesh/monigo/exporters"
+	"github.com/iyashjayesh/monigo/internal/logger"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 func init() {
 	prometheus.MustRegister(exporters.NewMonigoCollector())
+	prometheus.MustRegister(exporters.NewRegistryCollector(core.CustomMetrics()))
+	prometheus.MustRegister(exporters.NewRegistryCollector(core.FunctionStats()))
+}
+
+// metricsHandler serves the default gatherer. The registry collectors are unchecked, so
+// a metric that fails to gather is logged and left out rather than failing the response.
+var metricsHandler = promhttp.InstrumentMetricHandler(
+	prometheus.DefaultRegisterer,
+	promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{
+		ErrorLog:      gatherErrorLog{},
+		ErrorHandling: promhttp.ContinueOnError,
+	}),
+)
+
+// gatherErrorLog reports the errors of metricsHandler through the monigo logger.
+type gatherErrorLog struct{}
+
+func (gatherErrorLog) Println(v ...any) {
+	logger.Log.Warn("failed to gather Prometheus metrics", "error", fmt.Sprint(v...))
 }
 
 func GetPrometheusHandler() http.Handler {
-	return promhttp.Handler()
+	return metricsHandler
 }
 
 // PrometheusMetricsHandler handles the /metrics endpoint.
 func PrometheusMetricsHandler(w http.ResponseWriter, r *http.Request) {
-	promhttp.Handler().ServeHTTP(w, r)
+	metricsHandler.ServeHTTP(w, r)
 }
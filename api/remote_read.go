@@ -0,0 +1,137 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+
+	"github.com/iyashjayesh/monigo/internal/logger"
+	"github.com/iyashjayesh/monigo/internal/prompb"
+	"github.com/iyashjayesh/monigo/timeseries"
+	"github.com/klauspost/compress/snappy"
+)
+
+// maxRemoteReadBodySize bounds the compressed remote read request body.
+const maxRemoteReadBodySize = 1 << 20
+
+// RemoteReadHandler implements the Prometheus remote read protocol (snappy-compressed
+// protobuf, sampled response type) on top of monigo's time series storage, so a Prometheus
+// server or Grafana datasource can query a service's history directly.
+// Every query must select a metric with an equality __name__ matcher.
+func RemoteReadHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	compressed, err := io.ReadAll(io.LimitReader(r.Body, maxRemoteReadBodySize))
+	if err != nil {
+		http.Error(w, "Failed to read request", http.StatusBadRequest)
+		return
+	}
+	body, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		http.Error(w, "Failed to decompress request", http.StatusBadRequest)
+		return
+	}
+
+	var req prompb.ReadRequest
+	if err := req.Unmarshal(body); err != nil {
+		http.Error(w, "Failed to decode request", http.StatusBadRequest)
+		return
+	}
+
+	if !acceptsSamples(req.AcceptedResponseTypes) {
+		http.Error(w, "Only the SAMPLES response type is supported", http.StatusBadRequest)
+		return
+	}
+
+	resp := prompb.ReadResponse{Results: make([]prompb.QueryResult, 0, len(req.Queries))}
+	for _, q := range req.Queries {
+		metric, matchers, err := remoteReadMatchers(q.Matchers)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Storage uses Unix seconds; round the start up so no point before it is returned.
+		start := (q.StartTimestampMs + 999) / 1000
+		end := q.EndTimestampMs / 1000
+		series, err := timeseries.SelectMatching(metric, matchers, start, end)
+		if err != nil {
+			logger.Log.Error("remote read query failed", "metric", metric, "error", err)
+			http.Error(w, "Failed to get data points", http.StatusInternalServerError)
+			return
+		}
+
+		result := prompb.QueryResult{Timeseries: make([]prompb.TimeSeries, 0, len(series))}
+		for _, s := range series {
+			result.Timeseries = append(result.Timeseries, toRemoteSeries(s))
+		}
+		resp.Results = append(resp.Results, result)
+	}
+
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	w.Header().Set("Content-Encoding", "snappy")
+	if _, err := w.Write(snappy.Encode(nil, resp.Marshal())); err != nil {
+		logger.Log.Warn("failed to write remote read response", "error", err)
+	}
+}
+
+// acceptsSamples reports whether the client accepts sampled responses. An empty list
+// comes from clients predating response type negotiation, which only understand samples.
+func acceptsSamples(types []int32) bool {
+	if len(types) == 0 {
+		return true
+	}
+	for _, t := range types {
+		if t == prompb.ResponseTypeSamples {
+			return true
+		}
+	}
+	return false
+}
+
+// remoteReadMatchers splits the __name__ matcher from the label matchers of a query.
+func remoteReadMatchers(in []prompb.LabelMatcher) (string, []timeseries.LabelMatcher, error) {
+	var (
+		metric   string
+		matchers []timeseries.LabelMatcher
+	)
+	for _, m := range in {
+		if m.Name == "__name__" {
+			if m.Type != prompb.MatchEqual {
+				return "", nil, fmt.Errorf("only equality __name__ matchers are supported")
+			}
+			metric = m.Value
+			continue
+		}
+		lm, err := timeseries.NewLabelMatcher(timeseries.MatchType(m.Type), m.Name, m.Value)
+		if err != nil {
+			return "", nil, err
+		}
+		matchers = append(matchers, lm)
+	}
+	if metric == "" {
+		return "", nil, fmt.Errorf("query must select a metric with a __name__ matcher")
+	}
+	return metric, matchers, nil
+}
+
+// toRemoteSeries converts a stored series to remote read form: labels sorted by name
+// including __name__, timestamps in milliseconds.
+func toRemoteSeries(s timeseries.Series) prompb.TimeSeries {
+	labels := make([]prompb.Label, 0, len(s.Labels)+1)
+	labels = append(labels, prompb.Label{Name: "__name__", Value: s.Metric})
+	for _, l := range s.Labels {
+		labels = append(labels, prompb.Label{Name: l.Name, Value: l.Value})
+	}
+	sort.Slice(labels, func(i, j int) bool { return labels[i].Name < labels[j].Name })
+
+	samples := make([]prompb.Sample, len(s.Points))
+	for i, p := range s.Points {
+		samples[i] = prompb.Sample{Value: p.Value, Timestamp: p.Timestamp * 1000}
+	}
+	return prompb.TimeSeries{Labels: labels, Samples: samples}
+}
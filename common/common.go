@@ -259,8 +259,9 @@ func GetServiceStartTime() time.Time {
 	return serviceInfo.ServiceStartTime
 }
 
-// parseDuration parses the duration string.
-func parseDuration(input string) (time.Duration, error) {
+// ParseDuration parses a duration string, accepting the "d" (days) and "month" suffixes
+// on top of the units understood by time.ParseDuration.
+func ParseDuration(input string) (time.Duration, error) {
 	if strings.HasSuffix(input, "d") {
 		daysStr := strings.TrimSuffix(input, "d")
 		days, err := strconv.Atoi(daysStr)
@@ -288,7 +289,7 @@ func GetDataRetentionPeriod() time.Duration {
 		period = "7d"
 	}
 
-	duration, err := parseDuration(period)
+	duration, err := ParseDuration(period)
 	if err != nil {
 		logger.Log.Error("parsing retention period, using default retention period (7d)", "error", err)
 		duration = 7 * 24 * time.Hour
@@ -3,7 +3,10 @@ package monigo
 import (
 	"log/slog"
 	"net/http"
+	"time"
 
+	"github.com/iyashjayesh/monigo/core"
+	"github.com/iyashjayesh/monigo/exporters"
 	"github.com/iyashjayesh/monigo/internal/logger"
 )
 
@@ -43,6 +46,41 @@ func (b *MonigoBuilder) WithDataPointsSyncFrequency(frequency string) *MonigoBui
 	return b
 }
 
+// WithStatsSampleInterval sets how often service stats are sampled in the background (default "5s").
+// The dashboard API and /metrics serve the latest sample instead of measuring on every request.
+func (b *MonigoBuilder) WithStatsSampleInterval(interval string) *MonigoBuilder {
+	b.config.StatsSampleInterval = interval
+	return b
+}
+
+// WithProfileStorageLimits keeps the profiles of at most perFunction sampled calls per
+// function, within maxBytes of disk in total, dropping the oldest first. Profiles older
+// than the retention period are dropped too. Zero selects the default limit.
+func (b *MonigoBuilder) WithProfileStorageLimits(perFunction int, maxBytes int64) *MonigoBuilder {
+	b.config.MaxProfilesPerFunction = perFunction
+	b.config.MaxProfileStorageBytes = maxBytes
+	return b
+}
+
+// WithContinuousProfiling profiles the CPU in cycles of the given length (e.g. "10s"),
+// one a minute unless WithContinuousProfilingInterval sets another interval, instead of
+// profiling sampled calls one by one. Traced calls are labelled, so each function's CPU
+// report keeps only its own samples of the last complete cycle.
+func (b *MonigoBuilder) WithContinuousProfiling(cycle string) *MonigoBuilder {
+	b.config.ContinuousProfileCycle = cycle
+	return b
+}
+
+// WithContinuousProfilingInterval starts a continuous CPU profile cycle every interval
+// (e.g. "5m"; an interval equal to the cycle runs them back to back), enabling continuous
+// profiling with the default cycle unless WithContinuousProfiling sets one. Every cycle is kept with a heap profile
+// within the profile storage budget and the data retention period, so the dashboard can
+// show the profile of any time window.
+func (b *MonigoBuilder) WithContinuousProfilingInterval(interval string) *MonigoBuilder {
+	b.config.ContinuousProfileInterval = interval
+	return b
+}
+
 // WithTimeZone sets the time zone
 func (b *MonigoBuilder) WithTimeZone(timeZone string) *MonigoBuilder {
 	b.config.TimeZone = timeZone
@@ -103,6 +141,14 @@ func (b *MonigoBuilder) WithStorageType(storageType string) *MonigoBuilder {
 	return b
 }
 
+// WithMemoryStorageLimits caps the in-memory storage at maxPointsPerSeries points per series
+// and roughly maxBytes in total, evicting the oldest points first. Zero disables a limit.
+func (b *MonigoBuilder) WithMemoryStorageLimits(maxPointsPerSeries int, maxBytes int64) *MonigoBuilder {
+	b.config.MemoryMaxPointsPerSeries = maxPointsPerSeries
+	b.config.MemoryMaxBytes = maxBytes
+	return b
+}
+
 // WithHeadless sets whether the dashboard should be started
 func (b *MonigoBuilder) WithHeadless(headless bool) *MonigoBuilder {
 	b.config.Headless = headless
@@ -121,6 +167,22 @@ func (b *MonigoBuilder) WithOTelHeaders(headers map[string]string) *MonigoBuilde
 	return b
 }
 
+// WithOTelConfig sets the OTel transport (gRPC or HTTP with protobuf or JSON), TLS,
+// compression, timeout, export interval and resource attributes. Endpoint and Headers
+// fall back to WithOTelEndpoint and WithOTelHeaders.
+func (b *MonigoBuilder) WithOTelConfig(cfg exporters.OTelConfig) *MonigoBuilder {
+	b.config.OTelOptions = &cfg
+	return b
+}
+
+// WithRemoteWrite enables pushing metrics to a Prometheus remote write endpoint
+// (e.g. "http://mimir:9009/api/v1/push") with optional headers such as auth or tenant IDs
+func (b *MonigoBuilder) WithRemoteWrite(url string, headers map[string]string) *MonigoBuilder {
+	b.config.RemoteWriteURL = url
+	b.config.RemoteWriteHeaders = headers
+	return b
+}
+
 // WithLogLevel sets the log level for monigo's structured logger
 func (b *MonigoBuilder) WithLogLevel(level slog.Level) *MonigoBuilder {
 	logger.Init(level)
@@ -148,5 +210,40 @@ func (b *MonigoBuilder) Build() *Monigo {
 	if b.config.StorageType != "" && b.config.StorageType != "disk" && b.config.StorageType != "memory" {
 		panic("[MoniGo] Build() failed: StorageType must be 'disk' or 'memory'")
 	}
+	if b.config.MemoryMaxPointsPerSeries < 0 || b.config.MemoryMaxBytes < 0 {
+		panic("[MoniGo] Build() failed: memory storage limits must be >= 0")
+	}
+	if b.config.StatsSampleInterval != "" {
+		if d, err := time.ParseDuration(b.config.StatsSampleInterval); err != nil || d <= 0 {
+			panic("[MoniGo] Build() failed: StatsSampleInterval must be a positive duration such as \"5s\"")
+		}
+	}
+	if b.config.MaxProfilesPerFunction < 0 || b.config.MaxProfileStorageBytes < 0 {
+		panic("[MoniGo] Build() failed: profile storage limits must be >= 0")
+	}
+	if b.config.ContinuousProfileCycle != "" {
+		if d, err := time.ParseDuration(b.config.ContinuousProfileCycle); err != nil || d <= 0 {
+			panic("[MoniGo] Build() failed: ContinuousProfileCycle must be a positive duration such as \"10s\"")
+		}
+	}
+	if b.config.ContinuousProfileInterval != "" {
+		cycle := core.DefaultContinuousProfileCycle
+		if d, err := time.ParseDuration(b.config.ContinuousProfileCycle); err == nil {
+			cycle = d
+		}
+		if d, err := time.ParseDuration(b.config.ContinuousProfileInterval); err != nil || d < cycle {
+			panic("[MoniGo] Build() failed: ContinuousProfileInterval must be a duration no shorter than the profile cycle")
+		}
+	}
+	if opts := b.config.OTelOptions; opts != nil {
+		switch opts.Protocol {
+		case "", exporters.OTelProtocolGRPC, exporters.OTelProtocolHTTPProtobuf, exporters.OTelProtocolHTTPJSON:
+		default:
+			panic("[MoniGo] Build() failed: OTel protocol must be 'grpc', 'http/protobuf' or 'http/json'")
+		}
+		if opts.Compression != "" && opts.Compression != "gzip" {
+			panic("[MoniGo] Build() failed: OTel compression must be 'gzip' or empty")
+		}
+	}
 	return b.config
 }
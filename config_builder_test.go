@@ -2,6 +2,8 @@ package monigo
 
 import (
 	"testing"
+
+	"github.com/iyashjayesh/monigo/exporters"
 )
 
 func TestBuilderValidBuild(t *testing.T) {
@@ -93,3 +95,115 @@ func TestBuilderAllOptions(t *testing.T) {
 		t.Errorf("expected '/custom/api', got %q", m.CustomBaseAPIPath)
 	}
 }
+
+func TestBuilderMemoryStorageLimits(t *testing.T) {
+	m := NewBuilder().
+		WithServiceName("test").
+		WithStorageType("memory").
+		WithMemoryStorageLimits(1000, 1<<20).
+		Build()
+
+	if m.MemoryMaxPointsPerSeries != 1000 {
+		t.Errorf("expected 1000 points per series, got %d", m.MemoryMaxPointsPerSeries)
+	}
+	if m.MemoryMaxBytes != 1<<20 {
+		t.Errorf("expected 1MiB budget, got %d", m.MemoryMaxBytes)
+	}
+}
+
+func TestBuilderRemoteWrite(t *testing.T) {
+	m := NewBuilder().
+		WithServiceName("test").
+		WithRemoteWrite("http://localhost:9009/api/v1/push", map[string]string{"X-Scope-OrgID": "team-a"}).
+		Build()
+
+	if m.RemoteWriteURL != "http://localhost:9009/api/v1/push" {
+		t.Errorf("unexpected remote write URL %q", m.RemoteWriteURL)
+	}
+	if m.RemoteWriteHeaders["X-Scope-OrgID"] != "team-a" {
+		t.Errorf("expected tenant header, got %v", m.RemoteWriteHeaders)
+	}
+}
+
+func TestBuilderInvalidMemoryStorageLimits(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("expected panic for negative memory limits")
+		}
+	}()
+
+	NewBuilder().WithServiceName("test").WithMemoryStorageLimits(-1, 0).Build()
+}
+
+func TestBuilderStatsSampleInterval(t *testing.T) {
+	m := NewBuilder().WithServiceName("test").WithStatsSampleInterval("10s").Build()
+	if m.StatsSampleInterval != "10s" {
+		t.Errorf("expected 10s, got %q", m.StatsSampleInterval)
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("expected panic for an invalid sample interval")
+		}
+	}()
+	NewBuilder().WithServiceName("test").WithStatsSampleInterval("soon").Build()
+}
+
+func TestBuilderProfileStorageLimits(t *testing.T) {
+	m := NewBuilder().WithServiceName("test").WithProfileStorageLimits(5, 1<<20).Build()
+	if m.MaxProfilesPerFunction != 5 || m.MaxProfileStorageBytes != 1<<20 {
+		t.Errorf("expected the profile storage limits, got %d and %d", m.MaxProfilesPerFunction, m.MaxProfileStorageBytes)
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("expected panic for a negative profile limit")
+		}
+	}()
+	NewBuilder().WithServiceName("test").WithProfileStorageLimits(-1, 0).Build()
+}
+
+func TestBuilderContinuousProfiling(t *testing.T) {
+	m := NewBuilder().WithServiceName("test").WithContinuousProfiling("10s").Build()
+	if m.ContinuousProfileCycle != "10s" {
+		t.Errorf("expected 10s, got %q", m.ContinuousProfileCycle)
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("expected panic for an invalid profile cycle")
+		}
+	}()
+	NewBuilder().WithServiceName("test").WithContinuousProfiling("-1s").Build()
+}
+
+func TestBuilderContinuousProfilingInterval(t *testing.T) {
+	m := NewBuilder().WithServiceName("test").WithContinuousProfilingInterval("1m").Build()
+	if m.ContinuousProfileInterval != "1m" {
+		t.Errorf("expected 1m, got %q", m.ContinuousProfileInterval)
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("expected panic for an interval shorter than the profile cycle")
+		}
+	}()
+	NewBuilder().WithServiceName("test").WithContinuousProfiling("10s").WithContinuousProfilingInterval("5s").Build()
+}
+
+func TestBuilderOTelConfig(t *testing.T) {
+	m := NewBuilder().
+		WithServiceName("test").
+		WithOTelConfig(exporters.OTelConfig{Endpoint: "collector:4318", Protocol: exporters.OTelProtocolHTTPJSON}).
+		Build()
+	if m.OTelOptions == nil || m.OTelOptions.Protocol != exporters.OTelProtocolHTTPJSON {
+		t.Errorf("expected http/json OTel options, got %+v", m.OTelOptions)
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("expected panic for an unknown OTel protocol")
+		}
+	}()
+	NewBuilder().WithServiceName("test").WithOTelConfig(exporters.OTelConfig{Protocol: "udp"}).Build()
+}
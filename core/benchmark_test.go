@@ -56,3 +56,12 @@ func BenchmarkTraceFunctionWithArgs(b *testing.B) {
 		TraceFunctionWithArgs(context.Background(), f, 42, "test")
 	}
 }
+
+func BenchmarkTraceNamed(b *testing.B) {
+	SetSamplingRate(1000)
+	f := func(context.Context) error { return nil }
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		TraceNamed(context.Background(), "benchmark.named", f)
+	}
+}
@@ -0,0 +1,287 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/pprof/profile"
+	"github.com/iyashjayesh/monigo/internal/logger"
+	"github.com/iyashjayesh/monigo/models"
+)
+
+// Defaults of StartContinuousProfiler: a 10s CPU profile cycle once a minute, so the CPU
+// is profiled a sixth of the time.
+const (
+	DefaultContinuousProfileCycle    = 10 * time.Second
+	DefaultContinuousProfileInterval = time.Minute
+)
+
+// DefaultMaxContinuousProfiles is how many profiles the continuous history keeps: a day of
+// cycles at the default interval, each with its heap profile.
+const DefaultMaxContinuousProfiles = 2 * 24 * 60
+
+// continuousIndexSaveInterval is how often the index of the continuous history is written
+// at most; StopContinuousProfiler writes what is left.
+const continuousIndexSaveInterval = 10 * time.Minute
+
+// continuousProfiler runs the CPU profiler in cycles, one per interval.
+// Traced calls carry the FunctionProfileLabel, so the profile of the last complete cycle
+// holds the CPU breakdown of every traced function without starting a profile per call.
+// Every cycle, with a heap profile taken at its end, is also kept in continuousHistory.
+type continuousProfiler struct {
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	running atomic.Bool
+	path    atomic.Value // string: the last complete cycle
+}
+
+var continuous = &continuousProfiler{}
+
+// continuousHistory keeps the profiles of past continuous cycles, within the profile disk
+// budget and the data retention period.
+var continuousHistory = newContinuousHistory(filepath.Join(basePath, "profiles", "continuous"))
+
+func newContinuousHistory(dir string) *processProfileStore {
+	s := newProcessProfileStore(dir, DefaultMaxContinuousProfiles, DefaultMaxProfileStoreBytes)
+	s.saveInterval = continuousIndexSaveInterval
+	return s
+}
+
+// ErrNoContinuousProfiles is returned for a time window without continuous profiles.
+var ErrNoContinuousProfiles = errors.New("[MoniGo] no continuous profiles in the time window")
+
+// StartContinuousProfiler profiles the CPU of the process in cycles of the given length,
+// starting one every interval, until ctx is done or StopContinuousProfiler is called.
+// Zero selects DefaultContinuousProfileCycle and DefaultContinuousProfileInterval; cycles
+// run back to back when interval is no longer than a cycle. Each complete cycle is written
+// to basePath/profiles/continuous_cpu.prof and, with a heap profile, kept in the history
+// under basePath/profiles/continuous. Calling it while running is a no-op.
+func StartContinuousProfiler(ctx context.Context, cycle, interval time.Duration) {
+	if cycle <= 0 {
+		cycle = DefaultContinuousProfileCycle
+	}
+	if interval <= 0 {
+		interval = DefaultContinuousProfileInterval
+	}
+	interval = max(interval, cycle)
+
+	continuous.mu.Lock()
+	defer continuous.mu.Unlock()
+	if continuous.cancel != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	continuous.cancel = cancel
+	continuous.done = make(chan struct{})
+	continuous.running.Store(true)
+
+	go continuous.run(ctx, cycle, interval, continuous.done)
+}
+
+// StopContinuousProfiler stops the continuous profiler and waits for it to exit. The
+// cycle in progress is dropped, keeping the last complete one.
+func StopContinuousProfiler() {
+	continuous.mu.Lock()
+	cancel, done := continuous.cancel, continuous.done
+	continuous.cancel, continuous.done = nil, nil
+	continuous.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-done
+		continuousHistory.flush()
+	}
+}
+
+// ContinuousProfilePath returns the profile of the last complete continuous cycle, or ""
+// before the first one completes.
+func ContinuousProfilePath() string {
+	path, _ := continuous.path.Load().(string)
+	return path
+}
+
+// continuousProfileFile is where the continuous profiler writes each complete cycle.
+func continuousProfileFile() string {
+	return filepath.Join(basePath, "profiles", "continuous_cpu.prof")
+}
+
+// continuousProfiling reports whether the continuous profiler owns the CPU profile.
+func continuousProfiling() bool {
+	return continuous.running.Load()
+}
+
+func (p *continuousProfiler) run(ctx context.Context, cycle, interval time.Duration, done chan struct{}) {
+	defer close(done)
+	defer p.running.Store(false)
+
+	path := continuousProfileFile()
+	for {
+		start := time.Now()
+		var buf bytes.Buffer
+		started := pprof.StartCPUProfile(&buf) == nil
+		if !started {
+			// A shared session of sampled calls may still be finishing; retry next cycle.
+			logger.Log.Warn("continuous profiler could not start the CPU profile")
+		}
+
+		err := sleepContext(ctx, cycle)
+		if started {
+			pprof.StopCPUProfile()
+			if err != nil {
+				return
+			}
+			if err := writeFileAtomic(path, buf.Bytes()); err != nil {
+				logger.Log.Warn("failed to write continuous CPU profile", "error", err)
+			} else {
+				p.path.Store(path)
+			}
+			p.keep(start, buf.Bytes())
+		}
+
+		if err != nil || sleepContext(ctx, interval-time.Since(start)) != nil {
+			return
+		}
+	}
+}
+
+// keep adds the CPU profile of the cycle that started at start, and a heap profile, to
+// the history. The heap profile is as of the last garbage collection, so taking it
+// doesn't force one.
+func (p *continuousProfiler) keep(start time.Time, cpu []byte) {
+	end := time.Now()
+	rec := models.ProcessProfileRecord{Kind: ProcessProfileCPU, CapturedAt: start, Duration: end.Sub(start)}
+	if _, err := continuousHistory.add(rec, cpu); err != nil {
+		logger.Log.Warn("failed to keep continuous CPU profile", "error", err)
+	}
+
+	heap, err := lookupProfile(ProcessProfileHeap)
+	if err == nil {
+		_, err = continuousHistory.add(models.ProcessProfileRecord{Kind: ProcessProfileHeap, CapturedAt: end}, heap)
+	}
+	if err != nil {
+		logger.Log.Warn("failed to keep continuous heap profile", "error", err)
+	}
+}
+
+// writeFileAtomic replaces path with data, so readers never see a partial file.
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// ContinuousProfiles returns the history of the continuous profiler between start and
+// end, oldest first: the CPU cycles overlapping the window and the heap profiles taken
+// in it.
+func ContinuousProfiles(start, end time.Time) []models.ProcessProfileRecord {
+	return continuousHistory.between(start, end)
+}
+
+// ContinuousFlameGraph renders the continuous profiles of kind, ProcessProfileCPU or
+// ProcessProfileHeap, between start and end as FlameGraph does. The CPU cycles
+// overlapping the window are merged. The heap in use is that of the last heap profile of
+// the window, and the allocations those made since the first one.
+func ContinuousFlameGraph(kind string, start, end time.Time, sampleType, format string) ([]byte, error) {
+	if !IsFlameGraphFormat(format) {
+		return nil, fmt.Errorf("[MoniGo] unknown flame graph format %q", format)
+	}
+	p, err := continuousWindowProfile(kind, start, end)
+	if err != nil {
+		return nil, err
+	}
+	return flameGraph(p, kind, "", sampleType, format)
+}
+
+func continuousWindowProfile(kind string, start, end time.Time) (*profile.Profile, error) {
+	if kind != ProcessProfileCPU && kind != ProcessProfileHeap {
+		return nil, fmt.Errorf("[MoniGo] unknown continuous profile %q", kind)
+	}
+	var profiles []*profile.Profile
+	for _, rec := range ContinuousProfiles(start, end) {
+		if rec.Kind != kind {
+			continue
+		}
+		data, err := os.ReadFile(rec.FilePath)
+		if err != nil {
+			continue // dropped since it was listed
+		}
+		p, err := profile.ParseData(data)
+		if err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, p)
+	}
+	if len(profiles) == 0 {
+		return nil, ErrNoContinuousProfiles
+	}
+	if kind == ProcessProfileHeap {
+		return heapProfileDelta(profiles[0], profiles[len(profiles)-1])
+	}
+	return mergeProfiles(profiles)
+}
+
+// mergeProfiles merges the profiles with the sample types of the first into one.
+func mergeProfiles(profiles []*profile.Profile) (*profile.Profile, error) {
+	first := profiles[0]
+	compatible := []*profile.Profile{first}
+	for _, p := range profiles[1:] {
+		if sameSampleTypes(p, first) {
+			compatible = append(compatible, p)
+		}
+	}
+	return profile.Merge(compatible)
+}
+
+// heapProfileDelta returns last with the allocations of first subtracted: heap profiles
+// count allocations since the start of the process, and memory in use at the time.
+func heapProfileDelta(first, last *profile.Profile) (*profile.Profile, error) {
+	if first == last || !sameSampleTypes(first, last) {
+		return last, nil
+	}
+	base := first.Copy()
+	for _, s := range base.Sample {
+		for i, st := range base.SampleType {
+			if strings.HasPrefix(st.Type, "alloc_") {
+				s.Value[i] = -s.Value[i]
+			} else {
+				s.Value[i] = 0
+			}
+		}
+	}
+	delta, err := profile.Merge([]*profile.Profile{last, base})
+	if err != nil {
+		return nil, err
+	}
+	delta.TimeNanos = first.TimeNanos
+	delta.DurationNanos = last.TimeNanos - first.TimeNanos
+	return delta, nil
+}
+
+// sameSampleTypes reports whether a and b have the same sample and period types, as
+// profile.Merge requires.
+func sameSampleTypes(a, b *profile.Profile) bool {
+	eq := func(x, y *profile.ValueType) bool {
+		if x == nil || y == nil {
+			return x == y
+		}
+		return x.Type == y.Type && x.Unit == y.Unit
+	}
+	return eq(a.PeriodType, b.PeriodType) && slices.EqualFunc(a.SampleType, b.SampleType, eq)
+}
@@ -0,0 +1,174 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/pprof/profile"
+	"github.com/iyashjayesh/monigo/models"
+)
+
+func TestContinuousProfiler(t *testing.T) {
+	history := continuousHistory
+	continuousHistory = newContinuousHistory(t.TempDir())
+	defer func() { continuousHistory = history }()
+
+	SetSamplingRate(1)
+	start := time.Now()
+	StartContinuousProfiler(context.Background(), 200*time.Millisecond, 0)
+
+	name := "continuous.traced"
+	TraceNamed(context.Background(), name, func(context.Context) error {
+		burnCPU(300 * time.Millisecond)
+		return nil
+	})
+	StopContinuousProfiler()
+
+	if continuousProfiling() {
+		t.Fatal("expected the continuous profiler to be stopped")
+	}
+	path := ContinuousProfilePath()
+	if path == "" {
+		t.Fatal("expected a continuous CPU profile")
+	}
+	if m := FunctionTraceDetails()[name]; m == nil || m.CPUProfileFilePath != path {
+		t.Errorf("expected the call to point at the continuous profile, got %+v", m)
+	}
+
+	// The first cycle completed during the call; the one in progress at stop is dropped.
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if labels := profileSampleLabels(t, data); !slices.Contains(labels, name) {
+		t.Errorf("expected samples labelled %q, got %v", name, labels)
+	}
+
+	// Every complete cycle is kept in the history with a heap profile.
+	var kinds []string
+	for _, rec := range ContinuousProfiles(start, time.Now()) {
+		kinds = append(kinds, rec.Kind)
+	}
+	if !slices.Contains(kinds, ProcessProfileCPU) || !slices.Contains(kinds, ProcessProfileHeap) {
+		t.Errorf("expected CPU and heap profiles in the history, got %v", kinds)
+	}
+	out, err := ContinuousFlameGraph(ProcessProfileCPU, start, time.Now(), "", FlameGraphCollapsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "burnCPU") {
+		t.Errorf("expected the traced call in the window's flame graph, got %q", out)
+	}
+	if _, err := ContinuousFlameGraph(ProcessProfileCPU, start.Add(-time.Hour), start.Add(-time.Minute), "", ""); !errors.Is(err, ErrNoContinuousProfiles) {
+		t.Errorf("expected ErrNoContinuousProfiles before the profiler started, got %v", err)
+	}
+
+	// The CPU profiler is free again once stopped.
+	if !cpuProfiles.join("after.continuous", t.TempDir()+"/cpu.prof") {
+		t.Fatal("expected a shared CPU profile to start after the continuous profiler stopped")
+	}
+	cpuProfiles.leave()
+	cpuProfiles.wait()
+}
+
+func TestContinuousHistoryIndexWrites(t *testing.T) {
+	dir := t.TempDir()
+	s := newContinuousHistory(dir)
+	index := filepath.Join(dir, profileIndexFile)
+	add := func() []byte {
+		t.Helper()
+		if _, err := s.add(models.ProcessProfileRecord{Kind: ProcessProfileHeap, CapturedAt: time.Now()}, []byte("heap")); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(index)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	// The index isn't rewritten for every cycle, only once per interval and when flushed.
+	first := add()
+	if second := add(); !bytes.Equal(first, second) {
+		t.Error("expected the index written at most once per interval")
+	}
+	s.flush()
+	if got := newContinuousHistory(dir).newestFirst(nil); len(got) != 2 {
+		t.Errorf("expected both profiles in the flushed index, got %+v", got)
+	}
+}
+
+func TestContinuousProfilerBeforeFirstCycle(t *testing.T) {
+	SetSamplingRate(1)
+	name := "continuous.first.cycle"
+	TraceNamed(context.Background(), name, func(context.Context) error { return nil })
+	want := FunctionTraceDetails()[name].CPUProfileFilePath
+	if want == "" {
+		t.Fatal("expected a CPU profile of the call")
+	}
+
+	continuous.path.Store("")
+	StartContinuousProfiler(context.Background(), time.Hour, 0)
+	TraceNamed(context.Background(), name, func(context.Context) error { return nil })
+	StopContinuousProfiler()
+
+	if got := FunctionTraceDetails()[name].CPUProfileFilePath; got != want {
+		t.Errorf("expected the profile of the last profiled call until a cycle completes, got %q", got)
+	}
+}
+
+func TestHeapProfileDelta(t *testing.T) {
+	heapProfile := func() *profile.Profile {
+		t.Helper()
+		runtime.GC()
+		data, err := lookupProfile(ProcessProfileHeap)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p, err := profile.ParseData(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	total := func(p *profile.Profile, sampleType string) int64 {
+		t.Helper()
+		idx, err := sampleIndex(p, sampleType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var v int64
+		for _, st := range flameStacks(p, idx, "") {
+			v += st.value
+		}
+		return v
+	}
+
+	first := heapProfile()
+	for range 64 {
+		flameGraphSink = append(flameGraphSink, make([]byte, 1<<20))
+	}
+	last := heapProfile()
+	delta, err := heapProfileDelta(first, last)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := total(delta, "alloc_space"); got < 32<<20 || got >= total(last, "alloc_space") {
+		t.Errorf("expected only the allocations between the profiles, got %d bytes", got)
+	}
+	if total(delta, "inuse_space") != total(last, "inuse_space") {
+		t.Error("expected the memory in use of the last profile")
+	}
+	if p, _ := heapProfileDelta(last, last); p != last {
+		t.Error("expected a single profile kept as is")
+	}
+}
@@ -17,6 +17,7 @@ import (
 )
 
 // GetServiceStats collects statistics related to service and system performance.
+// It blocks for the CPU measurement window; readers that poll should use CachedServiceStats.
 func GetServiceStats(_ context.Context) models.ServiceStats {
 	var stats models.ServiceStats
 	stats.CoreStatistics = GetCoreStatistics()
@@ -71,6 +72,7 @@ func GetServiceStats(_ context.Context) models.ServiceStats {
 	wg.Wait()
 
 	stats.Health = GetServiceHealth(&stats)
+	stats.SampledAt = time.Now()
 
 	return stats
 }
@@ -0,0 +1,101 @@
+package core
+
+import (
+	"bytes"
+	"os"
+	"runtime/pprof"
+	"sync"
+	"time"
+
+	"github.com/iyashjayesh/monigo/internal/logger"
+)
+
+// FunctionProfileLabel is the pprof label carrying the function name on goroutines
+// running a traced call.
+const FunctionProfileLabel = "monigo_function"
+
+// maxCPUProfileSession bounds how long a shared CPU profile keeps admitting calls, so
+// constant traffic still produces profiles.
+const maxCPUProfileSession = 10 * time.Second
+
+// cpuProfiler runs one process-wide CPU profile shared by the sampled calls running at
+// the same time; Go only allows one. Traced calls carry the FunctionProfileLabel, so when
+// the last call leaves, the profile is split by label into one profile per function.
+// Samples of a traced call nested in another are attributed to the inner function.
+type cpuProfiler struct {
+	mu      sync.Mutex
+	active  int
+	started time.Time
+	buf     *bytes.Buffer
+	paths   map[string]string // CPU profile path per function of the session
+	writes  sync.WaitGroup    // sessions whose profiles are being written
+}
+
+var cpuProfiles = &cpuProfiler{}
+
+// join adds a sampled call of the named function to the running session, starting one
+// if needed. It reports false when the call can't be profiled: a session has run for too
+// long, or someone else is profiling the process.
+func (p *cpuProfiler) join(name, path string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.active == 0 {
+		p.buf = new(bytes.Buffer)
+		if err := pprof.StartCPUProfile(p.buf); err != nil {
+			logger.Log.Warn("failed to start CPU profile", "error", err)
+			return false
+		}
+		p.started = time.Now()
+		p.paths = make(map[string]string)
+	} else if time.Since(p.started) > maxCPUProfileSession {
+		return false
+	}
+	p.active++
+	p.paths[name] = path
+	return true
+}
+
+// leave ends a joined call. The last call of a session stops the profile; the profile of
+// every function in it is written in the background, so the call returns meanwhile.
+func (p *cpuProfiler) leave() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.active--
+	if p.active > 0 {
+		return
+	}
+	pprof.StopCPUProfile()
+
+	buf, paths := p.buf, p.paths
+	p.buf, p.paths = nil, nil
+	p.writes.Add(1)
+	go func() {
+		defer p.writes.Done()
+		writeFunctionCPUProfiles(buf.Bytes(), paths)
+	}()
+}
+
+// wait returns once the profiles of the sessions ended so far are written.
+func (p *cpuProfiler) wait() {
+	p.writes.Wait()
+}
+
+// writeFunctionCPUProfiles splits a session's profile by function and writes each part
+// to its path.
+func writeFunctionCPUProfiles(data []byte, paths map[string]string) {
+	names := make([]string, 0, len(paths))
+	for name := range paths {
+		names = append(names, name)
+	}
+	profiles, err := filterProfileByLabel(data, FunctionProfileLabel, names)
+	if err != nil {
+		logger.Log.Warn("failed to split CPU profile", "error", err)
+		return
+	}
+	for name, data := range profiles {
+		if err := os.WriteFile(paths[name], data, 0o644); err != nil {
+			logger.Log.Warn("failed to write CPU profile", "function", name, "error", err)
+		}
+	}
+}
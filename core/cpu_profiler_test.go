@@ -0,0 +1,130 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/pprof/profile"
+)
+
+// profileSampleLabels returns the FunctionProfileLabel value of every sample in a
+// gzipped profile, "" for unlabelled samples.
+func profileSampleLabels(t *testing.T, data []byte) []string {
+	t.Helper()
+	if len(data) < 2 || data[0] != 0x1f || data[1] != 0x8b {
+		t.Fatal("profile is not gzipped")
+	}
+	p, err := profile.ParseData(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	labels := make([]string, len(p.Sample))
+	for i, s := range p.Sample {
+		if v := s.Label[FunctionProfileLabel]; len(v) > 0 {
+			labels[i] = v[0]
+		}
+	}
+	return labels
+}
+
+func TestFilterProfileByLabel(t *testing.T) {
+	// A profile of four samples, labelled a, b, unlabelled and a.
+	fn := &profile.Function{ID: 1, Name: "main.work"}
+	loc := &profile.Location{ID: 1, Line: []profile.Line{{Function: fn, Line: 1}}}
+	p := &profile.Profile{
+		SampleType: []*profile.ValueType{{Type: "samples", Unit: "count"}},
+		PeriodType: &profile.ValueType{Type: "cpu", Unit: "nanoseconds"},
+		Location:   []*profile.Location{loc},
+		Function:   []*profile.Function{fn},
+	}
+	for _, value := range []string{"a", "b", "", "a"} {
+		s := &profile.Sample{Location: []*profile.Location{loc}, Value: []int64{1}}
+		if value != "" {
+			s.Label = map[string][]string{FunctionProfileLabel: {value}}
+		}
+		p.Sample = append(p.Sample, s)
+	}
+	var raw bytes.Buffer
+	if err := p.WriteUncompressed(&raw); err != nil {
+		t.Fatal(err)
+	}
+
+	profiles, err := filterProfileByLabel(raw.Bytes(), FunctionProfileLabel, []string{"a", "b", "c"})
+	if err != nil {
+		t.Fatalf("filterProfileByLabel() error = %v", err)
+	}
+	for value, want := range map[string]int{"a": 2, "b": 1, "c": 0} {
+		labels := profileSampleLabels(t, profiles[value])
+		if len(labels) != want {
+			t.Errorf("%s: expected %d samples, got %d", value, want, len(labels))
+		}
+		for _, l := range labels {
+			if l != value {
+				t.Errorf("%s: got a sample labelled %q", value, l)
+			}
+		}
+	}
+
+	if _, err := filterProfileByLabel([]byte{0xff}, FunctionProfileLabel, []string{"a"}); err == nil {
+		t.Error("expected an error for a malformed profile")
+	}
+}
+
+func TestConcurrentFunctionProfiles(t *testing.T) {
+	SetSamplingRate(1)
+
+	// Overlapping sampled calls share one CPU profile and each get their own samples.
+	var wg sync.WaitGroup
+	names := []string{"profiled.alpha", "profiled.beta", "profiled.gamma"}
+	for _, name := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			TraceNamed(context.Background(), name, func(context.Context) error {
+				burnCPU(200 * time.Millisecond)
+				return nil
+			})
+		}()
+	}
+	wg.Wait()
+	cpuProfiles.wait()
+
+	details := FunctionTraceDetails()
+	var total int
+	for _, name := range names {
+		m := details[name]
+		if m == nil || m.CPUProfileFilePath == "" {
+			t.Fatalf("%s: expected a CPU profile, got %+v", name, m)
+		}
+		data, err := os.ReadFile(m.CPUProfileFilePath)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		labels := profileSampleLabels(t, data)
+		for _, l := range labels {
+			if l != name {
+				t.Errorf("%s: found a sample of %q", name, l)
+			}
+		}
+		total += len(labels)
+	}
+	if total == 0 {
+		t.Error("expected CPU samples in the function profiles")
+	}
+}
+
+// burnCPU spins for d so the CPU profiler records samples.
+func burnCPU(d time.Duration) {
+	var x uint64
+	for end := time.Now().Add(d); time.Now().Before(end); {
+		for i := 0; i < 1000; i++ {
+			x += uint64(i) * 31
+		}
+	}
+	_ = fmt.Sprint(x)
+}
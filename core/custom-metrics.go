@@ -0,0 +1,11 @@
+package core
+
+import "github.com/iyashjayesh/monigo/internal/registry"
+
+var customMetrics = registry.NewRegistry()
+
+// CustomMetrics returns the registry holding the business metrics recorded through
+// Monigo.Counter, Monigo.Gauge and Monigo.Histogram.
+func CustomMetrics() *registry.Registry {
+	return customMetrics
+}
@@ -0,0 +1,203 @@
+package core
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/google/pprof/profile"
+	"github.com/iyashjayesh/monigo/models"
+)
+
+// Formats of FlameGraph.
+const (
+	FlameGraphD3         = "d3"         // the JSON tree of d3-flame-graph
+	FlameGraphSpeedscope = "speedscope" // a speedscope file
+	FlameGraphCollapsed  = "collapsed"  // "root;caller;callee value" lines, as flamegraph.pl reads
+)
+
+const speedscopeSchema = "https://www.speedscope.app/file-format-schema.json"
+
+// flameStack is a distinct stack of a profile, root first, with the total value of its
+// samples.
+type flameStack struct {
+	key    string
+	frames []profileFrame
+	value  int64
+}
+
+// FlameGraph converts a pprof profile into a flame graph named name, in one of the
+// FlameGraph formats; empty selects FlameGraphD3. sampleType selects the values, such as
+// "alloc_space" of a heap profile, or the default ones when empty. With a focus, only the
+// samples labelled FunctionProfileLabel=focus are kept.
+func FlameGraph(data []byte, name, focus, sampleType, format string) ([]byte, error) {
+	if !IsFlameGraphFormat(format) {
+		return nil, fmt.Errorf("[MoniGo] unknown flame graph format %q", format)
+	}
+	p, err := profile.ParseData(data)
+	if err != nil {
+		return nil, err
+	}
+	return flameGraph(p, name, focus, sampleType, format)
+}
+
+func flameGraph(p *profile.Profile, name, focus, sampleType, format string) ([]byte, error) {
+	idx, err := sampleIndex(p, sampleType)
+	if err != nil {
+		return nil, err
+	}
+
+	stacks := flameStacks(p, idx, focus)
+	switch format {
+	case FlameGraphSpeedscope:
+		return json.Marshal(speedscopeFile(name, p.SampleType[idx], stacks))
+	case FlameGraphCollapsed:
+		return collapsedStacks(stacks), nil
+	}
+	return json.Marshal(flameTree(name, stacks))
+}
+
+// IsFlameGraphFormat reports whether FlameGraph renders format.
+func IsFlameGraphFormat(format string) bool {
+	switch format {
+	case "", FlameGraphD3, FlameGraphSpeedscope, FlameGraphCollapsed:
+		return true
+	}
+	return false
+}
+
+// flameStacks merges the samples of p with the same stack, sorted by stack.
+func flameStacks(p *profile.Profile, idx int, focus string) []flameStack {
+	byKey := make(map[string]*flameStack)
+	var frames []profileFrame
+	var key strings.Builder
+	for _, s := range p.Sample {
+		if focus != "" && !slices.Contains(s.Label[FunctionProfileLabel], focus) {
+			continue
+		}
+		v := s.Value[idx]
+		if v == 0 || len(s.Location) == 0 {
+			continue
+		}
+
+		frames = sampleFrames(frames[:0], s)
+		slices.Reverse(frames)
+		key.Reset()
+		for _, f := range frames {
+			key.WriteString(f.name)
+			key.WriteByte(';')
+		}
+		st := byKey[key.String()]
+		if st == nil {
+			st = &flameStack{key: key.String(), frames: slices.Clone(frames)}
+			byKey[st.key] = st
+		}
+		st.value += v
+	}
+
+	stacks := make([]flameStack, 0, len(byKey))
+	for _, st := range byKey {
+		// Differences of profiles can leave stacks without a positive value.
+		if st.value > 0 {
+			stacks = append(stacks, *st)
+		}
+	}
+	slices.SortFunc(stacks, func(a, b flameStack) int { return strings.Compare(a.key, b.key) })
+	return stacks
+}
+
+// flameTree merges stacks into a tree under a root named name, children sorted by name.
+func flameTree(name string, stacks []flameStack) *models.FlameNode {
+	root := &models.FlameNode{Name: name}
+	children := make(map[*models.FlameNode]map[string]*models.FlameNode)
+	for _, st := range stacks {
+		root.Value += st.value
+		n := root
+		for _, f := range st.frames {
+			byName := children[n]
+			if byName == nil {
+				byName = make(map[string]*models.FlameNode)
+				children[n] = byName
+			}
+			c := byName[f.name]
+			if c == nil {
+				c = &models.FlameNode{Name: f.name}
+				byName[f.name] = c
+				n.Children = append(n.Children, c)
+			}
+			c.Value += st.value
+			n = c
+		}
+	}
+	for n := range children {
+		slices.SortFunc(n.Children, func(a, b *models.FlameNode) int { return cmp.Compare(a.Name, b.Name) })
+	}
+	return root
+}
+
+// speedscopeFile converts stacks into a sampled speedscope profile, one weighted sample
+// per stack.
+func speedscopeFile(name string, st *profile.ValueType, stacks []flameStack) models.SpeedscopeFile {
+	f := models.SpeedscopeFile{
+		Schema:   speedscopeSchema,
+		Name:     name,
+		Exporter: "monigo",
+		Shared:   models.SpeedscopeShared{Frames: []models.SpeedscopeFrame{}},
+	}
+	p := models.SpeedscopeProfile{
+		Type:    "sampled",
+		Name:    strings.TrimSpace(name + " " + st.Type),
+		Unit:    speedscopeUnit(st.Unit),
+		Samples: make([][]int, 0, len(stacks)),
+		Weights: make([]int64, 0, len(stacks)),
+	}
+
+	frames := make(map[[2]string]int)
+	for _, s := range stacks {
+		sample := make([]int, len(s.frames))
+		for i, fr := range s.frames {
+			key := [2]string{fr.name, fr.file}
+			id, ok := frames[key]
+			if !ok {
+				id = len(f.Shared.Frames)
+				frames[key] = id
+				f.Shared.Frames = append(f.Shared.Frames, models.SpeedscopeFrame{Name: fr.name, File: fr.file})
+			}
+			sample[i] = id
+		}
+		p.Samples = append(p.Samples, sample)
+		p.Weights = append(p.Weights, s.value)
+		p.EndValue += s.value
+	}
+	f.Profiles = []models.SpeedscopeProfile{p}
+	return f
+}
+
+// speedscopeUnit maps a pprof unit to one of speedscope's, "none" for counts.
+func speedscopeUnit(unit string) string {
+	switch unit {
+	case "nanoseconds", "microseconds", "milliseconds", "seconds", "bytes":
+		return unit
+	}
+	return "none"
+}
+
+func collapsedStacks(stacks []flameStack) []byte {
+	var b bytes.Buffer
+	for _, s := range stacks {
+		for i, f := range s.frames {
+			if i > 0 {
+				b.WriteByte(';')
+			}
+			b.WriteString(f.name)
+		}
+		b.WriteByte(' ')
+		b.WriteString(strconv.FormatInt(s.value, 10))
+		b.WriteByte('\n')
+	}
+	return b.Bytes()
+}
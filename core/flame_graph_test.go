@@ -0,0 +1,115 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/iyashjayesh/monigo/models"
+)
+
+func TestFlameStacksCollapsed(t *testing.T) {
+	p := testProfile()
+	want := "main.main 60000000\n" +
+		"main.main;main.work;main.leaf 30000000\n" +
+		"main.main;main.work;main.work 10000000\n"
+	if got := string(collapsedStacks(flameStacks(p, 1, ""))); got != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, got)
+	}
+
+	want = "main.main;main.work;main.leaf 3\nmain.main;main.work;main.work 1\n"
+	if got := string(collapsedStacks(flameStacks(p, 0, "main.work"))); got != want {
+		t.Errorf("expected only the samples labelled main.work\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestFlameTree(t *testing.T) {
+	root := flameTree("cpu", flameStacks(testProfile(), 1, ""))
+	if root.Name != "cpu" || root.Value != 100e6 || len(root.Children) != 1 {
+		t.Fatalf("unexpected root %+v", root)
+	}
+	main := root.Children[0]
+	if main.Name != "main.main" || main.Value != 100e6 || len(main.Children) != 1 {
+		t.Fatalf("unexpected main.main %+v", main)
+	}
+	work := main.Children[0]
+	if work.Value != 40e6 || len(work.Children) != 2 {
+		t.Fatalf("unexpected main.work %+v", work)
+	}
+	if work.Children[0].Name != "main.leaf" || work.Children[0].Value != 30e6 || work.Children[1].Name != "main.work" || work.Children[1].Value != 10e6 {
+		t.Errorf("expected main.leaf and the recursive main.work by name, got %+v %+v", work.Children[0], work.Children[1])
+	}
+}
+
+func TestSpeedscopeFile(t *testing.T) {
+	p := testProfile()
+	f := speedscopeFile("main.work", p.SampleType[1], flameStacks(p, 1, ""))
+	if f.Schema != speedscopeSchema || len(f.Profiles) != 1 {
+		t.Fatalf("unexpected file %+v", f)
+	}
+	names := make([]string, len(f.Shared.Frames))
+	for i, fr := range f.Shared.Frames {
+		names[i] = fr.Name
+	}
+	if !slices.Equal(names, []string{"main.main", "main.work", "main.leaf"}) {
+		t.Errorf("unexpected frames %v", names)
+	}
+
+	sp := f.Profiles[0]
+	if sp.Type != "sampled" || sp.Unit != "nanoseconds" || sp.EndValue != 100e6 {
+		t.Errorf("unexpected profile %+v", sp)
+	}
+	wantSamples := [][]int{{0}, {0, 1, 2}, {0, 1, 1}}
+	if !slices.EqualFunc(sp.Samples, wantSamples, slices.Equal) || !slices.Equal(sp.Weights, []int64{60e6, 30e6, 10e6}) {
+		t.Errorf("unexpected samples %v weighted %v", sp.Samples, sp.Weights)
+	}
+	if speedscopeUnit("count") != "none" {
+		t.Error("expected counts in speedscope's none unit")
+	}
+}
+
+var flameGraphSink [][]byte
+
+func TestFlameGraphOfProcessProfile(t *testing.T) {
+	// Allocate well above the sampling rate of the heap profile.
+	for range 64 {
+		flameGraphSink = append(flameGraphSink, make([]byte, 1<<20))
+	}
+	data, err := ProcessProfile(context.Background(), ProcessProfileHeap, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := FlameGraph(data, "heap", "", "alloc_space", FlameGraphD3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var root models.FlameNode
+	if err := json.Unmarshal(out, &root); err != nil {
+		t.Fatal(err)
+	}
+	if root.Name != "heap" || root.Value <= 0 || len(root.Children) == 0 {
+		t.Errorf("expected allocations under the heap root, got %+v", root)
+	}
+
+	out, err = FlameGraph(data, "heap", "", "", FlameGraphSpeedscope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var f models.SpeedscopeFile
+	if err := json.Unmarshal(out, &f); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Profiles) != 1 || f.Profiles[0].Name != "heap inuse_space" || f.Profiles[0].Unit != "bytes" {
+		t.Errorf("expected the in-use bytes by default, got %+v", f.Profiles)
+	}
+
+	if _, err := FlameGraph(data, "heap", "", "cpu", ""); !errors.Is(err, ErrNoSampleType) {
+		t.Errorf("expected ErrNoSampleType, got %v", err)
+	}
+	if _, err := FlameGraph(data, "heap", "", "", "svg"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
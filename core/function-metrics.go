@@ -3,11 +3,10 @@ package core
 import (
 	"context"
 	"fmt"
-	"os"
-	"os/exec"
-	"path/filepath"
 	"reflect"
 	"runtime"
+	"runtime/debug"
+	"runtime/pprof"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -15,18 +14,38 @@ import (
 
 	"github.com/iyashjayesh/monigo/common"
 	"github.com/iyashjayesh/monigo/internal/logger"
+	"github.com/iyashjayesh/monigo/internal/registry"
 	"github.com/iyashjayesh/monigo/models"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const maxTrackedFunctions = 10000
 
+// Names of the per-function series published to the registry set with SetFunctionRegistry.
+// Every series carries a "function" label.
+const (
+	FunctionCallsMetric          = "monigo_function_calls_total"
+	FunctionExecutionTimeMetric  = "monigo_function_execution_seconds"
+	FunctionMemoryDeltaMetric    = "monigo_function_memory_delta_bytes"
+	FunctionGoroutineDeltaMetric = "monigo_function_goroutine_delta"
+	FunctionErrorsMetric         = "monigo_function_errors_total" // panics included
+	FunctionPanicsMetric         = "monigo_function_panics_total"
+)
+
+// FunctionDurationBuckets are the upper bounds, in seconds, of the execution-time
+// histogram kept for every traced function.
+var FunctionDurationBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
 var (
-	functionMetrics = make(map[string]*models.FunctionMetrics)
+	functionMetrics = make(map[string]*functionStats)
 	basePath        = common.GetBasePath()
 
 	samplingRate atomic.Int64
 	callCounters = make(map[string]uint64)
 	countersMu   sync.Mutex
+
+	functionRegistry atomic.Pointer[registry.Registry]
 )
 
 func init() {
@@ -41,10 +60,23 @@ func SetSamplingRate(rate int) {
 	samplingRate.Store(int64(rate))
 }
 
-// TraceFunction traces the function and captures the metrics
-func TraceFunction(_ context.Context, f func()) {
+// SetFunctionRegistry publishes every traced call into r as series labelled with the
+// function name. Passing nil stops publishing.
+func SetFunctionRegistry(r *registry.Registry) {
+	if r != nil {
+		r.ConfigureHistogram(FunctionExecutionTimeMetric, registry.HistogramOpts{Buckets: FunctionDurationBuckets})
+	}
+	functionRegistry.Store(r)
+}
+
+// TraceFunction traces the function and captures the metrics. The call is recorded as a
+// span, a child of any span in ctx, from the provider set with SetTracerProvider.
+func TraceFunction(ctx context.Context, f func()) {
 	name := strings.ReplaceAll(runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name(), "/", "-")
-	executeFunctionWithProfiling(name, f)
+	executeFunctionWithProfiling(ctx, name, func(context.Context) error {
+		f()
+		return nil
+	})
 }
 
 // FunctionTraceDetails returns a snapshot copy of the function trace details (thread-safe)
@@ -52,16 +84,17 @@ func FunctionTraceDetails() map[string]*models.FunctionMetrics {
 	mu.Lock()
 	defer mu.Unlock()
 
+	now := time.Now()
 	result := make(map[string]*models.FunctionMetrics, len(functionMetrics))
 	for k, v := range functionMetrics {
-		copied := *v
-		result[k] = &copied
+		result[k] = v.snapshot(now)
 	}
 	return result
 }
 
-// TraceFunctionWithArgs traces a function with parameters and captures the metrics
-func TraceFunctionWithArgs(_ context.Context, f interface{}, args ...interface{}) {
+// TraceFunctionWithArgs traces a function with parameters and captures the metrics.
+// Arguments that are ctx itself are replaced by the context of the call's span.
+func TraceFunctionWithArgs(ctx context.Context, f interface{}, args ...interface{}) {
 	fnValue := reflect.ValueOf(f)
 	if fnValue.Kind() != reflect.Func {
 		logger.Log.Error("first argument must be a function", "type", fmt.Sprintf("%T", f))
@@ -89,8 +122,8 @@ func TraceFunctionWithArgs(_ context.Context, f interface{}, args ...interface{}
 
 	name := generateFunctionName(fnValue, fnType)
 
-	executeFunctionWithProfiling(name, func() {
-		fnValue.Call(argValues)
+	executeFunctionWithProfiling(ctx, name, func(spanCtx context.Context) error {
+		return errorResult(fnValue.Call(withSpanContext(argValues, ctx, spanCtx)))
 	})
 }
 
@@ -103,8 +136,9 @@ func TraceFunctionWithReturn(ctx context.Context, f interface{}, args ...interfa
 	return nil
 }
 
-// TraceFunctionWithReturns traces a function and returns all results.
-func TraceFunctionWithReturns(_ context.Context, f interface{}, args ...interface{}) []interface{} {
+// TraceFunctionWithReturns traces a function and returns all results. A non-nil error
+// as the last result marks the call's span as failed.
+func TraceFunctionWithReturns(ctx context.Context, f interface{}, args ...interface{}) []interface{} {
 	fnValue := reflect.ValueOf(f)
 	if fnValue.Kind() != reflect.Func {
 		logger.Log.Error("first argument must be a function", "type", fmt.Sprintf("%T", f))
@@ -133,12 +167,13 @@ func TraceFunctionWithReturns(_ context.Context, f interface{}, args ...interfac
 	name := generateFunctionName(fnValue, fnType)
 
 	var results []interface{}
-	executeFunctionWithProfiling(name, func() {
-		reflectResults := fnValue.Call(argValues)
+	executeFunctionWithProfiling(ctx, name, func(spanCtx context.Context) error {
+		reflectResults := fnValue.Call(withSpanContext(argValues, ctx, spanCtx))
 		results = make([]interface{}, len(reflectResults))
 		for i, result := range reflectResults {
 			results[i] = result.Interface()
 		}
+		return errorResult(reflectResults)
 	})
 
 	return results
@@ -166,19 +201,70 @@ func generateFunctionName(fnValue reflect.Value, fnType reflect.Type) string {
 	return baseName
 }
 
-// sanitizeFileName replaces characters that are invalid in file paths.
-func sanitizeFileName(name string) string {
-	replacer := strings.NewReplacer(
-		"(", "_", ")", "_",
-		"<", "_", ">", "_",
-		":", "_", "*", "_",
-		"?", "_", "\"", "_",
-		"|", "_", " ", "_",
-	)
-	return replacer.Replace(name)
+// TraceNamed traces fn under the given name, without reflection. fn receives the context
+// of the call's span; the error it returns is recorded and returned.
+func TraceNamed(ctx context.Context, name string, fn func(context.Context) error) error {
+	var err error
+	executeFunctionWithProfiling(ctx, name, func(spanCtx context.Context) error {
+		err = fn(spanCtx)
+		return err
+	})
+	return err
+}
+
+// StartFunction starts a traced call of the given name, recorded when the returned
+// function is called. The call's span context is not handed out, so use TraceNamed when
+// spans started inside the call should nest under it. Deferring the returned function
+// also records a panic before re-raising it:
+//
+//	done := core.StartFunction(ctx, "checkout")
+//	defer done()
+func StartFunction(ctx context.Context, name string) func() {
+	call, _ := beginFunctionCall(ctx, name)
+	return func() {
+		r := recover()
+		call.end(nil, r)
+		if r != nil {
+			panic(r)
+		}
+	}
+}
+
+// executeFunctionWithProfiling runs fn inside a span named after the function, passing it
+// the span's context, and records the call. An error returned by fn counts as a failed
+// call; a panic is recorded with its stack, after profiling has stopped, and re-raised.
+func executeFunctionWithProfiling(ctx context.Context, name string, fn func(context.Context) error) {
+	call, spanCtx := beginFunctionCall(ctx, name)
+	var callErr error
+	defer func() {
+		r := recover()
+		call.end(callErr, r)
+		if r != nil {
+			panic(r)
+		}
+	}()
+	callErr = fn(spanCtx)
+}
+
+// functionCall is a traced call in progress.
+type functionCall struct {
+	name              string
+	start             time.Time
+	span              trace.Span
+	profiled          bool
+	initialGoroutines int
+	memStatsBefore    runtime.MemStats
+	cpuProfiled       bool                 // joined a shared CPU profile
+	parentCtx         context.Context      // restores the goroutine's pprof labels
+	profile           models.ProfileRecord // paths of a profiled call's profiles
 }
 
-func executeFunctionWithProfiling(name string, fn func()) {
+// beginFunctionCall counts the call, starts profiling it when sampled and starts its span.
+func beginFunctionCall(ctx context.Context, name string) (*functionCall, context.Context) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
 	countersMu.Lock()
 	if len(callCounters) > maxTrackedFunctions {
 		// Evict oldest entries to prevent unbounded growth.
@@ -191,59 +277,99 @@ func executeFunctionWithProfiling(name string, fn func()) {
 	count := callCounters[name]
 	countersMu.Unlock()
 
-	shouldProfile := count%uint64(samplingRate.Load()) == 0
-
-	initialGoroutines := runtime.NumGoroutine()
-	var memStatsBefore runtime.MemStats
-	if shouldProfile {
-		runtime.ReadMemStats(&memStatsBefore)
+	c := &functionCall{
+		name:              name,
+		parentCtx:         ctx,
+		profiled:          count%uint64(samplingRate.Load()) == 0,
+		initialGoroutines: runtime.NumGoroutine(),
 	}
 
-	var cpuProfFilePath, memProfFilePath string
-	var cpuProfileFile *os.File
-
-	if shouldProfile {
-		folderPath := fmt.Sprintf("%s/profiles", basePath)
-		if err := os.MkdirAll(folderPath, os.ModePerm); err != nil {
-			logger.Log.Warn("failed to create profiles directory", "error", err)
-		}
+	if c.profiled {
+		runtime.ReadMemStats(&c.memStatsBefore)
 
-		safeName := sanitizeFileName(name)
-		cpuProfFilePath = filepath.Join(folderPath, fmt.Sprintf("%s_cpu.prof", safeName))
-		memProfFilePath = filepath.Join(folderPath, fmt.Sprintf("%s_mem.prof", safeName))
+		c.profile = storedProfiles.reserve(name, time.Now())
 
-		var err error
-		cpuProfileFile, err = StartCPUProfile(cpuProfFilePath)
-		if err != nil {
-			logger.Log.Warn("failed to start CPU profile", "error", err)
+		// The continuous profiler or a process capture, when running, already covers the call.
+		if !continuousProfiling() && !processCPUProfiling.Load() {
+			c.cpuProfiled = cpuProfiles.join(name, c.profile.CPUProfileFilePath)
+		}
+		if !c.cpuProfiled {
+			c.profile.CPUProfileFilePath = ""
 		}
 	}
 
-	start := time.Now()
-	fn()
-	elapsed := time.Since(start)
+	// As pprof.Do: label the goroutine, and those it starts, with the function name.
+	ctx = pprof.WithLabels(ctx, pprof.Labels(FunctionProfileLabel, name))
+	pprof.SetGoroutineLabels(ctx)
+
+	spanCtx, span := startFunctionSpan(ctx, name)
+	c.span = span
+	c.start = time.Now()
+	return c, spanCtx
+}
+
+// end stops profiling and records the call, which failed with callErr or panicked with r.
+func (c *functionCall) end(callErr error, r any) {
+	elapsed := time.Since(c.start)
+	pprof.SetGoroutineLabels(c.parentCtx)
 
-	if shouldProfile {
-		StopCPUProfile(cpuProfileFile)
-		if err := WriteHeapProfile(memProfFilePath); err != nil {
+	if c.profiled {
+		if c.cpuProfiled {
+			cpuProfiles.leave()
+		}
+		if err := WriteHeapProfile(c.profile.MemProfileFilePath); err != nil {
 			logger.Log.Warn("failed to write heap profile", "error", err)
 		}
 	}
 
-	finalGoroutines := runtime.NumGoroutine() - initialGoroutines
+	finalGoroutines := runtime.NumGoroutine() - c.initialGoroutines
 	if finalGoroutines < 0 {
 		finalGoroutines = 0
 	}
 
 	var memoryUsage uint64
-	if shouldProfile {
+	if c.profiled {
 		var memStatsAfter runtime.MemStats
 		runtime.ReadMemStats(&memStatsAfter)
-		if memStatsAfter.Alloc >= memStatsBefore.Alloc {
-			memoryUsage = memStatsAfter.Alloc - memStatsBefore.Alloc
+		if memStatsAfter.Alloc >= c.memStatsBefore.Alloc {
+			memoryUsage = memStatsAfter.Alloc - c.memStatsBefore.Alloc
 		}
 	}
 
+	var panicStack string
+	if r != nil {
+		callErr = panicError(r)
+		panicStack = string(debug.Stack())
+	}
+
+	c.span.SetAttributes(
+		attribute.Float64(SpanExecutionTimeAttribute, elapsed.Seconds()),
+		attribute.Int(SpanGoroutineDeltaAttribute, finalGoroutines),
+		attribute.Bool(SpanProfiledAttribute, c.profiled),
+	)
+	if c.profiled {
+		c.span.SetAttributes(attribute.Int64(SpanMemoryDeltaAttribute, int64(memoryUsage)))
+	}
+	if r != nil {
+		recordSpanPanic(c.span, callErr, panicStack)
+	} else {
+		recordSpanError(c.span, callErr)
+	}
+	c.span.End()
+
+	publishFunctionMetrics(c.name, elapsed, finalGoroutines, memoryUsage, c.profiled, callErr, r != nil)
+
+	if c.profiled {
+		c.profile.ExecutionTime = elapsed
+		c.profile.MemoryUsage = memoryUsage
+		c.profile.GoroutineCount = finalGoroutines
+		c.profile.Panicked = r != nil
+		if callErr != nil {
+			c.profile.Error = callErr.Error()
+		}
+		storedProfiles.add(c.profile)
+	}
+
 	mu.Lock()
 	defer mu.Unlock()
 
@@ -255,71 +381,92 @@ func executeFunctionWithProfiling(name string, fn func()) {
 		}
 	}
 
-	if m, exists := functionMetrics[name]; exists {
-		m.FunctionLastRanAt = start
-		m.ExecutionTime = elapsed
-		m.GoroutineCount = finalGoroutines
-		if shouldProfile {
-			m.MemoryUsage = memoryUsage
-			m.CPUProfileFilePath = cpuProfFilePath
-			m.MemProfileFilePath = memProfFilePath
+	stats, exists := functionMetrics[c.name]
+	if !exists {
+		stats = newFunctionStats()
+		functionMetrics[c.name] = stats
+	}
+	stats.record(c.start, elapsed, finalGoroutines, memoryUsage, c.profiled, callErr != nil)
+
+	m := &stats.metrics
+	m.FunctionLastRanAt = c.start
+	m.ExecutionTime = elapsed
+	m.GoroutineCount = finalGoroutines
+	if c.profiled {
+		m.MemoryUsage = memoryUsage
+		if c.cpuProfiled {
+			m.CPUProfileFilePath = c.profile.CPUProfileFilePath
 		}
-	} else {
-		functionMetrics[name] = &models.FunctionMetrics{
-			FunctionLastRanAt:  start,
-			ExecutionTime:      elapsed,
-			GoroutineCount:     finalGoroutines,
-			MemoryUsage:        memoryUsage,
-			CPUProfileFilePath: cpuProfFilePath,
-			MemProfileFilePath: memProfFilePath,
+		m.MemProfileFilePath = c.profile.MemProfileFilePath
+	}
+	// Until the first continuous cycle completes, the last profiled call's path is kept.
+	if path := ContinuousProfilePath(); path != "" && continuousProfiling() {
+		m.CPUProfileFilePath = path
+	}
+
+	m.CallCount++
+	m.TotalExecutionTime += elapsed
+	seconds := elapsed.Seconds()
+	for i, bound := range FunctionDurationBuckets {
+		if seconds <= bound {
+			m.ExecutionTimeBuckets[i]++
 		}
 	}
+
+	if callErr != nil {
+		m.ErrorCount++
+		m.LastError = callErr.Error()
+	}
+	if r != nil {
+		m.PanicCount++
+		m.LastPanicStack = panicStack
+	}
+	m.ErrorRate = float64(m.ErrorCount) / float64(m.CallCount)
 }
 
-// ViewFunctionMetrics generates the function metrics
-func ViewFunctionMetrics(name, reportType string, metrics *models.FunctionMetrics) models.FunctionTraceDetails {
-	_, err := exec.LookPath("go")
+// publishFunctionMetrics records a traced call in the function registry, if one is set.
+// Memory is only measured on profiled calls, so the memory gauge is left untouched otherwise.
+// Failed calls, panics included, also count as errors.
+func publishFunctionMetrics(name string, elapsed time.Duration, goroutines int, memoryUsage uint64, profiled bool, err error, panicked bool) {
+	r := functionRegistry.Load()
+	if r == nil {
+		return
+	}
+	labels := map[string]string{"function": name}
+	r.IncrementCounter(FunctionCallsMetric, 1, labels)
+	r.RecordHistogram(FunctionExecutionTimeMetric, elapsed.Seconds(), labels)
+	r.SetGauge(FunctionGoroutineDeltaMetric, float64(goroutines), labels)
+	if profiled {
+		r.SetGauge(FunctionMemoryDeltaMetric, float64(memoryUsage), labels)
+	}
 	if err != nil {
-		logger.Log.Warn("'go' command not found in PATH, pprof reports will be unavailable")
-		return models.FunctionTraceDetails{
-			FunctionName: name,
-			CoreProfile: models.Profiles{
-				CPU: "Error: 'go' command not found. pprof reports require the Go SDK.",
-				Mem: "Error: 'go' command not found. pprof reports require the Go SDK.",
-			},
-			FunctionCodeTrace: "Error: 'go' command not found.",
-		}
+		r.IncrementCounter(FunctionErrorsMetric, 1, labels)
 	}
-
-	executePprof := func(profileFilePath, reportType string) string {
-		if profileFilePath == "" {
-			return "Error: Profile file path is empty"
-		}
-		cmd := exec.Command("go", "tool", "pprof", "-"+reportType, profileFilePath)
-		output, err := cmd.CombinedOutput()
-		if err != nil {
-			return fmt.Sprintf("Error executing pprof: %v\nOutput: %s", err, string(output))
-		}
-		return string(output)
+	if panicked {
+		r.IncrementCounter(FunctionPanicsMetric, 1, labels)
 	}
+}
 
-	var codeStack string
-	if metrics.CPUProfileFilePath != "" {
-		codeStackView := exec.Command("go", "tool", "pprof", "-list", name, metrics.CPUProfileFilePath)
-		output, err := codeStackView.CombinedOutput()
-		if err != nil {
-			codeStack = fmt.Sprintf("Error generating code trace: %v\nOutput: %s", err, string(output))
-		} else {
-			codeStack = string(output)
-		}
+// ViewFunctionMetrics renders the profiles of metrics in-process, with no Go SDK needed.
+// The CPU report only keeps the samples labelled with the function: the profile may be
+// shared with other functions, such as a continuous one or a call nested in this one.
+// See formatProfileReport for the text views selected by reportType.
+func ViewFunctionMetrics(name, reportType string, metrics *models.FunctionMetrics) models.FunctionTraceDetails {
+	details := models.FunctionTraceDetails{FunctionName: name}
+
+	if cpu, err := readProfileReport(metrics.CPUProfileFilePath, name, name); err != nil {
+		details.CoreProfile.CPU = "Error: " + err.Error()
+	} else {
+		details.CPUReport = cpu
+		details.CoreProfile.CPU = formatProfileReport(cpu, reportType)
+		details.FunctionCodeTrace = formatProfileListing(cpu)
 	}
 
-	return models.FunctionTraceDetails{
-		FunctionName: name,
-		CoreProfile: models.Profiles{
-			CPU: executePprof(metrics.CPUProfileFilePath, reportType),
-			Mem: executePprof(metrics.MemProfileFilePath, reportType),
-		},
-		FunctionCodeTrace: codeStack,
+	if mem, err := readProfileReport(metrics.MemProfileFilePath, "", name); err != nil {
+		details.CoreProfile.Mem = "Error: " + err.Error()
+	} else {
+		details.MemReport = mem
+		details.CoreProfile.Mem = formatProfileReport(mem, reportType)
 	}
+	return details
 }
@@ -0,0 +1,169 @@
+package core
+
+import (
+	"time"
+
+	"github.com/iyashjayesh/monigo/internal/registry"
+	"github.com/iyashjayesh/monigo/internal/sketch"
+	"github.com/iyashjayesh/monigo/models"
+)
+
+// FunctionStatsWindow is the span of the rolling window summarised in
+// FunctionMetrics.Window and published to FunctionStats.
+const FunctionStatsWindow = 5 * time.Minute
+
+// functionWindowSlots splits the window; it rolls forward one slot at a time.
+const functionWindowSlots = 10
+
+// Names of the windowed series published to FunctionStats, labelled with "function".
+// Latency quantiles also carry a "quantile" label of 0.5, 0.9 or 0.99.
+const (
+	FunctionLatencyMetric      = "monigo_function_latency_seconds"
+	FunctionWindowCallsMetric  = "monigo_function_window_calls"
+	FunctionWindowErrorsMetric = "monigo_function_window_errors"
+)
+
+var functionStatsRegistry = registry.NewRegistry()
+
+// FunctionStats returns the registry holding the windowed latency quantiles, call and
+// error counts of every traced function, refreshed by the stats sampler and persisted
+// into storage so they can be charted over time.
+func FunctionStats() *registry.Registry {
+	return functionStatsRegistry
+}
+
+// functionStats holds everything recorded for a traced function.
+type functionStats struct {
+	metrics models.FunctionMetrics
+	latency *sketch.Sketch // seconds, every call
+
+	profiledCalls uint64
+	memoryTotal   uint64
+
+	window [functionWindowSlots]windowSlot
+}
+
+// windowSlot holds the calls that started in [start, start+slot width).
+type windowSlot struct {
+	start   time.Time
+	latency *sketch.Sketch
+	calls   uint64
+	errors  uint64
+}
+
+func newFunctionStats() *functionStats {
+	return &functionStats{
+		metrics: models.FunctionMetrics{ExecutionTimeBuckets: make([]uint64, len(FunctionDurationBuckets))},
+		latency: sketch.New(sketch.DefaultRelativeAccuracy),
+	}
+}
+
+// record adds a call started at start to the aggregates and its window slot.
+func (s *functionStats) record(start time.Time, elapsed time.Duration, goroutines int, memoryUsage uint64, profiled, failed bool) {
+	s.latency.Add(elapsed.Seconds())
+	s.metrics.MaxGoroutineCount = max(s.metrics.MaxGoroutineCount, goroutines)
+	if profiled {
+		s.profiledCalls++
+		s.memoryTotal += memoryUsage
+	}
+
+	width := FunctionStatsWindow / functionWindowSlots
+	slotStart := start.Truncate(width)
+	slot := &s.window[(slotStart.UnixNano()/int64(width))%functionWindowSlots]
+	if !slot.start.Equal(slotStart) {
+		if slot.latency == nil {
+			slot.latency = sketch.New(sketch.DefaultRelativeAccuracy)
+		}
+		slot.latency.Reset()
+		slot.start, slot.calls, slot.errors = slotStart, 0, 0
+	}
+	slot.latency.Add(elapsed.Seconds())
+	slot.calls++
+	if failed {
+		slot.errors++
+	}
+}
+
+// snapshot returns a copy of the metrics with the aggregates and the window ending at now.
+func (s *functionStats) snapshot(now time.Time) *models.FunctionMetrics {
+	m := s.metrics
+	m.ExecutionTimeBuckets = append([]uint64(nil), s.metrics.ExecutionTimeBuckets...)
+
+	m.MinExecutionTime = seconds(s.latency.Min())
+	m.MaxExecutionTime = seconds(s.latency.Max())
+	m.MeanExecutionTime = seconds(s.latency.Mean())
+	m.P50ExecutionTime = seconds(s.latency.Quantile(0.5))
+	m.P90ExecutionTime = seconds(s.latency.Quantile(0.9))
+	m.P99ExecutionTime = seconds(s.latency.Quantile(0.99))
+	if s.profiledCalls > 0 {
+		m.MeanMemoryUsage = s.memoryTotal / s.profiledCalls
+	}
+
+	latency := s.windowLatency(now)
+	m.Window = models.FunctionWindowStats{
+		Duration:          FunctionStatsWindow,
+		MeanExecutionTime: seconds(latency.Mean()),
+		MaxExecutionTime:  seconds(latency.Max()),
+		P50ExecutionTime:  seconds(latency.Quantile(0.5)),
+		P90ExecutionTime:  seconds(latency.Quantile(0.9)),
+		P99ExecutionTime:  seconds(latency.Quantile(0.99)),
+	}
+	m.Window.CallCount, m.Window.ErrorCount = s.windowCounts(now)
+	if m.Window.CallCount > 0 {
+		m.Window.ErrorRate = float64(m.Window.ErrorCount) / float64(m.Window.CallCount)
+	}
+	return &m
+}
+
+// inWindow reports whether slot holds calls of the window ending at now.
+func (slot *windowSlot) inWindow(now time.Time) bool {
+	return slot.calls > 0 && slot.start.After(now.Add(-FunctionStatsWindow))
+}
+
+func (s *functionStats) windowLatency(now time.Time) *sketch.Sketch {
+	merged := sketch.New(sketch.DefaultRelativeAccuracy)
+	for i := range s.window {
+		if s.window[i].inWindow(now) {
+			merged.Merge(s.window[i].latency)
+		}
+	}
+	return merged
+}
+
+func (s *functionStats) windowCounts(now time.Time) (calls, errors uint64) {
+	for i := range s.window {
+		if s.window[i].inWindow(now) {
+			calls += s.window[i].calls
+			errors += s.window[i].errors
+		}
+	}
+	return calls, errors
+}
+
+func seconds(v float64) time.Duration {
+	return time.Duration(v * float64(time.Second))
+}
+
+// publishFunctionStats writes the window of every traced function into FunctionStats.
+// Quantiles are only published for functions called within the window; those of a
+// function no longer called are removed.
+func publishFunctionStats() {
+	r := functionStatsRegistry
+	for name, m := range FunctionTraceDetails() {
+		labels := map[string]string{"function": name}
+		r.SetGauge(FunctionWindowCallsMetric, float64(m.Window.CallCount), labels)
+		r.SetGauge(FunctionWindowErrorsMetric, float64(m.Window.ErrorCount), labels)
+		for q, v := range map[string]time.Duration{
+			"0.5":  m.Window.P50ExecutionTime,
+			"0.9":  m.Window.P90ExecutionTime,
+			"0.99": m.Window.P99ExecutionTime,
+		} {
+			quantile := map[string]string{"function": name, "quantile": q}
+			if m.Window.CallCount == 0 {
+				r.DeleteSeries(FunctionLatencyMetric, quantile)
+				continue
+			}
+			r.SetGauge(FunctionLatencyMetric, v.Seconds(), quantile)
+		}
+	}
+}
@@ -2,7 +2,15 @@ package core
 
 import (
 	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"runtime/pprof"
+	"strings"
 	"testing"
+
+	"github.com/iyashjayesh/monigo/internal/registry"
+	"github.com/iyashjayesh/monigo/models"
 )
 
 func TestTraceFunction(t *testing.T) {
@@ -109,3 +117,219 @@ func TestFunctionTraceDetailsReturnsCopy(t *testing.T) {
 		t.Error("expected FunctionTraceDetails to return independent copies")
 	}
 }
+
+func TestTraceFunctionCallStatistics(t *testing.T) {
+	SetSamplingRate(1000)
+	defer SetSamplingRate(1)
+	fn := func(n int) int { return n * 2 }
+	for i := 0; i < 3; i++ {
+		TraceFunctionWithReturn(context.Background(), fn, i)
+	}
+
+	var m *models.FunctionMetrics
+	for name, v := range FunctionTraceDetails() {
+		if strings.Contains(name, "TestTraceFunctionCallStatistics") {
+			m = v
+		}
+	}
+	if m == nil {
+		t.Fatal("expected an entry for the traced function")
+	}
+	if m.CallCount != 3 {
+		t.Errorf("expected 3 calls, got %d", m.CallCount)
+	}
+	if len(m.ExecutionTimeBuckets) != len(FunctionDurationBuckets) {
+		t.Fatalf("expected %d buckets, got %d", len(FunctionDurationBuckets), len(m.ExecutionTimeBuckets))
+	}
+	// A trivial function finishes well within the largest bucket.
+	if got := m.ExecutionTimeBuckets[len(m.ExecutionTimeBuckets)-1]; got != 3 {
+		t.Errorf("expected all 3 calls in the last bucket, got %d", got)
+	}
+}
+
+func TestSetFunctionRegistry(t *testing.T) {
+	SetSamplingRate(1)
+	r := registry.NewRegistry()
+	SetFunctionRegistry(r)
+	defer SetFunctionRegistry(nil)
+
+	fn := func() {}
+	TraceFunction(context.Background(), fn)
+	TraceFunction(context.Background(), fn)
+
+	byName := map[string]*registry.MetricValue{}
+	for _, m := range r.GetAll() {
+		if m.Labels["function"] == "" {
+			t.Errorf("%s: missing function label", m.Name)
+		}
+		byName[m.Name] = m
+	}
+	for _, name := range []string{FunctionCallsMetric, FunctionExecutionTimeMetric, FunctionMemoryDeltaMetric, FunctionGoroutineDeltaMetric} {
+		if byName[name] == nil {
+			t.Errorf("missing %s", name)
+		}
+	}
+	if calls := byName[FunctionCallsMetric]; calls != nil && (calls.Value != 2 || calls.Type != registry.Counter) {
+		t.Errorf("expected a call counter of 2, got %+v", calls)
+	}
+	if h := byName[FunctionExecutionTimeMetric]; h != nil && (h.Histogram == nil || h.Histogram.Count != 2 || len(h.Histogram.Bounds) != len(FunctionDurationBuckets)) {
+		t.Errorf("expected an execution time histogram over FunctionDurationBuckets, got %+v", h)
+	}
+}
+
+// tracedFunction returns the metrics of the single traced function whose name contains substr.
+func tracedFunction(t *testing.T, substr string) *models.FunctionMetrics {
+	t.Helper()
+	for name, v := range FunctionTraceDetails() {
+		if strings.Contains(name, substr) {
+			return v
+		}
+	}
+	t.Fatalf("expected an entry for %s", substr)
+	return nil
+}
+
+func TestTraceFunctionErrors(t *testing.T) {
+	SetSamplingRate(1000)
+	defer SetSamplingRate(1)
+	r := registry.NewRegistry()
+	SetFunctionRegistry(r)
+	defer SetFunctionRegistry(nil)
+
+	fn := func(fail bool) (int, error) {
+		if fail {
+			return 0, errors.New("payment declined")
+		}
+		return 1, nil
+	}
+	for _, fail := range []bool{true, false, false, true} {
+		TraceFunctionWithReturns(context.Background(), fn, fail)
+	}
+
+	m := tracedFunction(t, "TestTraceFunctionErrors")
+	if m.CallCount != 4 || m.ErrorCount != 2 || m.PanicCount != 0 {
+		t.Errorf("expected 2 errors in 4 calls, got %+v", m)
+	}
+	if m.ErrorRate != 0.5 {
+		t.Errorf("expected an error rate of 0.5, got %v", m.ErrorRate)
+	}
+	if m.LastError != "payment declined" {
+		t.Errorf("expected the last error, got %q", m.LastError)
+	}
+
+	for _, mv := range r.GetAll() {
+		if mv.Name == FunctionErrorsMetric && mv.Value != 2 {
+			t.Errorf("expected %s of 2, got %v", FunctionErrorsMetric, mv.Value)
+		}
+		if mv.Name == FunctionPanicsMetric {
+			t.Errorf("expected no %s without panics", FunctionPanicsMetric)
+		}
+	}
+}
+
+func TestTraceFunctionPanicStopsProfiling(t *testing.T) {
+	SetSamplingRate(1)
+	r := registry.NewRegistry()
+	SetFunctionRegistry(r)
+	defer SetFunctionRegistry(nil)
+
+	func() {
+		defer func() {
+			if rec := recover(); rec != "out of stock" {
+				t.Errorf("expected the panic to propagate, got %v", rec)
+			}
+		}()
+		TraceFunction(context.Background(), func() { panic("out of stock") })
+	}()
+
+	// Starting a profile fails while another one is running.
+	if err := pprof.StartCPUProfile(io.Discard); err != nil {
+		t.Fatalf("expected the CPU profile to be stopped after a panic: %v", err)
+	}
+	pprof.StopCPUProfile()
+
+	m := tracedFunction(t, "TestTraceFunctionPanicStopsProfiling")
+	if m.CallCount != 1 || m.ErrorCount != 1 || m.PanicCount != 1 || m.ErrorRate != 1 {
+		t.Errorf("expected a single failed call, got %+v", m)
+	}
+	if m.LastError != "panic: out of stock" {
+		t.Errorf("expected the panic as last error, got %q", m.LastError)
+	}
+	if !strings.Contains(m.LastPanicStack, "TestTraceFunctionPanicStopsProfiling") {
+		t.Errorf("expected the panicking function in the stack, got %q", m.LastPanicStack)
+	}
+
+	byName := map[string]float64{}
+	for _, mv := range r.GetAll() {
+		byName[mv.Name] = mv.Value
+	}
+	if byName[FunctionErrorsMetric] != 1 || byName[FunctionPanicsMetric] != 1 || byName[FunctionCallsMetric] != 1 {
+		t.Errorf("expected the panic counted as a call, an error and a panic, got %v", byName)
+	}
+}
+
+func TestTraceNamed(t *testing.T) {
+	SetSamplingRate(1)
+	rec, tp := recordSpans(t)
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "request")
+	err := TraceNamed(ctx, "orders/charge", func(ctx context.Context) error {
+		_, inner := tp.Tracer("test").Start(ctx, "inner")
+		inner.End()
+		return errors.New("card expired")
+	})
+	parent.End()
+
+	if err == nil || err.Error() != "card expired" {
+		t.Errorf("expected the function's error, got %v", err)
+	}
+	m := FunctionTraceDetails()["orders/charge"]
+	if m == nil {
+		t.Fatal("expected metrics under the explicit name")
+	}
+	if m.CallCount != 1 || m.ErrorCount != 1 {
+		t.Errorf("expected one failed call, got %+v", m)
+	}
+	if dir := filepath.Base(filepath.Dir(m.CPUProfileFilePath)); !strings.HasPrefix(dir, "orders_charge-") {
+		t.Errorf("expected a profile directory without the name's slash, got %q", m.CPUProfileFilePath)
+	}
+
+	spans := rec.Ended()
+	if len(spans) != 3 || spans[1].Name() != "orders/charge" {
+		t.Fatalf("expected the inner, call and parent spans, got %d", len(spans))
+	}
+	if spans[0].Parent().SpanID() != spans[1].SpanContext().SpanID() || spans[1].Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Error("expected the call span between the parent and inner spans")
+	}
+}
+
+func TestStartFunction(t *testing.T) {
+	SetSamplingRate(1)
+
+	func() {
+		done := StartFunction(context.Background(), "orders.ship")
+		defer done()
+	}()
+	func() {
+		defer func() {
+			if r := recover(); r != "carrier down" {
+				t.Errorf("expected the panic to propagate, got %v", r)
+			}
+		}()
+		done := StartFunction(context.Background(), "orders.ship")
+		defer done()
+		panic("carrier down")
+	}()
+
+	m := FunctionTraceDetails()["orders.ship"]
+	if m == nil {
+		t.Fatal("expected metrics under the explicit name")
+	}
+	if m.CallCount != 2 || m.PanicCount != 1 || m.ErrorRate != 0.5 {
+		t.Errorf("expected one successful call and one panic, got %+v", m)
+	}
+	if err := pprof.StartCPUProfile(io.Discard); err != nil {
+		t.Fatalf("expected the CPU profile to be stopped: %v", err)
+	}
+	pprof.StopCPUProfile()
+}
@@ -0,0 +1,149 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+// within reports whether got is within 1% of want.
+func within(got, want time.Duration) bool {
+	return math.Abs(float64(got-want)) <= 0.01*float64(want)
+}
+
+func TestFunctionStatsAggregates(t *testing.T) {
+	s := newFunctionStats()
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i := 1; i <= 100; i++ {
+		s.record(start.Add(time.Duration(i)*time.Second), time.Duration(i)*time.Millisecond, i%10, uint64(i), i%2 == 0, i%4 == 0)
+	}
+
+	m := s.snapshot(start.Add(101 * time.Second))
+	if m.MinExecutionTime != time.Millisecond || m.MaxExecutionTime != 100*time.Millisecond {
+		t.Errorf("expected min 1ms and max 100ms, got %v and %v", m.MinExecutionTime, m.MaxExecutionTime)
+	}
+	if !within(m.MeanExecutionTime, 50500*time.Microsecond) {
+		t.Errorf("expected a mean of 50.5ms, got %v", m.MeanExecutionTime)
+	}
+	for got, want := range map[time.Duration]time.Duration{
+		m.P50ExecutionTime: 50 * time.Millisecond,
+		m.P90ExecutionTime: 90 * time.Millisecond,
+		m.P99ExecutionTime: 99 * time.Millisecond,
+	} {
+		if !within(got, want) {
+			t.Errorf("expected %v within 1%%, got %v", want, got)
+		}
+	}
+	if m.MaxGoroutineCount != 9 {
+		t.Errorf("expected a max goroutine delta of 9, got %d", m.MaxGoroutineCount)
+	}
+	if m.MeanMemoryUsage != 51 {
+		t.Errorf("expected a mean memory usage of 51 over the profiled calls, got %d", m.MeanMemoryUsage)
+	}
+
+	w := m.Window
+	if w.Duration != FunctionStatsWindow || w.CallCount != 100 || w.ErrorCount != 25 || w.ErrorRate != 0.25 {
+		t.Errorf("expected every call in the window, got %+v", w)
+	}
+	if !within(w.P99ExecutionTime, 99*time.Millisecond) {
+		t.Errorf("expected a window p99 of 99ms, got %v", w.P99ExecutionTime)
+	}
+}
+
+func TestFunctionStatsWindowRolls(t *testing.T) {
+	s := newFunctionStats()
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	s.record(start, time.Second, 0, 0, false, true)
+	s.record(start.Add(4*time.Minute), 10*time.Millisecond, 0, 0, false, false)
+
+	w := s.snapshot(start.Add(6 * time.Minute)).Window
+	if w.CallCount != 1 || w.ErrorCount != 0 || w.MaxExecutionTime != 10*time.Millisecond {
+		t.Errorf("expected only the call of the last 5 minutes, got %+v", w)
+	}
+	// A slot reused a window later starts empty.
+	s.record(start.Add(FunctionStatsWindow), 20*time.Millisecond, 0, 0, false, false)
+	w = s.snapshot(start.Add(FunctionStatsWindow + time.Second)).Window
+	if w.CallCount != 2 || w.MaxExecutionTime != 20*time.Millisecond {
+		t.Errorf("expected the recycled slot to drop its old call, got %+v", w)
+	}
+
+	w = s.snapshot(start.Add(time.Hour)).Window
+	if w.CallCount != 0 || w.P50ExecutionTime != 0 {
+		t.Errorf("expected an empty window, got %+v", w)
+	}
+	if m := s.snapshot(start.Add(time.Hour)); m.MaxExecutionTime != time.Second {
+		t.Errorf("expected lifetime aggregates to outlive the window, got max %v", m.MaxExecutionTime)
+	}
+}
+
+func TestPublishFunctionStats(t *testing.T) {
+	SetSamplingRate(1000)
+	defer SetSamplingRate(1)
+	for i := 0; i < 3; i++ {
+		TraceNamed(context.Background(), "stats.publish", func(context.Context) error {
+			if i == 0 {
+				return errors.New("retry")
+			}
+			return nil
+		})
+	}
+	publishFunctionStats()
+
+	quantiles := map[string]bool{}
+	var calls, errs float64
+	for _, m := range FunctionStats().GetAll() {
+		if m.Labels["function"] != "stats.publish" {
+			continue
+		}
+		switch m.Name {
+		case FunctionLatencyMetric:
+			quantiles[m.Labels["quantile"]] = true
+		case FunctionWindowCallsMetric:
+			calls = m.Value
+		case FunctionWindowErrorsMetric:
+			errs = m.Value
+		}
+	}
+	if calls != 3 || errs != 1 {
+		t.Errorf("expected 3 calls and 1 error in the window, got %v and %v", calls, errs)
+	}
+	for _, q := range []string{"0.5", "0.9", "0.99"} {
+		if !quantiles[q] {
+			t.Errorf("missing latency quantile %s", q)
+		}
+	}
+}
+
+func TestPublishFunctionStatsDropsExpiredQuantiles(t *testing.T) {
+	SetSamplingRate(1000)
+	defer SetSamplingRate(1)
+	TraceNamed(context.Background(), "stats.expire", func(context.Context) error { return nil })
+	publishFunctionStats()
+	if got := latencyQuantiles("stats.expire"); got != 3 {
+		t.Fatalf("expected 3 latency quantiles, got %d", got)
+	}
+
+	// Let the window expire.
+	mu.Lock()
+	s := functionMetrics["stats.expire"]
+	for i := range s.window {
+		s.window[i].start = s.window[i].start.Add(-2 * FunctionStatsWindow)
+	}
+	mu.Unlock()
+	publishFunctionStats()
+	if got := latencyQuantiles("stats.expire"); got != 0 {
+		t.Errorf("expected the latency quantiles removed once the window is empty, got %d", got)
+	}
+}
+
+func latencyQuantiles(function string) int {
+	n := 0
+	for _, m := range FunctionStats().GetAll() {
+		if m.Name == FunctionLatencyMetric && m.Labels["function"] == function {
+			n++
+		}
+	}
+	return n
+}
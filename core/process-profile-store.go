@@ -0,0 +1,103 @@
+package core
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/pprof/profile"
+	"github.com/iyashjayesh/monigo/models"
+)
+
+// DefaultMaxProcessProfiles is how many captured process profiles are kept.
+const DefaultMaxProcessProfiles = 20
+
+// processProfileStore keeps the last process profiles captured on demand under
+// dir/<id>_<kind>.{prof,trace}.
+type processProfileStore struct {
+	recordStore[models.ProcessProfileRecord]
+}
+
+var processProfiles = newProcessProfileStore(filepath.Join(basePath, "profiles", "process"), DefaultMaxProcessProfiles, DefaultMaxProfileStoreBytes)
+
+func newProcessProfileStore(dir string, maxCount int, maxBytes int64) *processProfileStore {
+	s := &processProfileStore{}
+	s.dir, s.maxCount, s.maxBytes = dir, maxCount, maxBytes
+	s.info = func(rec models.ProcessProfileRecord) recordInfo {
+		return recordInfo{id: rec.ID, capturedAt: rec.CapturedAt, size: rec.SizeBytes, files: []string{rec.FilePath}}
+	}
+	return s
+}
+
+// CaptureProcessProfile takes a profile of the process as ProcessProfile does, keeps it
+// in the data directory and returns its record.
+func CaptureProcessProfile(ctx context.Context, kind string, d time.Duration) (models.ProcessProfileRecord, error) {
+	rec := models.ProcessProfileRecord{Kind: kind, CapturedAt: time.Now()}
+	data, err := ProcessProfile(ctx, kind, d)
+	if err != nil {
+		return rec, err
+	}
+
+	switch kind {
+	case ProcessProfileCPU:
+		// The last continuous cycle has a length of its own.
+		if p, err := profile.ParseData(data); err == nil {
+			rec.Duration = time.Duration(p.DurationNanos)
+		}
+	case ProcessProfileTrace, ProcessProfileBlock, ProcessProfileMutex:
+		rec.Duration = d
+	}
+	return processProfiles.add(rec, data)
+}
+
+// ProcessProfiles returns the captured process profiles, newest first.
+func ProcessProfiles() []models.ProcessProfileRecord {
+	return processProfiles.newestFirst(nil)
+}
+
+// StoredProcessProfile returns the captured process profile with the given ID.
+func StoredProcessProfile(id string) (models.ProcessProfileRecord, bool) {
+	return processProfiles.find(id)
+}
+
+// between returns the records overlapping start to end, oldest first.
+func (s *processProfileStore) between(start, end time.Time) []models.ProcessProfileRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.readLocked()
+
+	out := make([]models.ProcessProfileRecord, 0)
+	for _, rec := range s.records {
+		if rec.CapturedAt.After(end) || rec.CapturedAt.Add(rec.Duration).Before(start) {
+			continue
+		}
+		out = append(out, rec)
+	}
+	return out
+}
+
+// add writes data as the file of rec, under a new ID, then drops the profiles beyond the
+// limits.
+func (s *processProfileStore) add(rec models.ProcessProfileRecord, data []byte) (models.ProcessProfileRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loadLocked()
+
+	rec.ID = s.nextIDLocked(rec.CapturedAt)
+	ext := ".prof"
+	if rec.Kind == ProcessProfileTrace {
+		ext = ".trace"
+	}
+	rec.FilePath = filepath.Join(s.dir, rec.ID+"_"+rec.Kind+ext)
+	rec.SizeBytes = int64(len(data))
+
+	if err := os.MkdirAll(s.dir, os.ModePerm); err != nil {
+		return rec, err
+	}
+	if err := os.WriteFile(rec.FilePath, data, 0o644); err != nil {
+		return rec, err
+	}
+	s.addLocked(rec)
+	return rec, nil
+}
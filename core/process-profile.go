@@ -0,0 +1,165 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Kinds of ProcessProfile. All but ProcessProfileTrace are pprof profiles; the execution
+// trace is read with go tool trace.
+const (
+	ProcessProfileCPU       = "cpu"
+	ProcessProfileHeap      = "heap"
+	ProcessProfileAllocs    = "allocs"
+	ProcessProfileGoroutine = "goroutine"
+	ProcessProfileBlock     = "block"
+	ProcessProfileMutex     = "mutex"
+	ProcessProfileTrace     = "trace"
+)
+
+// MaxProcessProfileDuration caps how long ProcessProfile profiles the process.
+const MaxProcessProfileDuration = time.Minute
+
+// Sampling of block and mutex events while ProcessProfile records them.
+const (
+	processBlockProfileRate     = int(10 * time.Microsecond) // a blocking event per 10µs blocked
+	processMutexProfileFraction = 100                        // 1 in 100 contention events
+)
+
+// ErrProfilerBusy is returned by ProcessProfile when the process is already being
+// profiled that way, by sampled calls or another capture.
+var ErrProfilerBusy = errors.New("[MoniGo] the profiler is already in use")
+
+var (
+	// processCPUProfiling is set while ProcessProfile profiles the CPU, which covers the
+	// sampled calls running meanwhile.
+	processCPUProfiling atomic.Bool
+
+	// processEventProfiling serializes the block and mutex captures, which set the
+	// sampling rates of the runtime.
+	processEventProfiling sync.Mutex
+)
+
+// ProcessProfile returns a profile of the whole process: of its CPU or an execution trace
+// over d; the blocking or mutex contention events recorded over d, or so far when d is 0;
+// or a snapshot of the heap, the allocations or the goroutines. While the continuous
+// profiler runs, the CPU profile is its last complete cycle.
+func ProcessProfile(ctx context.Context, kind string, d time.Duration) ([]byte, error) {
+	switch kind {
+	case ProcessProfileCPU:
+		if path := ContinuousProfilePath(); path != "" && continuousProfiling() {
+			return os.ReadFile(path)
+		}
+		if err := checkProcessProfileDuration(d); err != nil {
+			return nil, err
+		}
+		return profileProcessCPU(ctx, d)
+	case ProcessProfileTrace:
+		if err := checkProcessProfileDuration(d); err != nil {
+			return nil, err
+		}
+		return traceProcess(ctx, d)
+	case ProcessProfileBlock, ProcessProfileMutex:
+		if d != 0 {
+			if err := checkProcessProfileDuration(d); err != nil {
+				return nil, err
+			}
+			if err := recordProcessEvents(ctx, kind, d); err != nil {
+				return nil, err
+			}
+		}
+		return lookupProfile(kind)
+	case ProcessProfileHeap, ProcessProfileAllocs:
+		runtime.GC() // Get up-to-date statistics
+		return lookupProfile(kind)
+	case ProcessProfileGoroutine:
+		return lookupProfile(kind)
+	}
+	return nil, fmt.Errorf("[MoniGo] unknown process profile %q", kind)
+}
+
+func checkProcessProfileDuration(d time.Duration) error {
+	if d <= 0 || d > MaxProcessProfileDuration {
+		return fmt.Errorf("[MoniGo] profile duration must be between 0 and %s", MaxProcessProfileDuration)
+	}
+	return nil
+}
+
+func lookupProfile(kind string) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := pprof.Lookup(kind).WriteTo(&buf, 0); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// sleepContext waits for d, or returns the error of ctx when it's done first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func profileProcessCPU(ctx context.Context, d time.Duration) ([]byte, error) {
+	if !processCPUProfiling.CompareAndSwap(false, true) {
+		return nil, ErrProfilerBusy
+	}
+	defer processCPUProfiling.Store(false)
+
+	var buf bytes.Buffer
+	if err := pprof.StartCPUProfile(&buf); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrProfilerBusy, err)
+	}
+	err := sleepContext(ctx, d)
+	pprof.StopCPUProfile()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func traceProcess(ctx context.Context, d time.Duration) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := trace.Start(&buf); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrProfilerBusy, err)
+	}
+	err := sleepContext(ctx, d)
+	trace.Stop()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// recordProcessEvents samples the block or mutex events of the process for d. Mutex
+// sampling set by the application is kept; block sampling is turned off afterwards, as
+// the runtime doesn't report its rate.
+func recordProcessEvents(ctx context.Context, kind string, d time.Duration) error {
+	if !processEventProfiling.TryLock() {
+		return ErrProfilerBusy
+	}
+	defer processEventProfiling.Unlock()
+
+	if kind == ProcessProfileBlock {
+		runtime.SetBlockProfileRate(processBlockProfileRate)
+		defer runtime.SetBlockProfileRate(0)
+	} else if runtime.SetMutexProfileFraction(-1) == 0 {
+		runtime.SetMutexProfileFraction(processMutexProfileFraction)
+		defer runtime.SetMutexProfileFraction(0)
+	}
+	return sleepContext(ctx, d)
+}
@@ -0,0 +1,137 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/pprof/profile"
+	"github.com/iyashjayesh/monigo/models"
+)
+
+func TestProcessProfileCPU(t *testing.T) {
+	data, err := ProcessProfile(context.Background(), ProcessProfileCPU, 50*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := profile.ParseData(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.DurationNanos <= 0 {
+		t.Errorf("expected a CPU profile with a duration, got %d", p.DurationNanos)
+	}
+
+	if _, err := ProcessProfile(context.Background(), ProcessProfileCPU, 0); err == nil {
+		t.Error("expected an error without a duration")
+	}
+	if _, err := ProcessProfile(context.Background(), "threads", 0); err == nil {
+		t.Error("expected an error for an unknown profile")
+	}
+
+	// A capture is refused while another one runs.
+	processCPUProfiling.Store(true)
+	_, err = ProcessProfile(context.Background(), ProcessProfileCPU, time.Second)
+	processCPUProfiling.Store(false)
+	if !errors.Is(err, ErrProfilerBusy) {
+		t.Errorf("expected ErrProfilerBusy, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := ProcessProfile(ctx, ProcessProfileCPU, time.Second); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the capture to stop with its context, got %v", err)
+	}
+}
+
+func TestProcessProfileEvents(t *testing.T) {
+	var mu sync.Mutex
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range 1000 {
+			mu.Lock()
+			runtime.Gosched()
+			mu.Unlock()
+		}
+	}()
+
+	data, err := ProcessProfile(context.Background(), ProcessProfileMutex, 50*time.Millisecond)
+	<-done
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := profile.ParseData(data); err != nil {
+		t.Fatal(err)
+	}
+	if runtime.SetMutexProfileFraction(-1) != 0 {
+		t.Error("expected mutex sampling to be turned off after the capture")
+	}
+
+	data, err = ProcessProfile(context.Background(), ProcessProfileTrace, 20*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) == 0 {
+		t.Error("expected an execution trace")
+	}
+
+	if _, err := ProcessProfile(context.Background(), ProcessProfileBlock, 0); err != nil {
+		t.Errorf("expected a snapshot of the block profile, got %v", err)
+	}
+}
+
+func TestProcessProfileStore(t *testing.T) {
+	dir := t.TempDir()
+	s := newProcessProfileStore(dir, 2, 1000)
+	now := time.Now()
+
+	add := func(kind string, size int) models.ProcessProfileRecord {
+		t.Helper()
+		rec, err := s.add(models.ProcessProfileRecord{Kind: kind, CapturedAt: now}, make([]byte, size))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rec
+	}
+	a := add(ProcessProfileHeap, 100)
+	b := add(ProcessProfileTrace, 100)
+	c := add(ProcessProfileCPU, 100)
+
+	if a.ID == b.ID || filepath.Ext(b.FilePath) != ".trace" || c.SizeBytes != 100 {
+		t.Fatalf("unexpected records %+v %+v", a, b)
+	}
+	if len(s.records) != 2 || s.records[0].ID != b.ID {
+		t.Fatalf("expected the oldest profile dropped beyond the count limit, got %+v", s.records)
+	}
+	if _, err := os.Stat(a.FilePath); !os.IsNotExist(err) {
+		t.Error("expected the file of the dropped profile removed")
+	}
+
+	// The newest profile stays even beyond the disk budget.
+	d := add(ProcessProfileAllocs, 2000)
+	if len(s.records) != 1 || s.records[0].ID != d.ID {
+		t.Errorf("expected only the newest profile kept, got %+v", s.records)
+	}
+
+	reloaded := newProcessProfileStore(dir, 2, 1000)
+	reloaded.loadLocked()
+	if len(reloaded.records) != 1 || reloaded.records[0].ID != d.ID || reloaded.lastID < d.CapturedAt.UnixNano() {
+		t.Errorf("expected the index reloaded, got %+v", reloaded.records)
+	}
+
+	// Profiles that expired since the last capture are dropped when the store is read.
+	e := add(ProcessProfileHeap, 10)
+	s.records[0].CapturedAt = now.Add(-30 * 24 * time.Hour)
+	if got := s.newestFirst(nil); len(got) != 1 || got[0].ID != e.ID {
+		t.Errorf("expected the expired profile dropped on read, got %+v", got)
+	}
+	if _, err := os.Stat(d.FilePath); !os.IsNotExist(err) {
+		t.Error("expected the file of the expired profile removed")
+	}
+}
@@ -0,0 +1,380 @@
+package core
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/pprof/profile"
+	"github.com/iyashjayesh/monigo/models"
+)
+
+// profileReportNodes caps the functions of the top, cumulative and graph views.
+const profileReportNodes = 25
+
+// ErrNoSampleType is returned for a sample type the profile doesn't have.
+var ErrNoSampleType = errors.New("[MoniGo] no such sample type")
+
+// readProfileReport renders the profile at path. With a focus, only the samples labelled
+// FunctionProfileLabel=focus are kept, as with pprof -tagfocus; list is the traced name of
+// the function whose source lines are listed, closures included.
+func readProfileReport(path, focus, list string) (*models.ProfileReport, error) {
+	if path == "" {
+		return nil, errors.New("profile file path is empty")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p, err := profile.ParseData(data)
+	if err != nil {
+		return nil, err
+	}
+	return buildProfileReport(p, focus, list)
+}
+
+// sampleIndex returns the index in Sample.Value of the named sample type, or of the
+// default one when name is empty.
+func sampleIndex(p *profile.Profile, name string) (int, error) {
+	if len(p.SampleType) == 0 {
+		return 0, errors.New("[MoniGo] profile has no sample types")
+	}
+	idx, err := p.SampleIndexByName(name)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrNoSampleType, name)
+	}
+	return idx, nil
+}
+
+// profileFrame is a function on the stack of a sample; inlined calls get their own frame.
+type profileFrame struct {
+	name, file string
+	line       int64
+}
+
+type profileLineKey struct {
+	function string
+	line     int64
+}
+
+func buildProfileReport(p *profile.Profile, focus, list string) (*models.ProfileReport, error) {
+	idx, err := sampleIndex(p, "")
+	if err != nil {
+		return nil, err
+	}
+	r := &models.ProfileReport{
+		SampleType: p.SampleType[idx].Type,
+		Unit:       p.SampleType[idx].Unit,
+		Duration:   time.Duration(p.DurationNanos),
+	}
+
+	functions := make(map[string]*models.ProfileEntry)
+	edges := make(map[[2]string]int64)
+	lines := make(map[profileLineKey]*models.ListingLine)
+
+	// A recursive function only counts once per sample.
+	seenFunctions := make(map[string]bool)
+	seenEdges := make(map[[2]string]bool)
+	seenLines := make(map[profileLineKey]bool)
+	var frames []profileFrame
+	list = tracedSymbol(list)
+
+	for _, s := range p.Sample {
+		if focus != "" && !slices.Contains(s.Label[FunctionProfileLabel], focus) {
+			continue
+		}
+		v := s.Value[idx]
+		if v == 0 {
+			continue
+		}
+		r.Total += v
+
+		frames = sampleFrames(frames[:0], s)
+		clear(seenFunctions)
+		clear(seenEdges)
+		clear(seenLines)
+		for i, f := range frames {
+			e := functions[f.name]
+			if e == nil {
+				e = &models.ProfileEntry{Function: f.name, File: f.file}
+				functions[f.name] = e
+			}
+			if i == 0 {
+				e.Flat += v
+			}
+			if !seenFunctions[f.name] {
+				seenFunctions[f.name] = true
+				e.Cum += v
+			}
+			if i > 0 {
+				edge := [2]string{f.name, frames[i-1].name}
+				if edge[0] != edge[1] && !seenEdges[edge] {
+					seenEdges[edge] = true
+					edges[edge] += v
+				}
+			}
+
+			if !listedFunction(f.name, list) {
+				continue
+			}
+			key := profileLineKey{f.name, f.line}
+			l := lines[key]
+			if l == nil {
+				l = &models.ListingLine{Line: f.line}
+				lines[key] = l
+			}
+			if i == 0 {
+				l.Flat += v
+			}
+			if !seenLines[key] {
+				seenLines[key] = true
+				l.Cum += v
+			}
+		}
+	}
+
+	entries := make([]models.ProfileEntry, 0, len(functions))
+	for _, e := range functions {
+		// Differences of profiles can cancel the samples of a function out.
+		if e.Flat == 0 && e.Cum == 0 {
+			continue
+		}
+		e.FlatPercent, e.CumPercent = percentOf(e.Flat, r.Total), percentOf(e.Cum, r.Total)
+		entries = append(entries, *e)
+	}
+	r.Functions = len(entries)
+
+	slices.SortFunc(entries, func(a, b models.ProfileEntry) int {
+		return cmp.Or(cmp.Compare(b.Flat, a.Flat), cmp.Compare(b.Cum, a.Cum), strings.Compare(a.Function, b.Function))
+	})
+	r.Top = slices.Clone(entries[:min(len(entries), profileReportNodes)])
+
+	slices.SortFunc(entries, func(a, b models.ProfileEntry) int {
+		return cmp.Or(cmp.Compare(b.Cum, a.Cum), cmp.Compare(b.Flat, a.Flat), strings.Compare(a.Function, b.Function))
+	})
+	r.Cumulative = slices.Clone(entries[:min(len(entries), profileReportNodes)])
+
+	r.Graph.Nodes = r.Cumulative
+	inGraph := make(map[string]bool, len(r.Graph.Nodes))
+	for _, n := range r.Graph.Nodes {
+		inGraph[n.Function] = true
+	}
+	r.Graph.Edges = []models.ProfileEdge{}
+	for edge, v := range edges {
+		if inGraph[edge[0]] && inGraph[edge[1]] {
+			r.Graph.Edges = append(r.Graph.Edges, models.ProfileEdge{Caller: edge[0], Callee: edge[1], Value: v})
+		}
+	}
+	slices.SortFunc(r.Graph.Edges, func(a, b models.ProfileEdge) int {
+		return cmp.Or(cmp.Compare(b.Value, a.Value), strings.Compare(a.Caller, b.Caller), strings.Compare(a.Callee, b.Callee))
+	})
+
+	r.Listing = profileListing(functions, lines)
+	return r, nil
+}
+
+// sampleFrames appends the frames of s to dst, leaf first.
+func sampleFrames(dst []profileFrame, s *profile.Sample) []profileFrame {
+	for _, loc := range s.Location {
+		if len(loc.Line) == 0 {
+			dst = append(dst, profileFrame{name: fmt.Sprintf("0x%x", loc.Address)})
+			continue
+		}
+		for _, line := range loc.Line {
+			dst = append(dst, profileFrame{name: line.Function.Name, file: line.Function.Filename, line: line.Line})
+		}
+	}
+	return dst
+}
+
+// tracedSymbol returns the function symbol of a traced name as generateFunctionName builds
+// it, without its signature and with '/' as '-'. Receivers start with ".(", signatures
+// with a "(" right after the name.
+func tracedSymbol(name string) string {
+	for i := 1; i < len(name); i++ {
+		if name[i] == '(' && name[i-1] != '.' {
+			name = name[:i]
+			break
+		}
+	}
+	return strings.ReplaceAll(name, "/", "-")
+}
+
+// listedFunction reports whether the samples of the function symbol are listed for the
+// traced symbol list: the function itself or a closure in it.
+func listedFunction(symbol, list string) bool {
+	if list == "" {
+		return false
+	}
+	symbol = strings.ReplaceAll(symbol, "/", "-")
+	return symbol == list || strings.HasPrefix(symbol, list+".")
+}
+
+// profileListing returns the listing of every listed function, with the source of the
+// lines from the first to the last sampled one when the file is readable.
+func profileListing(functions map[string]*models.ProfileEntry, lines map[profileLineKey]*models.ListingLine) []models.FunctionListing {
+	byFunction := make(map[string][]models.ListingLine)
+	for key, l := range lines {
+		byFunction[key.function] = append(byFunction[key.function], *l)
+	}
+
+	sources := make(map[string][]string)
+	listing := make([]models.FunctionListing, 0, len(byFunction))
+	for name, sampled := range byFunction {
+		e := functions[name]
+		slices.SortFunc(sampled, func(a, b models.ListingLine) int { return cmp.Compare(a.Line, b.Line) })
+		fl := models.FunctionListing{Function: name, File: e.File, Flat: e.Flat, Cum: e.Cum, Lines: sampled}
+
+		source, ok := sources[e.File]
+		if !ok {
+			if data, err := os.ReadFile(e.File); err == nil {
+				source = strings.Split(string(data), "\n")
+			}
+			sources[e.File] = source
+		}
+		first, last := sampled[0].Line, sampled[len(sampled)-1].Line
+		if first > 0 && last <= int64(len(source)) {
+			fl.Lines = make([]models.ListingLine, 0, last-first+1)
+			next := 0
+			for n := first; n <= last; n++ {
+				l := models.ListingLine{Line: n}
+				if next < len(sampled) && sampled[next].Line == n {
+					l = sampled[next]
+					next++
+				}
+				l.Source = strings.TrimRight(source[n-1], "\r")
+				fl.Lines = append(fl.Lines, l)
+			}
+		}
+		listing = append(listing, fl)
+	}
+	slices.SortFunc(listing, func(a, b models.FunctionListing) int {
+		return cmp.Or(cmp.Compare(b.Cum, a.Cum), strings.Compare(a.Function, b.Function))
+	})
+	return listing
+}
+
+func percentOf(v, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return 100 * float64(v) / float64(total)
+}
+
+// formatProfileReport renders r as text in the layout of go tool pprof: "cum" sorts the
+// functions by cumulative value, "list" lists the source lines of the traced function,
+// "graph" the calls between functions, and anything else, such as "text" or "top",
+// sorts the functions by flat value.
+func formatProfileReport(r *models.ProfileReport, reportType string) string {
+	switch reportType {
+	case "list":
+		return formatProfileListing(r)
+	case "graph", "tree":
+		return formatProfileGraph(r)
+	}
+
+	entries := r.Top
+	if reportType == "cum" {
+		entries = r.Cumulative
+	}
+
+	var b strings.Builder
+	writeProfileHeader(&b, r)
+	if r.Total == 0 {
+		b.WriteString("No samples\n")
+		return b.String()
+	}
+	if len(entries) < r.Functions {
+		fmt.Fprintf(&b, "Showing top %d nodes out of %d\n", len(entries), r.Functions)
+	}
+	fmt.Fprintf(&b, "%10s %6s %6s %10s %6s\n", "flat", "flat%", "sum%", "cum", "cum%")
+	var sum float64
+	for _, e := range entries {
+		sum += e.FlatPercent
+		fmt.Fprintf(&b, "%10s %5.2f%% %5.2f%% %10s %5.2f%%  %s\n",
+			formatProfileValue(e.Flat, r.Unit), e.FlatPercent, sum, formatProfileValue(e.Cum, r.Unit), e.CumPercent, e.Function)
+	}
+	return b.String()
+}
+
+func writeProfileHeader(b *strings.Builder, r *models.ProfileReport) {
+	fmt.Fprintf(b, "Type: %s\n", r.SampleType)
+	if r.Duration > 0 {
+		fmt.Fprintf(b, "Duration: %s, ", formatProfileValue(int64(r.Duration), "nanoseconds"))
+	}
+	fmt.Fprintf(b, "Total: %s\n", formatProfileValue(r.Total, r.Unit))
+}
+
+// formatProfileListing renders the listing of r as go tool pprof -list does.
+func formatProfileListing(r *models.ProfileReport) string {
+	if len(r.Listing) == 0 {
+		return "No samples in the traced function\n"
+	}
+	var b strings.Builder
+	for _, fl := range r.Listing {
+		fmt.Fprintf(&b, "ROUTINE ======================== %s in %s\n", fl.Function, fl.File)
+		fmt.Fprintf(&b, "%10s %10s (flat, cum) %.2f%% of Total\n", formatProfileValue(fl.Flat, r.Unit), formatProfileValue(fl.Cum, r.Unit), percentOf(fl.Cum, r.Total))
+		for _, l := range fl.Lines {
+			flat, cum := ".", "."
+			if l.Flat != 0 {
+				flat = formatProfileValue(l.Flat, r.Unit)
+			}
+			if l.Cum != 0 {
+				cum = formatProfileValue(l.Cum, r.Unit)
+			}
+			fmt.Fprintf(&b, "%10s %10s %6d:%s\n", flat, cum, l.Line, l.Source)
+		}
+	}
+	return b.String()
+}
+
+// formatProfileGraph renders the call graph of r, one caller -> callee edge per line.
+func formatProfileGraph(r *models.ProfileReport) string {
+	var b strings.Builder
+	writeProfileHeader(&b, r)
+	for _, e := range r.Graph.Edges {
+		fmt.Fprintf(&b, "%10s %6.2f%%  %s -> %s\n", formatProfileValue(e.Value, r.Unit), percentOf(e.Value, r.Total), e.Caller, e.Callee)
+	}
+	return b.String()
+}
+
+// formatProfileValue renders v in unit the way pprof does, such as "1.20s" or "512kB".
+func formatProfileValue(v int64, unit string) string {
+	if v == 0 {
+		return "0"
+	}
+	f := float64(v)
+	switch unit {
+	case "nanoseconds":
+		switch d := time.Duration(v); {
+		case d >= time.Second:
+			return trimFloat(f/1e9) + "s"
+		case d >= time.Millisecond:
+			return trimFloat(f/1e6) + "ms"
+		case d >= time.Microsecond:
+			return trimFloat(f/1e3) + "us"
+		}
+		return strconv.FormatInt(v, 10) + "ns"
+	case "bytes":
+		switch {
+		case v >= 1<<30:
+			return trimFloat(f/(1<<30)) + "GB"
+		case v >= 1<<20:
+			return trimFloat(f/(1<<20)) + "MB"
+		case v >= 1<<10:
+			return trimFloat(f/(1<<10)) + "kB"
+		}
+		return strconv.FormatInt(v, 10) + "B"
+	}
+	return strconv.FormatInt(v, 10)
+}
+
+func trimFloat(f float64) string {
+	s := strconv.FormatFloat(f, 'f', 2, 64)
+	return strings.TrimSuffix(strings.TrimRight(s, "0"), ".")
+}
@@ -0,0 +1,34 @@
+package core
+
+import (
+	"bytes"
+
+	"github.com/google/pprof/profile"
+)
+
+// filterProfileByLabel returns, for every value, a gzipped copy of the pprof profile in
+// data holding only the samples whose label key has that value. Everything else, such as
+// locations and functions, is kept, so each copy is a valid profile of its own.
+func filterProfileByLabel(data []byte, key string, values []string) (map[string][]byte, error) {
+	p, err := profile.ParseData(data)
+	if err != nil {
+		return nil, err
+	}
+	samples := p.Sample
+
+	out := make(map[string][]byte, len(values))
+	for _, v := range values {
+		p.Sample = nil
+		for _, s := range samples {
+			if s.HasLabel(key, v) {
+				p.Sample = append(p.Sample, s)
+			}
+		}
+		var buf bytes.Buffer
+		if err := p.Write(&buf); err != nil {
+			return nil, err
+		}
+		out[v] = buf.Bytes()
+	}
+	return out, nil
+}
@@ -0,0 +1,173 @@
+package core
+
+import (
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/iyashjayesh/monigo/internal/logger"
+	"github.com/iyashjayesh/monigo/models"
+)
+
+// Limits of the profile store when none are configured.
+const (
+	DefaultMaxProfilesPerFunction = 10
+	DefaultMaxProfileStoreBytes   = 256 << 20
+)
+
+// profileStore keeps the profiles of the last sampled calls of every traced function
+// under dir/<function>/<id>_{cpu,mem}.prof, at most maxCount per function.
+type profileStore struct {
+	recordStore[models.ProfileRecord]
+	unsized  map[string]bool // IDs of records whose CPU profile isn't written yet
+	reserved map[string]int  // calls per function whose profiles are being written
+}
+
+var storedProfiles = newProfileStore(filepath.Join(basePath, "profiles"), 0, 0)
+
+func newProfileStore(dir string, perFunction int, maxBytes int64) *profileStore {
+	s := &profileStore{unsized: make(map[string]bool), reserved: make(map[string]int)}
+	s.dir = dir
+	s.info = func(rec models.ProfileRecord) recordInfo {
+		return recordInfo{
+			id:         rec.ID,
+			group:      rec.FunctionName,
+			capturedAt: rec.CapturedAt,
+			size:       rec.SizeBytes,
+			files:      []string{rec.CPUProfileFilePath, rec.MemProfileFilePath},
+		}
+	}
+	s.dropped = func(rec models.ProfileRecord, last bool) {
+		delete(s.unsized, rec.ID)
+		if last && s.reserved[rec.FunctionName] == 0 {
+			// Nothing is left to write to the function's directory.
+			_ = os.Remove(filepath.Dir(rec.MemProfileFilePath))
+		}
+	}
+	s.setLimits(perFunction, maxBytes)
+	return s
+}
+
+// SetProfileStoreLimits keeps at most perFunction profiled calls per function within
+// maxBytes of disk in total; zero selects the defaults. Captured process profiles and
+// the continuous profiling history have a budget of maxBytes each.
+func SetProfileStoreLimits(perFunction int, maxBytes int64) {
+	storedProfiles.mu.Lock()
+	storedProfiles.setLimits(perFunction, maxBytes)
+	maxBytes = storedProfiles.maxBytes
+	storedProfiles.mu.Unlock()
+
+	for _, s := range []*processProfileStore{processProfiles, continuousHistory} {
+		s.mu.Lock()
+		s.maxBytes = maxBytes
+		s.mu.Unlock()
+	}
+}
+
+// StoredProfiles returns the profiled calls of the named function, or of every function
+// when name is empty, newest first.
+func StoredProfiles(name string) []models.ProfileRecord {
+	s := storedProfiles
+	s.mu.Lock()
+	s.sizeLocked()
+	s.mu.Unlock()
+
+	return s.newestFirst(func(rec models.ProfileRecord) bool {
+		return name == "" || rec.FunctionName == name
+	})
+}
+
+// StoredProfile returns the profiled call with the given ID.
+func StoredProfile(id string) (models.ProfileRecord, bool) {
+	return storedProfiles.find(id)
+}
+
+func (s *profileStore) setLimits(perFunction int, maxBytes int64) {
+	if perFunction <= 0 {
+		perFunction = DefaultMaxProfilesPerFunction
+	}
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxProfileStoreBytes
+	}
+	s.maxCount, s.maxBytes = perFunction, maxBytes
+}
+
+// reserve returns a new record for a sampled call of the named function, with the paths
+// its profiles are to be written to. IDs increase with time and never repeat.
+func (s *profileStore) reserve(name string, at time.Time) models.ProfileRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := s.nextIDLocked(at)
+	s.reserved[name]++
+
+	dir := filepath.Join(s.dir, sanitizeFileName(name))
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		logger.Log.Warn("failed to create profiles directory", "error", err)
+	}
+	rec := models.ProfileRecord{
+		ID:           id,
+		FunctionName: name,
+		CapturedAt:   at,
+	}
+	rec.CPUProfileFilePath = filepath.Join(dir, rec.ID+"_cpu.prof")
+	rec.MemProfileFilePath = filepath.Join(dir, rec.ID+"_mem.prof")
+	return rec
+}
+
+// add keeps rec, reserved before its profiles were written, then drops the profiles
+// beyond the limits.
+func (s *profileStore) add(rec models.ProfileRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loadLocked()
+	if s.reserved[rec.FunctionName]--; s.reserved[rec.FunctionName] <= 0 {
+		delete(s.reserved, rec.FunctionName)
+	}
+
+	cpuSize := fileSize(rec.CPUProfileFilePath)
+	if rec.CPUProfileFilePath != "" && cpuSize == 0 {
+		s.unsized[rec.ID] = true
+	}
+	rec.SizeBytes = cpuSize + fileSize(rec.MemProfileFilePath)
+	s.sizeLocked()
+	s.addLocked(rec)
+}
+
+// sizeLocked adds the size of CPU profiles that weren't written yet when their record was
+// added: a shared CPU profile is written when the last of its calls returns.
+func (s *profileStore) sizeLocked() {
+	if len(s.unsized) == 0 {
+		return
+	}
+	for i := range s.records {
+		rec := &s.records[i]
+		if !s.unsized[rec.ID] {
+			continue
+		}
+		if size := fileSize(rec.CPUProfileFilePath); size > 0 {
+			rec.SizeBytes += size
+			delete(s.unsized, rec.ID)
+		}
+	}
+}
+
+// sanitizeFileName maps name to a file name of letters, digits, '.', '-' and '_',
+// suffixed with a hash of name so that distinct names never share one.
+func sanitizeFileName(name string) string {
+	safe := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, name)
+	if len(safe) > 64 {
+		safe = safe[:64]
+	}
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	return fmt.Sprintf("%s-%08x", safe, h.Sum32())
+}
@@ -15,7 +15,10 @@ func StartCPUProfile(filename string) (*os.File, error) {
 	if err != nil {
 		return nil, err
 	}
-	pprof.StartCPUProfile(f)
+	if err := pprof.StartCPUProfile(f); err != nil {
+		f.Close()
+		return nil, err
+	}
 	return f, nil
 }
 
@@ -31,6 +34,7 @@ func WriteHeapProfile(filename string) error {
 	if err != nil {
 		return err
 	}
+	defer f.Close()
 	runtime.GC() // Get up-to-date statistics
 	return pprof.WriteHeapProfile(f)
 }
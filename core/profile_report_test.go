@@ -0,0 +1,163 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/pprof/profile"
+	"github.com/iyashjayesh/monigo/models"
+)
+
+// testProfile returns a CPU profile of main -> work -> leaf stacks, with one recursive
+// work -> work sample and one sample labelled for another function.
+func testProfile() *profile.Profile {
+	fn := func(id uint64, name string) *profile.Function {
+		return &profile.Function{ID: id, Name: name, Filename: "/nonexistent/" + name + ".go", StartLine: 10}
+	}
+	main, work, leaf := fn(1, "main.main"), fn(2, "main.work"), fn(3, "main.leaf")
+	loc := func(id uint64, f *profile.Function, line int64) *profile.Location {
+		return &profile.Location{ID: id, Line: []profile.Line{{Function: f, Line: line}}}
+	}
+	mainLoc, workLoc, workLoc2, leafLoc := loc(1, main, 12), loc(2, work, 20), loc(3, work, 21), loc(4, leaf, 30)
+	labels := map[string][]string{FunctionProfileLabel: {"main.work"}}
+	return &profile.Profile{
+		SampleType:    []*profile.ValueType{{Type: "samples", Unit: "count"}, {Type: "cpu", Unit: "nanoseconds"}},
+		DurationNanos: int64(time.Second),
+		Sample: []*profile.Sample{
+			{Location: []*profile.Location{leafLoc, workLoc, mainLoc}, Value: []int64{3, 30e6}, Label: labels},
+			{Location: []*profile.Location{workLoc2, workLoc, mainLoc}, Value: []int64{1, 10e6}, Label: labels},
+			{Location: []*profile.Location{mainLoc}, Value: []int64{6, 60e6}, Label: map[string][]string{FunctionProfileLabel: {"main.other"}}},
+		},
+	}
+}
+
+func findEntry(entries []models.ProfileEntry, name string) models.ProfileEntry {
+	for _, e := range entries {
+		if e.Function == name {
+			return e
+		}
+	}
+	return models.ProfileEntry{}
+}
+
+func TestBuildProfileReport(t *testing.T) {
+	r, err := buildProfileReport(testProfile(), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.SampleType != "cpu" || r.Unit != "nanoseconds" || r.Total != 100e6 || r.Functions != 3 {
+		t.Fatalf("unexpected report %+v", r)
+	}
+	if r.Top[0].Function != "main.main" || r.Top[0].Flat != 60e6 || r.Top[0].FlatPercent != 60 {
+		t.Errorf("expected main.main first by flat value, got %+v", r.Top[0])
+	}
+	// The recursive sample counts once in the cumulative value of main.work.
+	if work := findEntry(r.Cumulative, "main.work"); work.Flat != 10e6 || work.Cum != 40e6 {
+		t.Errorf("unexpected main.work %+v", work)
+	}
+	if r.Cumulative[0].Function != "main.main" || r.Cumulative[0].Cum != 100e6 {
+		t.Errorf("expected main.main first by cumulative value, got %+v", r.Cumulative[0])
+	}
+	want := []models.ProfileEdge{{Caller: "main.main", Callee: "main.work", Value: 40e6}, {Caller: "main.work", Callee: "main.leaf", Value: 30e6}}
+	if len(r.Graph.Edges) != len(want) || r.Graph.Edges[0] != want[0] || r.Graph.Edges[1] != want[1] {
+		t.Errorf("expected edges %v without the recursive one, got %v", want, r.Graph.Edges)
+	}
+}
+
+func TestBuildProfileReportFocusAndListing(t *testing.T) {
+	r, err := buildProfileReport(testProfile(), "main.work", "main.work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Total != 40e6 || findEntry(r.Top, "main.main").Flat != 0 {
+		t.Errorf("expected only the samples labelled main.work, got total %d", r.Total)
+	}
+	if len(r.Listing) != 1 || r.Listing[0].Function != "main.work" {
+		t.Fatalf("expected a listing of main.work, got %+v", r.Listing)
+	}
+	// The source file is missing, so only the sampled lines are listed.
+	lines := r.Listing[0].Lines
+	if len(lines) != 2 || lines[0] != (models.ListingLine{Line: 20, Cum: 40e6}) || lines[1] != (models.ListingLine{Line: 21, Flat: 10e6, Cum: 10e6}) {
+		t.Errorf("unexpected lines %+v", lines)
+	}
+
+	text := formatProfileReport(r, "list")
+	if !strings.Contains(text, "ROUTINE ======================== main.work in /nonexistent/main.work.go") || !strings.Contains(text, "10ms       10ms     21:") {
+		t.Errorf("unexpected listing:\n%s", text)
+	}
+}
+
+func TestFormatProfileReport(t *testing.T) {
+	r, _ := buildProfileReport(testProfile(), "", "")
+	text := formatProfileReport(r, "top")
+	for _, want := range []string{"Type: cpu\n", "Duration: 1s, Total: 100ms\n", "      60ms 60.00% 60.00%      100ms 100.00%  main.main\n"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %q in:\n%s", want, text)
+		}
+	}
+	if graph := formatProfileReport(r, "graph"); !strings.Contains(graph, "main.main -> main.work") {
+		t.Errorf("unexpected graph:\n%s", graph)
+	}
+}
+
+func TestFormatProfileValue(t *testing.T) {
+	for _, tc := range []struct {
+		v    int64
+		unit string
+		want string
+	}{
+		{1200e6, "nanoseconds", "1.2s"},
+		{10e6, "nanoseconds", "10ms"},
+		{1500, "nanoseconds", "1.5us"},
+		{512 << 10, "bytes", "512kB"},
+		{3 << 19, "bytes", "1.5MB"},
+		{42, "count", "42"},
+		{0, "bytes", "0"},
+	} {
+		if got := formatProfileValue(tc.v, tc.unit); got != tc.want {
+			t.Errorf("formatProfileValue(%d, %q) = %q, want %q", tc.v, tc.unit, got, tc.want)
+		}
+	}
+}
+
+func TestTracedSymbol(t *testing.T) {
+	for name, want := range map[string]string{
+		"github.com-acme-app.handle(string)->(error)": "github.com-acme-app.handle",
+		"github.com/acme/app.(*Server).Run(int)":      "github.com-acme-app.(*Server).Run",
+		"orders/charge":                               "orders-charge",
+	} {
+		if got := tracedSymbol(name); got != want {
+			t.Errorf("tracedSymbol(%q) = %q, want %q", name, got, want)
+		}
+	}
+	if !listedFunction("github.com/acme/app.(*Server).Run.func1", tracedSymbol("github.com-acme-app.(*Server).Run(int)")) {
+		t.Error("expected closures of the traced function to be listed")
+	}
+}
+
+func TestViewFunctionMetrics(t *testing.T) {
+	SetSamplingRate(1)
+	name := "profile.report"
+	TraceNamed(context.Background(), name, func(context.Context) error {
+		burnCPU(200 * time.Millisecond)
+		return nil
+	})
+	cpuProfiles.wait()
+
+	details := ViewFunctionMetrics(name, "top", FunctionTraceDetails()[name])
+	if details.CPUReport == nil || details.CPUReport.Total == 0 || details.MemReport == nil {
+		t.Fatalf("expected CPU and heap reports, got %+v", details)
+	}
+	if e := findEntry(details.CPUReport.Cumulative, "github.com/iyashjayesh/monigo/core.burnCPU"); e.Cum == 0 {
+		t.Errorf("expected burnCPU in the CPU report, got %+v", details.CPUReport.Cumulative)
+	}
+	if !strings.HasPrefix(details.CoreProfile.CPU, "Type: cpu") || !strings.HasPrefix(details.CoreProfile.Mem, "Type: inuse_space") {
+		t.Errorf("unexpected text reports:\n%s\n%s", details.CoreProfile.CPU, details.CoreProfile.Mem)
+	}
+
+	if missing := ViewFunctionMetrics(name, "top", &models.FunctionMetrics{}); missing.CPUReport != nil || !strings.HasPrefix(missing.CoreProfile.CPU, "Error:") {
+		t.Errorf("expected an error for a missing profile, got %+v", missing.CoreProfile)
+	}
+}
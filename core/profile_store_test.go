@@ -0,0 +1,135 @@
+package core
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iyashjayesh/monigo/models"
+)
+
+// storeProfile reserves a record in s and writes size bytes as its heap profile.
+func storeProfile(t *testing.T, s *profileStore, name string, at time.Time, size int) models.ProfileRecord {
+	t.Helper()
+	rec := s.reserve(name, at)
+	rec.CPUProfileFilePath = ""
+	if err := os.WriteFile(rec.MemProfileFilePath, make([]byte, size), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s.add(rec)
+	return rec
+}
+
+// profileID returns the ID of rec as the number it encodes.
+func profileID(t *testing.T, rec models.ProfileRecord) int64 {
+	t.Helper()
+	id, err := strconv.ParseInt(rec.ID, 10, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func storeIDs(s *profileStore) []string {
+	ids := make([]string, len(s.records))
+	for i, rec := range s.records {
+		ids[i] = rec.ID
+	}
+	return ids
+}
+
+func TestProfileStoreLimits(t *testing.T) {
+	s := newProfileStore(t.TempDir(), 2, 1000)
+	now := time.Now()
+
+	// The oldest profiles of a function beyond its limit go first.
+	a1 := storeProfile(t, s, "a", now, 100)
+	a2 := storeProfile(t, s, "a", now, 100)
+	a3 := storeProfile(t, s, "a", now, 100)
+	if got := storeIDs(s); len(got) != 2 || got[0] != a2.ID || got[1] != a3.ID {
+		t.Fatalf("expected the two newest profiles of a, got %v", got)
+	}
+	if _, err := os.Stat(a1.MemProfileFilePath); !os.IsNotExist(err) {
+		t.Errorf("expected the dropped profile to be removed, got %v", err)
+	}
+	if profileID(t, a2) <= profileID(t, a1) {
+		t.Errorf("expected increasing IDs for calls at the same time, got %s then %s", a1.ID, a2.ID)
+	}
+
+	// Then the oldest overall beyond the disk budget.
+	b := storeProfile(t, s, "b", now, 950)
+	if got := storeIDs(s); len(got) != 1 || got[0] != b.ID {
+		t.Fatalf("expected only the newest profile within the budget, got %v", got)
+	}
+	if _, err := os.Stat(filepath.Dir(a3.MemProfileFilePath)); !os.IsNotExist(err) {
+		t.Errorf("expected the directory of a to be removed with its last profile, got %v", err)
+	}
+
+	// And those older than the retention period.
+	storeProfile(t, s, "c", now.Add(-30*24*time.Hour), 10)
+	c := storeProfile(t, s, "c", now, 10)
+	if got := storeIDs(s); len(got) != 2 || got[0] != b.ID || got[1] != c.ID {
+		t.Fatalf("expected the expired profile to be dropped, got %v", got)
+	}
+}
+
+func TestProfileStoreIndex(t *testing.T) {
+	dir := t.TempDir()
+	s := newProfileStore(dir, 5, 0)
+	rec := storeProfile(t, s, "pkg.(*T).Run", time.Now(), 10)
+	gone := storeProfile(t, s, "pkg.(*T).Run", time.Now(), 10)
+	if err := os.Remove(gone.MemProfileFilePath); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new store reads the index back, skipping records whose files are gone.
+	reloaded := newProfileStore(dir, 5, 0)
+	reloaded.loadLocked()
+	if got := storeIDs(reloaded); len(got) != 1 || got[0] != rec.ID {
+		t.Fatalf("expected the surviving record, got %v", got)
+	}
+	if got := reloaded.records[0]; got.FunctionName != rec.FunctionName || got.SizeBytes != 10 {
+		t.Errorf("unexpected record %+v", got)
+	}
+	if next := reloaded.reserve("pkg.(*T).Run", time.Unix(0, 0)); profileID(t, next) <= profileID(t, gone) {
+		t.Errorf("expected IDs to keep increasing after a reload, got %s after %s", next.ID, gone.ID)
+	}
+}
+
+func TestStoredProfiles(t *testing.T) {
+	SetSamplingRate(1)
+	name := "stored.profiles"
+	for range 2 {
+		TraceNamed(context.Background(), name, func(context.Context) error { return nil })
+	}
+
+	// The index may hold records of earlier runs.
+	records := StoredProfiles(name)
+	if len(records) < 2 || !records[0].CapturedAt.After(records[1].CapturedAt) {
+		t.Fatalf("expected two records, newest first, got %+v", records)
+	}
+	m := FunctionTraceDetails()[name]
+	if m.MemProfileFilePath != records[0].MemProfileFilePath || records[0].SizeBytes == 0 {
+		t.Errorf("expected the newest record to hold the last heap profile, got %+v", records[0])
+	}
+	if rec, ok := StoredProfile(records[1].ID); !ok || rec.MemProfileFilePath == m.MemProfileFilePath {
+		t.Errorf("expected the older call to keep its own profile, got %+v", rec)
+	}
+}
+
+func TestSanitizeFileName(t *testing.T) {
+	a, b := sanitizeFileName("orders/charge"), sanitizeFileName("orders-charge")
+	if a == b {
+		t.Errorf("expected distinct names to map to distinct files, got %q", a)
+	}
+	for _, name := range []string{"orders/charge", "..", `a\b:c*?"<>|`, strings.Repeat("x", 300)} {
+		got := sanitizeFileName(name)
+		if strings.ContainsAny(got, `/\:*?"<>| `) || got == ".." || len(got) > 80 {
+			t.Errorf("sanitizeFileName(%q) = %q", name, got)
+		}
+	}
+}
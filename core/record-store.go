@@ -0,0 +1,225 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/iyashjayesh/monigo/common"
+	"github.com/iyashjayesh/monigo/internal/logger"
+)
+
+// profileIndexFile holds the records of a store, so they survive restarts.
+const profileIndexFile = "index.json"
+
+// recordInfo is what a recordStore reads of one of its records.
+type recordInfo struct {
+	id         string
+	group      string // the count limit applies per group
+	capturedAt time.Time
+	size       int64
+	files      []string // removed with the record, which is kept while one isn't empty
+}
+
+// recordStore keeps records of profile files, oldest first, with an index in dir. It
+// drops the oldest records of a group beyond maxCount, the oldest overall beyond
+// maxBytes, and those older than the data retention period, with their files; the
+// newest record always stays. Expired records are dropped when the store is read, not
+// only when one is added. The index is written on every change, or at most once per
+// saveInterval when set, flush writing the changes left.
+type recordStore[R any] struct {
+	mu           sync.Mutex
+	dir          string
+	maxCount     int
+	maxBytes     int64
+	saveInterval time.Duration
+	records      []R // oldest first
+	lastID       int64
+	loaded       bool
+	dirty        bool // the index lacks changes
+	savedAt      time.Time
+
+	info func(R) recordInfo
+	// dropped, if set, is called for every dropped record, with whether it was the last
+	// of its group.
+	dropped func(rec R, last bool)
+}
+
+// nextIDLocked returns a new record ID. IDs increase with time and never repeat.
+func (s *recordStore[R]) nextIDLocked(at time.Time) string {
+	id := max(at.UnixNano(), s.lastID+1)
+	s.lastID = id
+	return strconv.FormatInt(id, 10)
+}
+
+// addLocked keeps rec, then drops the records beyond the limits.
+func (s *recordStore[R]) addLocked(rec R) {
+	s.records = append(s.records, rec)
+	s.pruneLocked(time.Now())
+	s.changedLocked()
+}
+
+// readLocked loads the index on first use and drops the records expired since.
+func (s *recordStore[R]) readLocked() {
+	s.loadLocked()
+	if s.pruneLocked(time.Now()) {
+		s.changedLocked()
+	}
+}
+
+// changedLocked writes the index, unless it was written less than saveInterval ago.
+func (s *recordStore[R]) changedLocked() {
+	s.dirty = true
+	if time.Since(s.savedAt) >= s.saveInterval {
+		s.saveLocked()
+	}
+}
+
+// flush writes the changes the index lacks.
+func (s *recordStore[R]) flush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.dirty {
+		s.saveLocked()
+	}
+}
+
+// newestFirst returns the records for which keep returns true, newest first.
+func (s *recordStore[R]) newestFirst(keep func(R) bool) []R {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.readLocked()
+
+	out := make([]R, 0, len(s.records))
+	for i := len(s.records) - 1; i >= 0; i-- {
+		if keep == nil || keep(s.records[i]) {
+			out = append(out, s.records[i])
+		}
+	}
+	return out
+}
+
+// find returns the record with the given ID.
+func (s *recordStore[R]) find(id string) (R, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.readLocked()
+
+	for _, rec := range s.records {
+		if s.info(rec).id == id {
+			return rec, true
+		}
+	}
+	var zero R
+	return zero, false
+}
+
+// pruneLocked drops the records beyond the limits and reports whether it dropped any.
+func (s *recordStore[R]) pruneLocked(now time.Time) bool {
+	retention := common.GetDataRetentionPeriod()
+	infos := make([]recordInfo, len(s.records))
+	count := make(map[string]int)
+	var total int64
+	for i, rec := range s.records {
+		infos[i] = s.info(rec)
+		count[infos[i].group]++
+		total += infos[i].size
+	}
+
+	kept := s.records[:0]
+	for i, rec := range s.records {
+		info := infos[i]
+		newest := i == len(s.records)-1
+		if !newest && (count[info.group] > s.maxCount || total > s.maxBytes || now.Sub(info.capturedAt) > retention) {
+			count[info.group]--
+			total -= info.size
+			removeProfileFiles(info.files)
+			if s.dropped != nil {
+				s.dropped(rec, count[info.group] == 0)
+			}
+			continue
+		}
+		kept = append(kept, rec)
+	}
+	if len(kept) == len(s.records) {
+		return false
+	}
+	clear(s.records[len(kept):])
+	s.records = kept
+	return true
+}
+
+// loadLocked reads the index on first use, dropping records whose files are gone.
+func (s *recordStore[R]) loadLocked() {
+	if s.loaded {
+		return
+	}
+	s.loaded = true
+
+	data, err := os.ReadFile(filepath.Join(s.dir, profileIndexFile))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			logger.Log.Warn("failed to read profile index", "dir", s.dir, "error", err)
+		}
+		return
+	}
+	var records []R
+	if err := json.Unmarshal(data, &records); err != nil {
+		logger.Log.Warn("failed to parse profile index", "dir", s.dir, "error", err)
+		return
+	}
+	for _, rec := range records {
+		info := s.info(rec)
+		if id, err := strconv.ParseInt(info.id, 10, 64); err == nil {
+			s.lastID = max(s.lastID, id)
+		}
+		if !slices.ContainsFunc(info.files, func(path string) bool { return fileSize(path) > 0 }) {
+			continue
+		}
+		s.records = append(s.records, rec)
+	}
+	// Records added before the index was read come last.
+	slices.SortStableFunc(s.records, func(a, b R) int {
+		return s.info(a).capturedAt.Compare(s.info(b).capturedAt)
+	})
+	s.pruneLocked(time.Now())
+}
+
+func (s *recordStore[R]) saveLocked() {
+	data, err := json.Marshal(s.records)
+	if err == nil {
+		err = writeFileAtomic(filepath.Join(s.dir, profileIndexFile), data)
+	}
+	if err != nil {
+		logger.Log.Warn("failed to write profile index", "dir", s.dir, "error", err)
+		return
+	}
+	s.dirty, s.savedAt = false, time.Now()
+}
+
+func removeProfileFiles(paths []string) {
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			logger.Log.Warn("failed to remove profile", "path", path, "error", err)
+		}
+	}
+}
+
+func fileSize(path string) int64 {
+	if path == "" {
+		return 0
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
@@ -0,0 +1,115 @@
+package core
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/iyashjayesh/monigo/models"
+)
+
+// DefaultStatsSampleInterval is how often the stats sampler refreshes the cached ServiceStats.
+const DefaultStatsSampleInterval = 5 * time.Second
+
+// statsSampler keeps the latest ServiceStats so readers don't pay for the CPU
+// measurement window of GetServiceStats on every request.
+type statsSampler struct {
+	mu       sync.RWMutex
+	stats    models.ServiceStats
+	interval time.Duration
+	cancel   context.CancelFunc
+	done     chan struct{}
+
+	refreshMu sync.Mutex // serialises sampling so concurrent readers share one pass
+}
+
+var sampler = &statsSampler{interval: DefaultStatsSampleInterval}
+
+// StartStatsSampler refreshes the cached ServiceStats every interval in the background
+// until ctx is done or StopStatsSampler is called. Calling it while running is a no-op.
+func StartStatsSampler(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultStatsSampleInterval
+	}
+
+	sampler.mu.Lock()
+	defer sampler.mu.Unlock()
+	if sampler.cancel != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	sampler.interval = interval
+	sampler.cancel = cancel
+	sampler.done = make(chan struct{})
+
+	go sampler.run(ctx, interval, sampler.done)
+}
+
+// StopStatsSampler stops the background sampler and waits for it to exit.
+// The last snapshot stays available to CachedServiceStats.
+func StopStatsSampler() {
+	sampler.mu.Lock()
+	cancel, done := sampler.cancel, sampler.done
+	sampler.cancel, sampler.done = nil, nil
+	sampler.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+}
+
+// CachedServiceStats returns the most recent sampled ServiceStats; SampledAt tells how
+// old it is. It only samples synchronously when there is no snapshot yet, or when the
+// sampler is not running and the snapshot is older than the sample interval.
+func CachedServiceStats(ctx context.Context) models.ServiceStats {
+	sampler.mu.RLock()
+	stats, running, interval := sampler.stats, sampler.cancel != nil, sampler.interval
+	sampler.mu.RUnlock()
+
+	if stats.SampledAt.IsZero() || (!running && time.Since(stats.SampledAt) > interval) {
+		return sampler.refresh(ctx, stats.SampledAt)
+	}
+	return stats
+}
+
+func (s *statsSampler) snapshot() models.ServiceStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.stats
+}
+
+func (s *statsSampler) run(ctx context.Context, interval time.Duration, done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	s.refresh(ctx, s.snapshot().SampledAt)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.refresh(ctx, s.snapshot().SampledAt)
+		}
+	}
+}
+
+// refresh takes a new sample, unless another caller replaced the snapshot the
+// caller saw (taken at seen) while this one waited for its turn.
+func (s *statsSampler) refresh(ctx context.Context, seen time.Time) models.ServiceStats {
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+
+	if current := s.snapshot(); !current.SampledAt.Equal(seen) {
+		return current
+	}
+
+	stats := GetServiceStats(ctx)
+	s.mu.Lock()
+	s.stats = stats
+	s.mu.Unlock()
+	publishServiceStats(&stats)
+	publishFunctionStats()
+	return stats
+}
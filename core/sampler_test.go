@@ -0,0 +1,91 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/iyashjayesh/monigo/internal/registry"
+	"github.com/iyashjayesh/monigo/models"
+)
+
+// resetSampler stops the sampler and drops its snapshot.
+func resetSampler() {
+	StopStatsSampler()
+	sampler.mu.Lock()
+	sampler.stats = models.ServiceStats{}
+	sampler.interval = DefaultStatsSampleInterval
+	sampler.mu.Unlock()
+}
+
+func TestCachedServiceStatsWithoutSampler(t *testing.T) {
+	resetSampler()
+	defer resetSampler()
+
+	first := CachedServiceStats(context.Background())
+	if first.SampledAt.IsZero() {
+		t.Fatal("expected a synchronous sample when nothing is cached")
+	}
+
+	start := time.Now()
+	second := CachedServiceStats(context.Background())
+	if !second.SampledAt.Equal(first.SampledAt) {
+		t.Error("expected the fresh snapshot to be reused")
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("cached read took %v", elapsed)
+	}
+}
+
+func TestStatsSamplerRefreshesInBackground(t *testing.T) {
+	resetSampler()
+	defer resetSampler()
+
+	StartStatsSampler(context.Background(), 10*time.Millisecond)
+	StartStatsSampler(context.Background(), time.Hour) // no-op while running
+
+	first := CachedServiceStats(context.Background())
+	if first.SampledAt.IsZero() {
+		t.Fatal("expected a snapshot")
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		start := time.Now()
+		stats := CachedServiceStats(context.Background())
+		if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+			t.Fatalf("read blocked for %v while the sampler was running", elapsed)
+		}
+		if stats.SampledAt.After(first.SampledAt) {
+			StopStatsSampler()
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatal("expected the sampler to replace the snapshot")
+}
+
+func TestSetStatsRegistry(t *testing.T) {
+	resetSampler()
+	defer resetSampler()
+	r := registry.NewRegistry()
+	SetStatsRegistry(r)
+	defer SetStatsRegistry(nil)
+
+	stats := CachedServiceStats(context.Background())
+
+	byName := map[string]*registry.MetricValue{}
+	for _, m := range r.GetAll() {
+		byName[m.Name] = m
+	}
+	goroutines := byName["monigo_goroutines_count"]
+	if goroutines == nil || goroutines.Type != registry.Gauge || goroutines.Value != float64(stats.CoreStatistics.Goroutines) {
+		t.Errorf("unexpected goroutines gauge %+v", goroutines)
+	}
+	if gc := byName["monigo_memstats_gc_completed_total"]; gc == nil || gc.Type != registry.Counter {
+		t.Errorf("unexpected GC counter %+v", gc)
+	}
+	if len(byName) < len(ServiceStatMetrics()) {
+		t.Errorf("expected every stat metric, got %d series", len(byName))
+	}
+}
@@ -0,0 +1,171 @@
+package core
+
+import (
+	"sync/atomic"
+
+	"github.com/iyashjayesh/monigo/internal/registry"
+	"github.com/iyashjayesh/monigo/models"
+)
+
+// StatMetric maps a ServiceStats field to a metric.
+type StatMetric struct {
+	Name    string
+	Help    string
+	Counter bool // cumulative value rather than a gauge
+	Value   func(*models.ServiceStats) float64
+}
+
+// MemStatMetric maps a RawMemStatsRecords entry to a metric. Records are stored in KB
+// for byte values, so Scale converts them back to base units.
+type MemStatMetric struct {
+	Name    string
+	Help    string
+	Counter bool
+	Scale   float64
+}
+
+var (
+	statsRegistry  atomic.Pointer[registry.Registry]
+	statMetrics    = ServiceStatMetrics()
+	memStatMetrics = MemStatMetrics()
+)
+
+// SetStatsRegistry publishes every ServiceStats sample into r, under the names of
+// ServiceStatMetrics and MemStatMetrics. Passing nil stops publishing.
+func SetStatsRegistry(r *registry.Registry) {
+	statsRegistry.Store(r)
+}
+
+// publishServiceStats records a sample in the stats registry, if one is set.
+func publishServiceStats(stats *models.ServiceStats) {
+	r := statsRegistry.Load()
+	if r == nil {
+		return
+	}
+	set := func(name string, counter bool, value float64) {
+		if counter {
+			r.SetCounter(name, value, nil)
+		} else {
+			r.SetGauge(name, value, nil)
+		}
+	}
+	for _, m := range statMetrics {
+		set(m.Name, m.Counter, m.Value(stats))
+	}
+	for _, record := range stats.MemoryStatistics.RawMemStatsRecords {
+		if m, ok := memStatMetrics[record.RecordName]; ok {
+			set(m.Name, m.Counter, record.RecordValue*m.Scale)
+		}
+	}
+}
+
+// ServiceStatMetrics lists the metrics read directly from ServiceStats fields.
+func ServiceStatMetrics() []StatMetric {
+	const gauge, counter = false, true
+	return []StatMetric{
+		// Core
+		{"monigo_goroutines_count", "Number of goroutines running.", gauge,
+			func(s *models.ServiceStats) float64 { return float64(s.CoreStatistics.Goroutines) }},
+
+		// Load
+		{"monigo_service_overall_load_percent", "Overall load of the service (weighted CPU and memory load).", gauge,
+			func(s *models.ServiceStats) float64 { return s.LoadStatistics.OverallLoadOfServiceRaw }},
+		{"monigo_service_cpu_load_percent", "CPU load of the service process.", gauge,
+			func(s *models.ServiceStats) float64 { return s.LoadStatistics.ServiceCPULoadRaw }},
+		{"monigo_service_memory_load_percent", "Memory load of the service process.", gauge,
+			func(s *models.ServiceStats) float64 { return s.LoadStatistics.ServiceMemLoadRaw }},
+		{"monigo_cpu_usage_percent", "Current system CPU usage percentage.", gauge,
+			func(s *models.ServiceStats) float64 { return s.LoadStatistics.SystemCPULoadRaw }},
+		{"monigo_system_memory_load_percent", "Current system memory usage percentage.", gauge,
+			func(s *models.ServiceStats) float64 { return s.LoadStatistics.SystemMemLoadRaw }},
+		{"monigo_system_disk_load_percent", "Disk usage percentage of the root partition.", gauge,
+			func(s *models.ServiceStats) float64 { return s.LoadStatistics.SystemDiskLoadRaw }},
+		{"monigo_system_disk_total_bytes", "Total size of the root partition in bytes.", gauge,
+			func(s *models.ServiceStats) float64 { return s.LoadStatistics.TotalDiskLoadRaw }},
+
+		// CPU
+		{"monigo_cpu_cores", "Number of physical CPU cores.", gauge,
+			func(s *models.ServiceStats) float64 { return s.CPUStatistics.TotalCores }},
+		{"monigo_service_cpu_cores_used", "CPU cores used by the service process.", gauge,
+			func(s *models.ServiceStats) float64 { return s.CPUStatistics.CoresUsedByService }},
+		{"monigo_system_cpu_cores_used", "CPU cores used by the whole system.", gauge,
+			func(s *models.ServiceStats) float64 { return s.CPUStatistics.CoresUsedBySystem }},
+
+		// Memory
+		{"monigo_system_memory_total_bytes", "Total system memory in bytes.", gauge,
+			func(s *models.ServiceStats) float64 { return s.MemoryStatistics.TotalSystemMemoryRaw }},
+		{"monigo_memory_usage_bytes", "Current system memory usage in bytes.", gauge,
+			func(s *models.ServiceStats) float64 { return s.MemoryStatistics.MemoryUsedBySystemRaw }},
+		{"monigo_system_memory_available_bytes", "System memory available for allocation in bytes.", gauge,
+			func(s *models.ServiceStats) float64 { return s.MemoryStatistics.AvailableMemoryRaw }},
+		{"monigo_service_memory_used_bytes", "Heap bytes allocated by the service.", gauge,
+			func(s *models.ServiceStats) float64 { return s.MemoryStatistics.MemoryUsedByServiceRaw }},
+		{"monigo_service_stack_memory_bytes", "Stack memory used by the service in bytes.", gauge,
+			func(s *models.ServiceStats) float64 { return s.MemoryStatistics.StackMemoryUsageRaw }},
+		{"monigo_gc_pause_duration_seconds_total", "Cumulative time spent in GC stop-the-world pauses.", counter,
+			func(s *models.ServiceStats) float64 { return s.MemoryStatistics.GCPauseDurationRaw / 1e3 }},
+		{"monigo_service_heap_alloc_bytes", "Bytes of allocated heap objects.", gauge,
+			func(s *models.ServiceStats) float64 { return float64(s.HeapAllocByServiceRaw) }},
+		{"monigo_service_heap_sys_bytes", "Bytes of heap memory obtained from the OS.", gauge,
+			func(s *models.ServiceStats) float64 { return float64(s.HeapAllocBySystemRaw) }},
+		{"monigo_service_alloc_bytes_total", "Cumulative bytes allocated for heap objects.", counter,
+			func(s *models.ServiceStats) float64 { return float64(s.TotalAllocByServiceRaw) }},
+		{"monigo_service_sys_bytes", "Total bytes of memory obtained from the OS.", gauge,
+			func(s *models.ServiceStats) float64 { return float64(s.TotalMemoryByOSRaw) }},
+
+		// IO
+		{"monigo_network_sent_bytes_total", "Total bytes sent over all network interfaces.", counter,
+			func(s *models.ServiceStats) float64 { return s.NetworkIO.BytesSent }},
+		{"monigo_network_received_bytes_total", "Total bytes received over all network interfaces.", counter,
+			func(s *models.ServiceStats) float64 { return s.NetworkIO.BytesReceived }},
+		{"monigo_disk_read_bytes_total", "Total bytes read from disk.", counter,
+			func(s *models.ServiceStats) float64 { return float64(s.DiskIO.ReadBytes) }},
+		{"monigo_disk_write_bytes_total", "Total bytes written to disk.", counter,
+			func(s *models.ServiceStats) float64 { return float64(s.DiskIO.WriteBytes) }},
+
+		// Health
+		{"monigo_service_health_percent", "Health score of the service.", gauge,
+			func(s *models.ServiceStats) float64 { return s.Health.ServiceHealth.Percent }},
+		{"monigo_system_health_percent", "Health score of the system.", gauge,
+			func(s *models.ServiceStats) float64 { return s.Health.SystemHealth.Percent }},
+	}
+}
+
+// MemStatMetrics lists the runtime.MemStats records produced by ConstructRawMemStats,
+// keyed by RecordName.
+func MemStatMetrics() map[string]MemStatMetric {
+	const kb = 1024
+	const gauge, counter = false, true
+	m := func(name, help string, counter bool, scale float64) MemStatMetric {
+		return MemStatMetric{Name: name, Help: help, Counter: counter, Scale: scale}
+	}
+	return map[string]MemStatMetric{
+		"alloc":           m("monigo_memstats_alloc_bytes", "Bytes of allocated heap objects.", gauge, kb),
+		"total_alloc":     m("monigo_memstats_alloc_bytes_total", "Cumulative bytes allocated for heap objects.", counter, kb),
+		"sys":             m("monigo_memstats_sys_bytes", "Total bytes of memory obtained from the OS.", gauge, kb),
+		"lookups":         m("monigo_memstats_lookups_total", "Number of pointer lookups performed by the runtime.", counter, 1),
+		"mallocs":         m("monigo_memstats_mallocs_total", "Cumulative count of heap objects allocated.", counter, 1),
+		"frees":           m("monigo_memstats_frees_total", "Cumulative count of heap objects freed.", counter, 1),
+		"heap_alloc":      m("monigo_memstats_heap_alloc_bytes", "Bytes of allocated heap objects.", gauge, kb),
+		"heap_sys":        m("monigo_memstats_heap_sys_bytes", "Bytes of heap memory obtained from the OS.", gauge, kb),
+		"heap_idle":       m("monigo_memstats_heap_idle_bytes", "Bytes in idle (unused) spans.", gauge, kb),
+		"heap_inuse":      m("monigo_memstats_heap_inuse_bytes", "Bytes in in-use spans.", gauge, kb),
+		"heap_released":   m("monigo_memstats_heap_released_bytes", "Bytes of physical memory returned to the OS.", gauge, kb),
+		"heap_objects":    m("monigo_memstats_heap_objects", "Number of allocated heap objects.", gauge, 1),
+		"stack_inuse":     m("monigo_memstats_stack_inuse_bytes", "Bytes in stack spans.", gauge, kb),
+		"stack_sys":       m("monigo_memstats_stack_sys_bytes", "Bytes of stack memory obtained from the OS.", gauge, kb),
+		"m_span_inuse":    m("monigo_memstats_mspan_inuse_bytes", "Bytes of allocated mspan structures.", gauge, kb),
+		"m_span_sys":      m("monigo_memstats_mspan_sys_bytes", "Bytes of memory obtained from the OS for mspan structures.", gauge, kb),
+		"m_cache_inuse":   m("monigo_memstats_mcache_inuse_bytes", "Bytes of allocated mcache structures.", gauge, kb),
+		"m_cache_sys":     m("monigo_memstats_mcache_sys_bytes", "Bytes of memory obtained from the OS for mcache structures.", gauge, kb),
+		"buck_hash_sys":   m("monigo_memstats_buck_hash_sys_bytes", "Bytes of memory in profiling bucket hash tables.", gauge, kb),
+		"gc_sys":          m("monigo_memstats_gc_sys_bytes", "Bytes of memory in garbage collection metadata.", gauge, kb),
+		"other_sys":       m("monigo_memstats_other_sys_bytes", "Bytes of memory in miscellaneous off-heap runtime allocations.", gauge, kb),
+		"next_gc":         m("monigo_memstats_next_gc_bytes", "Target heap size of the next GC cycle.", gauge, 1),
+		"last_gc":         m("monigo_memstats_last_gc_time_seconds", "Time the last garbage collection finished, in seconds since the epoch.", gauge, 1e-9),
+		"pause_total_ns":  m("monigo_memstats_gc_pause_seconds_total", "Cumulative seconds in GC stop-the-world pauses.", counter, 1e-9),
+		"num_gc":          m("monigo_memstats_gc_completed_total", "Number of completed GC cycles.", counter, 1),
+		"num_forced_gc":   m("monigo_memstats_gc_forced_total", "Number of GC cycles forced by the application.", counter, 1),
+		"gc_cpu_fraction": m("monigo_memstats_gc_cpu_fraction", "Fraction of the available CPU time used by the GC.", gauge, 1),
+	}
+}
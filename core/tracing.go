@@ -0,0 +1,110 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.39.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName is the instrumentation scope of the spans started for traced functions.
+const TracerName = "github.com/iyashjayesh/monigo"
+
+// Attributes set on the span of every traced call, next to code.function.name.
+const (
+	SpanExecutionTimeAttribute  = "monigo.execution_time_seconds"
+	SpanMemoryDeltaAttribute    = "monigo.memory_delta_bytes" // profiled calls only
+	SpanGoroutineDeltaAttribute = "monigo.goroutine_delta"
+	SpanProfiledAttribute       = "monigo.profiled"
+)
+
+// tracerProviderHolder lets atomic.Value hold providers of different concrete types.
+type tracerProviderHolder struct {
+	provider trace.TracerProvider
+}
+
+var tracerProvider atomic.Value // tracerProviderHolder
+
+// SetTracerProvider sets the provider traced functions start their spans from. Passing
+// nil falls back to the global provider from otel.GetTracerProvider, which discards
+// spans unless the application installs one.
+func SetTracerProvider(tp trace.TracerProvider) {
+	tracerProvider.Store(tracerProviderHolder{provider: tp})
+}
+
+func tracer() trace.Tracer {
+	if h, ok := tracerProvider.Load().(tracerProviderHolder); ok && h.provider != nil {
+		return h.provider.Tracer(TracerName)
+	}
+	return otel.GetTracerProvider().Tracer(TracerName)
+}
+
+// startFunctionSpan starts the span of a traced call as a child of any span in ctx.
+func startFunctionSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	return tracer().Start(ctx, name, trace.WithAttributes(semconv.CodeFunctionName(name)))
+}
+
+// panicError returns the recovered panic value r as an error.
+func panicError(r any) error {
+	if err, ok := r.(error); ok {
+		return fmt.Errorf("panic: %w", err)
+	}
+	return fmt.Errorf("panic: %v", r)
+}
+
+// recordSpanPanic marks span as failed by a panic raised with the given stack.
+func recordSpanPanic(span trace.Span, err error, stack string) {
+	span.RecordError(err, trace.WithAttributes(
+		semconv.ExceptionStacktrace(stack),
+		attribute.Bool("exception.escaped", true),
+	))
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// recordSpanError marks span as failed by err; nil errors are ignored.
+func recordSpanError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+
+// withSpanContext replaces the arguments that are the caller's ctx with spanCtx, so spans
+// started by the traced function become children of its call span.
+func withSpanContext(args []reflect.Value, ctx, spanCtx context.Context) []reflect.Value {
+	if ctx == nil || !reflect.TypeOf(ctx).Comparable() {
+		return args
+	}
+	out := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		out[i] = arg
+		if arg.Type().Implements(contextType) && arg.Interface() == any(ctx) {
+			out[i] = reflect.ValueOf(spanCtx)
+		}
+	}
+	return out
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// errorResult returns the error of results whose last value is a non-nil error.
+func errorResult(results []reflect.Value) error {
+	if len(results) == 0 {
+		return nil
+	}
+	last := results[len(results)-1]
+	if last.Kind() != reflect.Interface || !last.Type().Implements(errorType) || last.IsNil() {
+		return nil
+	}
+	err, _ := last.Interface().(error)
+	return err
+}
@@ -0,0 +1,135 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordSpans routes the spans of traced functions to a recorder for the test.
+func recordSpans(t *testing.T) (*tracetest.SpanRecorder, *sdktrace.TracerProvider) {
+	t.Helper()
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	SetTracerProvider(tp)
+	t.Cleanup(func() { SetTracerProvider(nil) })
+	return rec, tp
+}
+
+func spanAttributes(s sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range s.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestTraceFunctionSpan(t *testing.T) {
+	SetSamplingRate(1)
+	rec, tp := recordSpans(t)
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "request")
+	TraceFunction(ctx, func() {})
+	parent.End()
+
+	spans := rec.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected the call and parent spans, got %d", len(spans))
+	}
+	call := spans[0]
+	if call.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("expected the call span to be a child of the span in ctx")
+	}
+	attrs := spanAttributes(call)
+	if attrs["code.function.name"].AsString() != call.Name() {
+		t.Errorf("expected code.function.name %q, got %q", call.Name(), attrs["code.function.name"].AsString())
+	}
+	for _, key := range []attribute.Key{SpanExecutionTimeAttribute, SpanGoroutineDeltaAttribute, SpanMemoryDeltaAttribute} {
+		if _, ok := attrs[key]; !ok {
+			t.Errorf("missing attribute %s", key)
+		}
+	}
+	if !attrs[SpanProfiledAttribute].AsBool() {
+		t.Errorf("expected a profiled call with sampling rate 1")
+	}
+	if call.Status().Code == codes.Error {
+		t.Errorf("expected a successful span, got %+v", call.Status())
+	}
+}
+
+func TestTraceFunctionWithReturnsErrorSpan(t *testing.T) {
+	SetSamplingRate(1)
+	rec, _ := recordSpans(t)
+
+	fail := func(string) (int, error) { return 0, errors.New("boom") }
+	TraceFunctionWithReturns(context.Background(), fail, "x")
+
+	spans := rec.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	if s := spans[0].Status(); s.Code != codes.Error || s.Description != "boom" {
+		t.Errorf("expected an error status, got %+v", s)
+	}
+	if events := spans[0].Events(); len(events) != 1 || events[0].Name != "exception" {
+		t.Errorf("expected the error recorded as an exception event, got %+v", events)
+	}
+}
+
+func TestTraceFunctionPanicSpan(t *testing.T) {
+	SetSamplingRate(1)
+	rec, _ := recordSpans(t)
+
+	func() {
+		defer func() {
+			if r := recover(); r != "kaboom" {
+				t.Errorf("expected the panic to propagate, got %v", r)
+			}
+		}()
+		TraceFunction(context.Background(), func() { panic("kaboom") })
+	}()
+
+	spans := rec.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected the span to end on panic, got %d spans", len(spans))
+	}
+	if s := spans[0].Status(); s.Code != codes.Error {
+		t.Errorf("expected an error status, got %+v", s)
+	}
+	events := spans[0].Events()
+	if len(events) != 1 {
+		t.Fatalf("expected 1 exception event, got %d", len(events))
+	}
+	attrs := make(map[attribute.Key]bool)
+	for _, kv := range events[0].Attributes {
+		attrs[kv.Key] = true
+	}
+	if !attrs["exception.stacktrace"] {
+		t.Errorf("expected the panic stack on the exception event")
+	}
+}
+
+func TestTraceFunctionWithArgsSpanContext(t *testing.T) {
+	SetSamplingRate(1)
+	rec, tp := recordSpans(t)
+
+	ctx := context.Background()
+	inner := func(ctx context.Context, _ int) {
+		_, span := tp.Tracer("test").Start(ctx, "inner")
+		span.End()
+	}
+	TraceFunctionWithArgs(ctx, inner, ctx, 1)
+
+	spans := rec.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected the inner and call spans, got %d", len(spans))
+	}
+	if spans[0].Parent().SpanID() != spans[1].SpanContext().SpanID() {
+		t.Errorf("expected spans started by the traced function to be children of its call span")
+	}
+}
@@ -2,21 +2,34 @@ package exporters
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/iyashjayesh/monigo/common"
 	"github.com/iyashjayesh/monigo/internal/logger"
 	"github.com/iyashjayesh/monigo/internal/registry"
+	"github.com/iyashjayesh/monigo/timeseries"
 
 	"go.opentelemetry.io/otel/attribute"
 	otelmetric "go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.39.0"
+	"google.golang.org/grpc/credentials"
 
 	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
 )
 
 // OTelExporter implements the internal exporter.Exporter interface
-// and pushes metrics to an OpenTelemetry Collector via OTLP/gRPC.
+// and pushes metrics to an OpenTelemetry Collector via OTLP/gRPC or OTLP/HTTP.
 type OTelExporter struct {
 	provider *metric.MeterProvider
 	meter    otelmetric.Meter
@@ -25,54 +38,256 @@ type OTelExporter struct {
 	gauges   map[string]otelmetric.Float64ObservableGauge
 	counters map[string]otelmetric.Float64Counter
 
-	// Latest gauge values, read by callbacks registered once per gauge.
-	gaugeValues sync.Map // map[string]gaugeSnapshot
+	// Histograms bypass the instruments: their bucket counts are produced as they are.
+	histograms *histogramProducer
+
+	// Latest gauge values per series, read by callbacks registered once per gauge.
+	gaugeValues sync.Map // gaugeSnapshot keyed by registry.SeriesKey
+
+	// Registry counters are cumulative; the last exported value per series is kept so
+	// only the increase is added to the OTel counters.
+	totalsMu      sync.Mutex
+	counterTotals map[string]float64
 }
 
 type gaugeSnapshot struct {
+	name  string
 	value float64
 	attrs []attribute.KeyValue
 }
 
+// OTLP transport protocols supported by the OTel exporter.
+const (
+	OTelProtocolGRPC         = "grpc"
+	OTelProtocolHTTPProtobuf = "http/protobuf"
+	OTelProtocolHTTPJSON     = "http/json"
+)
+
+// DefaultOTelExportInterval is how often metrics are pushed when OTelConfig.ExportInterval is zero.
+const DefaultOTelExportInterval = 30 * time.Second
+
 // OTelConfig holds configuration for the OTel exporter.
 type OTelConfig struct {
+	// Endpoint is host:port ("localhost:4317", or 4318 for HTTP) or a URL. HTTP endpoints
+	// without a path post to /v1/metrics.
 	Endpoint string
 	Headers  map[string]string
-	Insecure bool // When true, use insecure gRPC (default true for backward compat)
+	// Protocol is OTelProtocolGRPC (the default), OTelProtocolHTTPProtobuf or
+	// OTelProtocolHTTPJSON. OTLP/JSON only applies to metrics: the trace exporter sends
+	// spans as OTelProtocolHTTPProtobuf to the same endpoint.
+	Protocol string
+
+	// Insecure disables TLS. Otherwise the collector certificate is verified against the
+	// system roots, or CAFile when set; CertFile and KeyFile present a client certificate.
+	Insecure bool
+	CAFile   string
+	CertFile string
+	KeyFile  string
+
+	Compression    string        // "gzip" or "" for none
+	Timeout        time.Duration // per export; default 10s
+	ExportInterval time.Duration // default DefaultOTelExportInterval
+
+	// ResourceAttributes are added to service.name, host.name and process.pid, which
+	// are derived from the service info.
+	ResourceAttributes map[string]string
 }
 
 // NewOTelExporter creates and initializes an OTel OTLP metric exporter.
 func NewOTelExporter(ctx context.Context, cfg OTelConfig) (*OTelExporter, error) {
-	opts := []otlpmetricgrpc.Option{
-		otlpmetricgrpc.WithEndpoint(cfg.Endpoint),
+	cfg, tlsCfg, err := prepareOTelConfig(cfg)
+	if err != nil {
+		return nil, err
 	}
 
-	if cfg.Insecure || len(cfg.Headers) == 0 {
-		opts = append(opts, otlpmetricgrpc.WithInsecure())
+	var exporter metric.Exporter
+	switch cfg.Protocol {
+	case "", OTelProtocolGRPC:
+		exporter, err = newOTLPGRPCExporter(ctx, cfg, tlsCfg)
+	case OTelProtocolHTTPProtobuf:
+		exporter, err = newOTLPHTTPExporter(ctx, cfg, tlsCfg)
+	case OTelProtocolHTTPJSON:
+		exporter, err = newOTLPJSONExporter(cfg, tlsCfg)
+	default:
+		return nil, fmt.Errorf("[MoniGo] unsupported OTel protocol %q", cfg.Protocol)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	newReader := func(histograms metric.Producer) metric.Reader {
+		return metric.NewPeriodicReader(exporter, metric.WithInterval(cfg.ExportInterval), metric.WithTimeout(cfg.Timeout), metric.WithProducer(histograms))
+	}
+	return newOTelExporter(newReader, metric.WithResource(otelResource(cfg.ResourceAttributes))), nil
+}
+
+// prepareOTelConfig validates cfg, fills in its defaults and loads its TLS settings;
+// the returned tls.Config is nil for insecure connections.
+func prepareOTelConfig(cfg OTelConfig) (OTelConfig, *tls.Config, error) {
+	if cfg.Endpoint == "" {
+		return cfg, nil, fmt.Errorf("[MoniGo] OTel endpoint is required")
+	}
+	if cfg.Compression != "" && cfg.Compression != "gzip" {
+		return cfg, nil, fmt.Errorf("[MoniGo] unsupported OTel compression %q", cfg.Compression)
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.ExportInterval <= 0 {
+		cfg.ExportInterval = DefaultOTelExportInterval
 	}
+	if cfg.Insecure {
+		return cfg, nil, nil
+	}
+	tlsCfg, err := otelTLSConfig(cfg)
+	return cfg, tlsCfg, err
+}
 
+func newOTLPGRPCExporter(ctx context.Context, cfg OTelConfig, tlsCfg *tls.Config) (metric.Exporter, error) {
+	opts := []otlpmetricgrpc.Option{otlpmetricgrpc.WithTimeout(cfg.Timeout)}
+	if strings.Contains(cfg.Endpoint, "://") {
+		opts = append(opts, otlpmetricgrpc.WithEndpointURL(cfg.Endpoint))
+	} else {
+		opts = append(opts, otlpmetricgrpc.WithEndpoint(cfg.Endpoint))
+	}
+	if tlsCfg == nil {
+		opts = append(opts, otlpmetricgrpc.WithInsecure())
+	} else {
+		opts = append(opts, otlpmetricgrpc.WithTLSCredentials(credentials.NewTLS(tlsCfg)))
+	}
 	if len(cfg.Headers) > 0 {
 		opts = append(opts, otlpmetricgrpc.WithHeaders(cfg.Headers))
 	}
+	if cfg.Compression != "" {
+		opts = append(opts, otlpmetricgrpc.WithCompressor(cfg.Compression))
+	}
+	return otlpmetricgrpc.New(ctx, opts...)
+}
 
-	exporter, err := otlpmetricgrpc.New(ctx, opts...)
+func newOTLPHTTPExporter(ctx context.Context, cfg OTelConfig, tlsCfg *tls.Config) (metric.Exporter, error) {
+	u, err := otlpHTTPURL(cfg, tlsCfg != nil, "metrics")
 	if err != nil {
 		return nil, err
 	}
 
-	provider := metric.NewMeterProvider(
-		metric.WithReader(metric.NewPeriodicReader(exporter, metric.WithInterval(30*time.Second))),
-	)
-	meter := provider.Meter("monigo")
+	opts := []otlpmetrichttp.Option{otlpmetrichttp.WithEndpointURL(u), otlpmetrichttp.WithTimeout(cfg.Timeout)}
+	if tlsCfg == nil {
+		opts = append(opts, otlpmetrichttp.WithInsecure())
+	} else {
+		opts = append(opts, otlpmetrichttp.WithTLSClientConfig(tlsCfg))
+	}
+	if len(cfg.Headers) > 0 {
+		opts = append(opts, otlpmetrichttp.WithHeaders(cfg.Headers))
+	}
+	if cfg.Compression == "gzip" {
+		opts = append(opts, otlpmetrichttp.WithCompression(otlpmetrichttp.GzipCompression))
+	}
+	return otlpmetrichttp.New(ctx, opts...)
+}
 
-	return &OTelExporter{
-		provider: provider,
-		meter:    meter,
-		gauges:   make(map[string]otelmetric.Float64ObservableGauge),
-		counters: make(map[string]otelmetric.Float64Counter),
+// newOTLPJSONExporter posts OTLP/JSON, which the official HTTP exporter doesn't encode.
+func newOTLPJSONExporter(cfg OTelConfig, tlsCfg *tls.Config) (metric.Exporter, error) {
+	u, err := otlpHTTPURL(cfg, tlsCfg != nil, "metrics")
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsCfg
+	return &otlpJSONExporter{
+		url:     u,
+		headers: cfg.Headers,
+		gzip:    cfg.Compression == "gzip",
+		client:  &http.Client{Timeout: cfg.Timeout, Transport: transport},
 	}, nil
 }
 
+// otlpHTTPURL resolves the URL a signal ("metrics" or "traces") is posted to. Endpoints
+// without a scheme use https when secure; without a path, /v1/<signal>. A path naming
+// another signal, such as .../v1/metrics for traces, is switched to this one.
+func otlpHTTPURL(cfg OTelConfig, secure bool, signal string) (string, error) {
+	endpoint := cfg.Endpoint
+	if !strings.Contains(endpoint, "://") {
+		scheme := "http://"
+		if secure {
+			scheme = "https://"
+		}
+		endpoint = scheme + endpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("[MoniGo] invalid OTel endpoint %q: %w", cfg.Endpoint, err)
+	}
+	switch {
+	case u.Path == "" || u.Path == "/":
+		u.Path = "/v1/" + signal
+	case signal == "traces" && strings.HasSuffix(u.Path, "/v1/metrics"):
+		u.Path = strings.TrimSuffix(u.Path, "metrics") + signal
+	}
+	return u.String(), nil
+}
+
+// otelTLSConfig loads the CA and client certificate files of cfg.
+func otelTLSConfig(cfg OTelConfig) (*tls.Config, error) {
+	tlsCfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("[MoniGo] failed to read OTel CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("[MoniGo] no certificates found in OTel CA file %s", cfg.CAFile)
+		}
+		tlsCfg.RootCAs = pool
+	}
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("[MoniGo] failed to load OTel client certificate: %w", err)
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+	return tlsCfg, nil
+}
+
+// otelResource describes the monitored service: service.name, host.name and process.pid
+// from the service info, plus the extra attributes.
+func otelResource(extra map[string]string) *resource.Resource {
+	info := common.GetServiceInfo()
+	attrs := []attribute.KeyValue{
+		semconv.HostName(timeseries.GetHostLabel().Value),
+		semconv.ProcessPID(int(info.ProcessId)),
+	}
+	if info.ServiceName != "" {
+		attrs = append(attrs, semconv.ServiceName(info.ServiceName))
+	}
+	for k, v := range extra {
+		attrs = append(attrs, attribute.String(k, v))
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, attrs...))
+	if err != nil {
+		// Only a schema URL conflict fails the merge; keep our attributes.
+		return resource.NewWithAttributes(semconv.SchemaURL, attrs...)
+	}
+	return res
+}
+
+// newOTelExporter builds the meter provider around the reader newReader returns, which
+// must collect the histograms producer as well.
+func newOTelExporter(newReader func(histograms metric.Producer) metric.Reader, opts ...metric.Option) *OTelExporter {
+	o := &OTelExporter{
+		gauges:        make(map[string]otelmetric.Float64ObservableGauge),
+		counters:      make(map[string]otelmetric.Float64Counter),
+		histograms:    newHistogramProducer(),
+		counterTotals: make(map[string]float64),
+	}
+	opts = append(opts, metric.WithReader(newReader(o.histograms)))
+	o.provider = metric.NewMeterProvider(opts...)
+	o.meter = o.provider.Meter("monigo")
+	return o
+}
+
 // Export sends metrics to the OTel collector.
 // Instruments are created once and reused on subsequent calls.
 func (o *OTelExporter) Export(ctx context.Context, metrics []*registry.MetricValue) error {
@@ -88,7 +303,9 @@ func (o *OTelExporter) Export(ctx context.Context, metrics []*registry.MetricVal
 				firstErr = err
 			}
 		case registry.Histogram:
-			logger.Log.Warn("histogram export not yet implemented", "metric", m.Name)
+			if m.Histogram != nil {
+				o.histograms.update(m)
+			}
 		}
 	}
 	return firstErr
@@ -110,10 +327,12 @@ func (o *OTelExporter) exportGauge(m *registry.MetricValue) error {
 			}
 			name := m.Name
 			_, err = o.meter.RegisterCallback(func(_ context.Context, observer otelmetric.Observer) error {
-				if snap, ok := o.gaugeValues.Load(name); ok {
-					s := snap.(gaugeSnapshot)
-					observer.ObserveFloat64(gauge, s.value, otelmetric.WithAttributes(s.attrs...))
-				}
+				o.gaugeValues.Range(func(_, snap any) bool {
+					if s := snap.(gaugeSnapshot); s.name == name {
+						observer.ObserveFloat64(gauge, s.value, otelmetric.WithAttributes(s.attrs...))
+					}
+					return true
+				})
 				return nil
 			}, gauge)
 			if err != nil {
@@ -126,7 +345,8 @@ func (o *OTelExporter) exportGauge(m *registry.MetricValue) error {
 		o.mu.Unlock()
 	}
 
-	o.gaugeValues.Store(m.Name, gaugeSnapshot{
+	o.gaugeValues.Store(registry.SeriesKey(m.Name, m.Labels), gaugeSnapshot{
+		name:  m.Name,
 		value: m.Value,
 		attrs: labelsToAttributes(m.Labels),
 	})
@@ -153,7 +373,19 @@ func (o *OTelExporter) exportCounter(ctx context.Context, m *registry.MetricValu
 		o.mu.Unlock()
 	}
 
-	counter.Add(ctx, m.Value, otelmetric.WithAttributes(labelsToAttributes(m.Labels)...))
+	key := registry.SeriesKey(m.Name, m.Labels)
+	o.totalsMu.Lock()
+	delta := m.Value - o.counterTotals[key]
+	if delta < 0 {
+		// The registry counter was reset; its whole value is new.
+		delta = m.Value
+	}
+	o.counterTotals[key] = m.Value
+	o.totalsMu.Unlock()
+
+	if delta > 0 {
+		counter.Add(ctx, delta, otelmetric.WithAttributes(labelsToAttributes(m.Labels)...))
+	}
 	return nil
 }
 
@@ -0,0 +1,139 @@
+package exporters
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/iyashjayesh/monigo/internal/registry"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// histogramProducer hands the registry histograms to the OTel reader as they are: the
+// cumulative bucket counts, sum, min and max of each series at its last export, with no
+// observation recorded again. It implements metric.Producer.
+type histogramProducer struct {
+	mu     sync.Mutex
+	series map[string]*histogramSeries // keyed by registry.SeriesKey
+}
+
+type histogramSeries struct {
+	name  string
+	attrs attribute.Set
+	start time.Time
+	data  *registry.HistogramData
+}
+
+func newHistogramProducer() *histogramProducer {
+	return &histogramProducer{series: make(map[string]*histogramSeries)}
+}
+
+// update keeps the state of the histogram series m for the next collection.
+func (p *histogramProducer) update(m *registry.MetricValue) {
+	key := registry.SeriesKey(m.Name, m.Labels)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s := p.series[key]
+	if s == nil || s.data.Count > m.Histogram.Count {
+		// A new series, or one reset in the registry, which starts again.
+		s = &histogramSeries{
+			name:  m.Name,
+			attrs: attribute.NewSet(labelsToAttributes(m.Labels)...),
+			start: time.Now(),
+		}
+		p.series[key] = s
+	}
+	s.data = m.Histogram
+}
+
+// Produce returns one cumulative data point per histogram series.
+func (p *histogramProducer) Produce(context.Context) ([]metricdata.ScopeMetrics, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.series) == 0 {
+		return nil, nil
+	}
+
+	byName := make(map[string][]*histogramSeries)
+	for _, s := range p.series {
+		byName[s.name] = append(byName[s.name], s)
+	}
+	now := time.Now()
+	metrics := make([]metricdata.Metrics, 0, len(byName))
+	for _, name := range slices.Sorted(maps.Keys(byName)) {
+		series := byName[name]
+		if series[0].data.Exponential {
+			data := metricdata.ExponentialHistogram[float64]{Temporality: metricdata.CumulativeTemporality}
+			for _, s := range series {
+				data.DataPoints = append(data.DataPoints, exponentialDataPoint(s, now))
+			}
+			metrics = append(metrics, metricdata.Metrics{Name: name, Data: data})
+			continue
+		}
+		data := metricdata.Histogram[float64]{Temporality: metricdata.CumulativeTemporality}
+		for _, s := range series {
+			data.DataPoints = append(data.DataPoints, explicitDataPoint(s, now))
+		}
+		metrics = append(metrics, metricdata.Metrics{Name: name, Data: data})
+	}
+	return []metricdata.ScopeMetrics{{Scope: instrumentation.Scope{Name: "monigo"}, Metrics: metrics}}, nil
+}
+
+func explicitDataPoint(s *histogramSeries, now time.Time) metricdata.HistogramDataPoint[float64] {
+	h := s.data
+	dp := metricdata.HistogramDataPoint[float64]{
+		Attributes:   s.attrs,
+		StartTime:    s.start,
+		Time:         now,
+		Count:        h.Count,
+		Sum:          h.Sum,
+		Bounds:       slices.Clone(h.Bounds),
+		BucketCounts: make([]uint64, len(h.Buckets)),
+	}
+	for i, b := range h.Buckets {
+		dp.BucketCounts[i] = b.Count
+	}
+	if h.Count > 0 {
+		dp.Min, dp.Max = metricdata.NewExtrema(h.Min), metricdata.NewExtrema(h.Max)
+	}
+	return dp
+}
+
+func exponentialDataPoint(s *histogramSeries, now time.Time) metricdata.ExponentialHistogramDataPoint[float64] {
+	h := s.data
+	dp := metricdata.ExponentialHistogramDataPoint[float64]{
+		Attributes:     s.attrs,
+		StartTime:      s.start,
+		Time:           now,
+		Count:          h.Count,
+		Sum:            h.Sum,
+		Scale:          h.Scale,
+		ZeroCount:      h.ZeroCount,
+		PositiveBucket: exponentialBucket(h.Positive),
+		NegativeBucket: exponentialBucket(h.Negative),
+	}
+	if h.Count > 0 {
+		dp.Min, dp.Max = metricdata.NewExtrema(h.Min), metricdata.NewExtrema(h.Max)
+	}
+	return dp
+}
+
+// exponentialBucket lays out the sparse buckets of a registry histogram from the lowest
+// index to the highest. Both index bucket i as the values in (base^i, base^(i+1)].
+func exponentialBucket(buckets map[int32]registry.Bucket) metricdata.ExponentialBucket {
+	if len(buckets) == 0 {
+		return metricdata.ExponentialBucket{}
+	}
+	indices := slices.Collect(maps.Keys(buckets))
+	lo, hi := slices.Min(indices), slices.Max(indices)
+	counts := make([]uint64, hi-lo+1)
+	for i, b := range buckets {
+		counts[i-lo] = b.Count
+	}
+	return metricdata.ExponentialBucket{Offset: lo, Counts: counts}
+}
@@ -0,0 +1,134 @@
+package exporters
+
+import (
+	"context"
+	"testing"
+
+	"github.com/iyashjayesh/monigo/internal/registry"
+	"go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// newTestOTelExporter returns an exporter whose metrics are read back through a manual reader.
+func newTestOTelExporter() (*OTelExporter, *metric.ManualReader) {
+	var reader *metric.ManualReader
+	o := newOTelExporter(func(histograms metric.Producer) metric.Reader {
+		reader = metric.NewManualReader(metric.WithProducer(histograms))
+		return reader
+	})
+	return o, reader
+}
+
+// collectByFunction returns the data points of a metric keyed by their function attribute.
+func collectByFunction(t *testing.T, reader *metric.ManualReader, name string) map[string]float64 {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	out := map[string]float64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != name {
+				continue
+			}
+			switch data := m.Data.(type) {
+			case metricdata.Gauge[float64]:
+				for _, dp := range data.DataPoints {
+					fn, _ := dp.Attributes.Value("function")
+					out[fn.AsString()] = dp.Value
+				}
+			case metricdata.Sum[float64]:
+				for _, dp := range data.DataPoints {
+					fn, _ := dp.Attributes.Value("function")
+					out[fn.AsString()] = dp.Value
+				}
+			}
+		}
+	}
+	return out
+}
+
+func TestOTelExporterLabelledSeries(t *testing.T) {
+	o, reader := newTestOTelExporter()
+	defer o.Shutdown(context.Background())
+
+	r := registry.NewRegistry()
+	r.SetGauge("goroutine_delta", 1, map[string]string{"function": "a"})
+	r.SetGauge("goroutine_delta", 2, map[string]string{"function": "b"})
+	r.IncrementCounter("calls", 3, map[string]string{"function": "a"})
+	if err := o.Export(context.Background(), r.GetAll()); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	// The registry counter is cumulative; exporting it again must only add the increase.
+	r.IncrementCounter("calls", 2, map[string]string{"function": "a"})
+	if err := o.Export(context.Background(), r.GetAll()); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	gauges := collectByFunction(t, reader, "goroutine_delta")
+	if len(gauges) != 2 || gauges["a"] != 1 || gauges["b"] != 2 {
+		t.Errorf("expected one gauge series per function, got %v", gauges)
+	}
+	if calls := collectByFunction(t, reader, "calls"); calls["a"] != 5 {
+		t.Errorf("expected counter total 5, got %v", calls)
+	}
+}
+
+func TestOTelExporterHistograms(t *testing.T) {
+	o, reader := newTestOTelExporter()
+	defer o.Shutdown(context.Background())
+
+	r := registry.NewRegistry()
+	r.ConfigureHistogram("latency", registry.HistogramOpts{Buckets: []float64{0.1, 1}})
+	r.ConfigureHistogram("payload", registry.HistogramOpts{Exponential: true, Scale: 2})
+	for _, v := range []float64{0.05, 0.1, 0.5} {
+		r.RecordHistogram("latency", v, nil)
+	}
+	r.RecordHistogram("payload", 300, nil)
+	if err := o.Export(context.Background(), r.GetAll()); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	// The next export carries the cumulative state, with no observation recorded again.
+	r.RecordHistogram("latency", 4, nil)
+	if err := o.Export(context.Background(), r.GetAll()); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	var explicit, exponential bool
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			switch data := m.Data.(type) {
+			case metricdata.Histogram[float64]:
+				explicit = true
+				dp := data.DataPoints[0]
+				if dp.Count != 4 || dp.Sum != 4.65 {
+					t.Errorf("expected count 4 and sum 4.65, got %d and %v", dp.Count, dp.Sum)
+				}
+				if len(dp.Bounds) != 2 || dp.BucketCounts[0] != 2 || dp.BucketCounts[1] != 1 || dp.BucketCounts[2] != 1 {
+					t.Errorf("unexpected buckets %v %v", dp.Bounds, dp.BucketCounts)
+				}
+				if lo, _ := dp.Min.Value(); lo != 0.05 {
+					t.Errorf("expected the observed min 0.05, got %v", lo)
+				}
+				if hi, _ := dp.Max.Value(); hi != 4 {
+					t.Errorf("expected the observed max 4, got %v", hi)
+				}
+			case metricdata.ExponentialHistogram[float64]:
+				exponential = true
+				if dp := data.DataPoints[0]; dp.Count != 1 || dp.Sum != 300 || dp.Scale != 2 || len(dp.PositiveBucket.Counts) != 1 {
+					t.Errorf("unexpected exponential data point %+v", dp)
+				}
+			}
+		}
+	}
+	if !explicit || !exponential {
+		t.Errorf("expected an explicit and an exponential histogram, got %v and %v", explicit, exponential)
+	}
+}
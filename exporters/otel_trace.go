@@ -0,0 +1,103 @@
+package exporters
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/iyashjayesh/monigo/internal/logger"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/credentials"
+)
+
+// OTelTraceExporter sends the spans of traced functions to an OpenTelemetry Collector
+// over OTLP, using the same OTelConfig as the metric exporter.
+type OTelTraceExporter struct {
+	provider *sdktrace.TracerProvider
+}
+
+// NewOTelTraceExporter creates a tracer provider batching spans to cfg.Endpoint. Spans
+// are sampled when their parent is, and always when they start a new trace. OTLP/JSON
+// is not supported for traces; http/json falls back to http/protobuf.
+func NewOTelTraceExporter(ctx context.Context, cfg OTelConfig) (*OTelTraceExporter, error) {
+	cfg, tlsCfg, err := prepareOTelConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var client otlptrace.Client
+	switch cfg.Protocol {
+	case "", OTelProtocolGRPC:
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithTimeout(cfg.Timeout)}
+		if strings.Contains(cfg.Endpoint, "://") {
+			opts = append(opts, otlptracegrpc.WithEndpointURL(cfg.Endpoint))
+		} else {
+			opts = append(opts, otlptracegrpc.WithEndpoint(cfg.Endpoint))
+		}
+		if tlsCfg == nil {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		} else {
+			opts = append(opts, otlptracegrpc.WithTLSCredentials(credentials.NewTLS(tlsCfg)))
+		}
+		if len(cfg.Headers) > 0 {
+			opts = append(opts, otlptracegrpc.WithHeaders(cfg.Headers))
+		}
+		if cfg.Compression != "" {
+			opts = append(opts, otlptracegrpc.WithCompressor(cfg.Compression))
+		}
+		client = otlptracegrpc.NewClient(opts...)
+	case OTelProtocolHTTPProtobuf, OTelProtocolHTTPJSON:
+		if cfg.Protocol == OTelProtocolHTTPJSON {
+			logger.Log.Warn("OTLP/JSON is not supported for traces, sending spans as protobuf")
+		}
+		u, err := otlpHTTPURL(cfg, tlsCfg != nil, "traces")
+		if err != nil {
+			return nil, err
+		}
+		opts := []otlptracehttp.Option{otlptracehttp.WithEndpointURL(u), otlptracehttp.WithTimeout(cfg.Timeout)}
+		if tlsCfg == nil {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		} else {
+			opts = append(opts, otlptracehttp.WithTLSClientConfig(tlsCfg))
+		}
+		if len(cfg.Headers) > 0 {
+			opts = append(opts, otlptracehttp.WithHeaders(cfg.Headers))
+		}
+		if cfg.Compression == "gzip" {
+			opts = append(opts, otlptracehttp.WithCompression(otlptracehttp.GzipCompression))
+		}
+		client = otlptracehttp.NewClient(opts...)
+	default:
+		return nil, fmt.Errorf("[MoniGo] unsupported OTel protocol %q", cfg.Protocol)
+	}
+
+	exporter, err := otlptrace.New(ctx, client)
+	if err != nil {
+		return nil, fmt.Errorf("[MoniGo] failed to create OTLP trace exporter: %w", err)
+	}
+	return newOTelTraceExporter(sdktrace.NewBatchSpanProcessor(exporter), sdktrace.WithResource(otelResource(cfg.ResourceAttributes))), nil
+}
+
+// newOTelTraceExporter builds the tracer provider around processor.
+func newOTelTraceExporter(processor sdktrace.SpanProcessor, opts ...sdktrace.TracerProviderOption) *OTelTraceExporter {
+	opts = append(opts,
+		sdktrace.WithSpanProcessor(processor),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.AlwaysSample())),
+	)
+	return &OTelTraceExporter{provider: sdktrace.NewTracerProvider(opts...)}
+}
+
+// TracerProvider returns the provider traced functions start their spans from.
+func (o *OTelTraceExporter) TracerProvider() trace.TracerProvider {
+	return o.provider
+}
+
+// Shutdown exports the buffered spans and stops the provider.
+func (o *OTelTraceExporter) Shutdown(ctx context.Context) error {
+	return o.provider.Shutdown(ctx)
+}
@@ -0,0 +1,76 @@
+package exporters
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestOTelTraceExporterHTTP(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		paths []string
+		spans []string
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+		req := &coltracepb.ExportTraceServiceRequest{}
+		if err := proto.Unmarshal(raw, req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		paths = append(paths, r.URL.Path)
+		for _, rs := range req.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				for _, s := range ss.Spans {
+					spans = append(spans, s.Name)
+				}
+			}
+		}
+	}))
+	defer ts.Close()
+
+	// The metrics path of a shared endpoint is switched to the traces one.
+	o, err := NewOTelTraceExporter(context.Background(), OTelConfig{
+		Endpoint: ts.URL + "/otlp/v1/metrics",
+		Protocol: OTelProtocolHTTPProtobuf,
+		Insecure: true,
+	})
+	if err != nil {
+		t.Fatalf("NewOTelTraceExporter() error = %v", err)
+	}
+
+	_, span := o.TracerProvider().Tracer("test").Start(context.Background(), "checkout")
+	span.End()
+	if err := o.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(paths) != 1 || paths[0] != "/otlp/v1/traces" {
+		t.Fatalf("expected one request to /otlp/v1/traces, got %v", paths)
+	}
+	if len(spans) != 1 || spans[0] != "checkout" {
+		t.Errorf("expected the checkout span, got %v", spans)
+	}
+}
+
+func TestNewOTelTraceExporterInvalidConfig(t *testing.T) {
+	for name, cfg := range map[string]OTelConfig{
+		"no endpoint": {},
+		"protocol":    {Endpoint: "localhost:4317", Protocol: "udp"},
+	} {
+		if _, err := NewOTelTraceExporter(context.Background(), cfg); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package exporters
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/iyashjayesh/monigo/common"
+	"github.com/iyashjayesh/monigo/internal/registry"
+	colmetricpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// otlpServer records the export requests posted to it.
+type otlpServer struct {
+	mu       sync.Mutex
+	requests []*colmetricpb.ExportMetricsServiceRequest
+	headers  []http.Header
+}
+
+func (s *otlpServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		body = zr
+	}
+	raw, _ := io.ReadAll(body)
+
+	req := &colmetricpb.ExportMetricsServiceRequest{}
+	var err error
+	if r.Header.Get("Content-Type") == "application/json" {
+		err = protojson.Unmarshal(raw, req)
+	} else {
+		err = proto.Unmarshal(raw, req)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	s.headers = append(s.headers, r.Header.Clone())
+	s.mu.Unlock()
+}
+
+// exportOnce records a gauge and forces the exporter to push it.
+func exportOnce(t *testing.T, o *OTelExporter) {
+	t.Helper()
+	err := o.Export(context.Background(), []*registry.MetricValue{
+		{Name: "queue_depth", Value: 7, Labels: map[string]string{"queue": "emails"}, Type: registry.Gauge},
+	})
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if err := o.provider.ForceFlush(context.Background()); err != nil {
+		t.Fatalf("ForceFlush() error = %v", err)
+	}
+}
+
+func TestOTelExporterHTTP(t *testing.T) {
+	common.SetServiceInfo("otlp-test", time.Now(), runtime.Version(), 4321, "7d")
+
+	for _, protocol := range []string{OTelProtocolHTTPProtobuf, OTelProtocolHTTPJSON} {
+		t.Run(protocol, func(t *testing.T) {
+			srv := &otlpServer{}
+			ts := httptest.NewServer(srv)
+			defer ts.Close()
+
+			o, err := NewOTelExporter(context.Background(), OTelConfig{
+				Endpoint:           ts.Listener.Addr().String(),
+				Protocol:           protocol,
+				Insecure:           true,
+				Headers:            map[string]string{"Authorization": "Bearer token"},
+				Compression:        "gzip",
+				ExportInterval:     time.Hour,
+				ResourceAttributes: map[string]string{"deployment.environment": "test"},
+			})
+			if err != nil {
+				t.Fatalf("NewOTelExporter() error = %v", err)
+			}
+			defer o.Shutdown(context.Background())
+			exportOnce(t, o)
+
+			srv.mu.Lock()
+			defer srv.mu.Unlock()
+			if len(srv.requests) != 1 {
+				t.Fatalf("expected 1 request, got %d", len(srv.requests))
+			}
+			if got := srv.headers[0].Get("Authorization"); got != "Bearer token" {
+				t.Errorf("expected auth header, got %q", got)
+			}
+
+			rm := srv.requests[0].ResourceMetrics[0]
+			attrs := map[string]string{}
+			for _, kv := range rm.Resource.Attributes {
+				attrs[kv.Key] = kv.Value.String()
+			}
+			for _, key := range []string{"service.name", "host.name", "process.pid", "deployment.environment"} {
+				if attrs[key] == "" {
+					t.Errorf("expected resource attribute %s, got %v", key, attrs)
+				}
+			}
+
+			var found bool
+			for _, sm := range rm.ScopeMetrics {
+				for _, m := range sm.Metrics {
+					if m.Name == "queue_depth" {
+						found = true
+						if v := m.GetGauge().DataPoints[0].GetAsDouble(); v != 7 {
+							t.Errorf("expected gauge of 7, got %v", v)
+						}
+					}
+				}
+			}
+			if !found {
+				t.Error("expected queue_depth to be exported")
+			}
+		})
+	}
+}
+
+func TestOTelExporterHTTPJSONRetries(t *testing.T) {
+	backoff := otlpRetryInitialBackoff
+	otlpRetryInitialBackoff = time.Millisecond
+	defer func() { otlpRetryInitialBackoff = backoff }()
+
+	srv := &otlpServer{}
+	var unavailable atomic.Int32
+	unavailable.Store(2)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if unavailable.Add(-1) >= 0 {
+			http.Error(w, "try again", http.StatusServiceUnavailable)
+			return
+		}
+		srv.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	o, err := NewOTelExporter(context.Background(), OTelConfig{
+		Endpoint:       ts.URL,
+		Protocol:       OTelProtocolHTTPJSON,
+		Insecure:       true,
+		ExportInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("NewOTelExporter() error = %v", err)
+	}
+	defer o.Shutdown(context.Background())
+	exportOnce(t, o)
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if len(srv.requests) != 1 {
+		t.Fatalf("expected the export delivered after 2 retries, got %d requests", len(srv.requests))
+	}
+}
+
+func TestOTelExporterHTTPWithTLS(t *testing.T) {
+	srv := &otlpServer{}
+	ts := httptest.NewTLSServer(srv)
+	defer ts.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw})
+	if err := os.WriteFile(caFile, caPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	o, err := NewOTelExporter(context.Background(), OTelConfig{
+		Endpoint:       ts.URL + "/otlp/v1/metrics",
+		Protocol:       OTelProtocolHTTPProtobuf,
+		CAFile:         caFile,
+		ExportInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("NewOTelExporter() error = %v", err)
+	}
+	defer o.Shutdown(context.Background())
+	exportOnce(t, o)
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if len(srv.requests) != 1 {
+		t.Fatalf("expected 1 request over TLS, got %d", len(srv.requests))
+	}
+}
+
+func TestNewOTelExporterInvalidConfig(t *testing.T) {
+	for name, cfg := range map[string]OTelConfig{
+		"no endpoint":     {},
+		"protocol":        {Endpoint: "localhost:4317", Protocol: "udp"},
+		"compression":     {Endpoint: "localhost:4317", Compression: "zstd"},
+		"missing CA file": {Endpoint: "localhost:4318", Protocol: OTelProtocolHTTPProtobuf, CAFile: "/nonexistent/ca.pem"},
+	} {
+		if _, err := NewOTelExporter(context.Background(), cfg); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
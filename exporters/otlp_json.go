@@ -0,0 +1,311 @@
+package exporters
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/iyashjayesh/monigo/internal/logger"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	colmetricpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricpb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// Backoff between the retries of an OTLP/JSON export, unless the collector asks for
+// longer with Retry-After.
+var (
+	otlpRetryInitialBackoff = 500 * time.Millisecond
+	otlpRetryMaxBackoff     = 5 * time.Second
+)
+
+// otlpJSONExporter is a metric.Exporter posting OTLP/JSON export requests, for the
+// collectors that only accept it. Like the official exporters, it retries throttled and
+// unavailable responses until the export times out, and logs partial successes.
+type otlpJSONExporter struct {
+	url     string
+	headers map[string]string
+	gzip    bool
+	client  *http.Client
+}
+
+// Temporality returns the default cumulative temporality.
+func (e *otlpJSONExporter) Temporality(k metric.InstrumentKind) metricdata.Temporality {
+	return metric.DefaultTemporalitySelector(k)
+}
+
+// Aggregation returns the default aggregation of the instrument kind.
+func (e *otlpJSONExporter) Aggregation(k metric.InstrumentKind) metric.Aggregation {
+	return metric.DefaultAggregationSelector(k)
+}
+
+// Export sends rm in a single request, retried while ctx allows.
+func (e *otlpJSONExporter) Export(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+	req := &colmetricpb.ExportMetricsServiceRequest{ResourceMetrics: []*metricpb.ResourceMetrics{toOTLPResourceMetrics(rm)}}
+	// OTLP/JSON requires enum values as integers.
+	body, err := protojson.MarshalOptions{UseEnumNumbers: true}.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("[MoniGo] failed to encode OTLP request: %w", err)
+	}
+
+	if e.gzip {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(body); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		body = buf.Bytes()
+	}
+
+	backoff := otlpRetryInitialBackoff
+	for {
+		retryAfter, err := e.post(ctx, body)
+		if err == nil || retryAfter < 0 {
+			return err
+		}
+		timer := time.NewTimer(max(backoff, retryAfter))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+		backoff = min(2*backoff, otlpRetryMaxBackoff)
+	}
+}
+
+// post sends one request. A failed one that may be retried returns how long the
+// collector asked to wait, zero if it didn't; any other returns -1.
+func (e *otlpJSONExporter) post(ctx context.Context, body []byte) (time.Duration, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return -1, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if e.gzip {
+		httpReq.Header.Set("Content-Encoding", "gzip")
+	}
+	for k, v := range e.headers {
+		httpReq.Header.Set(k, v)
+	}
+
+	resp, err := e.client.Do(httpReq)
+	if err != nil {
+		return -1, err
+	}
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		var retryAfter time.Duration
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+			retryAfter = time.Duration(secs) * time.Second
+		}
+		return retryAfter, fmt.Errorf("[MoniGo] OTLP export failed with status %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	if resp.StatusCode/100 != 2 {
+		return -1, fmt.Errorf("[MoniGo] OTLP export failed with status %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+
+	var out colmetricpb.ExportMetricsServiceResponse
+	if len(msg) > 0 && protojson.Unmarshal(msg, &out) == nil {
+		if ps := out.GetPartialSuccess(); ps.GetRejectedDataPoints() > 0 || ps.GetErrorMessage() != "" {
+			logger.Log.Warn("OTLP collector rejected part of the export",
+				"rejected_data_points", ps.GetRejectedDataPoints(), "error", ps.GetErrorMessage())
+		}
+	}
+	return 0, nil
+}
+
+// ForceFlush is a no-op: Export sends synchronously.
+func (e *otlpJSONExporter) ForceFlush(context.Context) error {
+	return nil
+}
+
+// Shutdown releases idle connections.
+func (e *otlpJSONExporter) Shutdown(context.Context) error {
+	e.client.CloseIdleConnections()
+	return nil
+}
+
+func toOTLPResourceMetrics(rm *metricdata.ResourceMetrics) *metricpb.ResourceMetrics {
+	out := &metricpb.ResourceMetrics{Resource: &resourcepb.Resource{}}
+	if rm.Resource != nil {
+		out.Resource.Attributes = toOTLPAttributes(rm.Resource.Iter())
+		out.SchemaUrl = rm.Resource.SchemaURL()
+	}
+	for _, sm := range rm.ScopeMetrics {
+		scope := &metricpb.ScopeMetrics{
+			Scope:     &commonpb.InstrumentationScope{Name: sm.Scope.Name, Version: sm.Scope.Version},
+			SchemaUrl: sm.Scope.SchemaURL,
+		}
+		for _, m := range sm.Metrics {
+			if pm := toOTLPMetric(m); pm != nil {
+				scope.Metrics = append(scope.Metrics, pm)
+			}
+		}
+		out.ScopeMetrics = append(out.ScopeMetrics, scope)
+	}
+	return out
+}
+
+// toOTLPMetric converts one metric; aggregations without an OTLP mapping return nil.
+func toOTLPMetric(m metricdata.Metrics) *metricpb.Metric {
+	out := &metricpb.Metric{Name: m.Name, Description: m.Description, Unit: m.Unit}
+	switch data := m.Data.(type) {
+	case metricdata.Gauge[float64]:
+		out.Data = &metricpb.Metric_Gauge{Gauge: &metricpb.Gauge{DataPoints: toOTLPNumberPoints(data.DataPoints)}}
+	case metricdata.Gauge[int64]:
+		out.Data = &metricpb.Metric_Gauge{Gauge: &metricpb.Gauge{DataPoints: toOTLPNumberPoints(data.DataPoints)}}
+	case metricdata.Sum[float64]:
+		out.Data = &metricpb.Metric_Sum{Sum: &metricpb.Sum{
+			DataPoints:             toOTLPNumberPoints(data.DataPoints),
+			AggregationTemporality: toOTLPTemporality(data.Temporality),
+			IsMonotonic:            data.IsMonotonic,
+		}}
+	case metricdata.Sum[int64]:
+		out.Data = &metricpb.Metric_Sum{Sum: &metricpb.Sum{
+			DataPoints:             toOTLPNumberPoints(data.DataPoints),
+			AggregationTemporality: toOTLPTemporality(data.Temporality),
+			IsMonotonic:            data.IsMonotonic,
+		}}
+	case metricdata.Histogram[float64]:
+		out.Data = &metricpb.Metric_Histogram{Histogram: &metricpb.Histogram{
+			DataPoints:             toOTLPHistogramPoints(data.DataPoints),
+			AggregationTemporality: toOTLPTemporality(data.Temporality),
+		}}
+	case metricdata.Histogram[int64]:
+		out.Data = &metricpb.Metric_Histogram{Histogram: &metricpb.Histogram{
+			DataPoints:             toOTLPHistogramPoints(data.DataPoints),
+			AggregationTemporality: toOTLPTemporality(data.Temporality),
+		}}
+	case metricdata.ExponentialHistogram[float64]:
+		out.Data = &metricpb.Metric_ExponentialHistogram{ExponentialHistogram: &metricpb.ExponentialHistogram{
+			DataPoints:             toOTLPExponentialPoints(data.DataPoints),
+			AggregationTemporality: toOTLPTemporality(data.Temporality),
+		}}
+	case metricdata.ExponentialHistogram[int64]:
+		out.Data = &metricpb.Metric_ExponentialHistogram{ExponentialHistogram: &metricpb.ExponentialHistogram{
+			DataPoints:             toOTLPExponentialPoints(data.DataPoints),
+			AggregationTemporality: toOTLPTemporality(data.Temporality),
+		}}
+	default:
+		return nil
+	}
+	return out
+}
+
+func toOTLPNumberPoints[N int64 | float64](points []metricdata.DataPoint[N]) []*metricpb.NumberDataPoint {
+	out := make([]*metricpb.NumberDataPoint, len(points))
+	for i, p := range points {
+		dp := &metricpb.NumberDataPoint{
+			Attributes:        toOTLPAttributes(p.Attributes.Iter()),
+			StartTimeUnixNano: uint64(p.StartTime.UnixNano()),
+			TimeUnixNano:      uint64(p.Time.UnixNano()),
+		}
+		switch v := any(p.Value).(type) {
+		case int64:
+			dp.Value = &metricpb.NumberDataPoint_AsInt{AsInt: v}
+		case float64:
+			dp.Value = &metricpb.NumberDataPoint_AsDouble{AsDouble: v}
+		}
+		out[i] = dp
+	}
+	return out
+}
+
+func toOTLPHistogramPoints[N int64 | float64](points []metricdata.HistogramDataPoint[N]) []*metricpb.HistogramDataPoint {
+	out := make([]*metricpb.HistogramDataPoint, len(points))
+	for i, p := range points {
+		sum := float64(p.Sum)
+		dp := &metricpb.HistogramDataPoint{
+			Attributes:        toOTLPAttributes(p.Attributes.Iter()),
+			StartTimeUnixNano: uint64(p.StartTime.UnixNano()),
+			TimeUnixNano:      uint64(p.Time.UnixNano()),
+			Count:             p.Count,
+			Sum:               &sum,
+			BucketCounts:      p.BucketCounts,
+			ExplicitBounds:    p.Bounds,
+		}
+		dp.Min, dp.Max = toOTLPExtrema(p.Min), toOTLPExtrema(p.Max)
+		out[i] = dp
+	}
+	return out
+}
+
+func toOTLPExponentialPoints[N int64 | float64](points []metricdata.ExponentialHistogramDataPoint[N]) []*metricpb.ExponentialHistogramDataPoint {
+	out := make([]*metricpb.ExponentialHistogramDataPoint, len(points))
+	for i, p := range points {
+		sum := float64(p.Sum)
+		dp := &metricpb.ExponentialHistogramDataPoint{
+			Attributes:        toOTLPAttributes(p.Attributes.Iter()),
+			StartTimeUnixNano: uint64(p.StartTime.UnixNano()),
+			TimeUnixNano:      uint64(p.Time.UnixNano()),
+			Count:             p.Count,
+			Sum:               &sum,
+			Scale:             p.Scale,
+			ZeroCount:         p.ZeroCount,
+			ZeroThreshold:     p.ZeroThreshold,
+			Positive:          &metricpb.ExponentialHistogramDataPoint_Buckets{Offset: p.PositiveBucket.Offset, BucketCounts: p.PositiveBucket.Counts},
+			Negative:          &metricpb.ExponentialHistogramDataPoint_Buckets{Offset: p.NegativeBucket.Offset, BucketCounts: p.NegativeBucket.Counts},
+		}
+		dp.Min, dp.Max = toOTLPExtrema(p.Min), toOTLPExtrema(p.Max)
+		out[i] = dp
+	}
+	return out
+}
+
+func toOTLPExtrema[N int64 | float64](e metricdata.Extrema[N]) *float64 {
+	v, ok := e.Value()
+	if !ok {
+		return nil
+	}
+	f := float64(v)
+	return &f
+}
+
+func toOTLPTemporality(t metricdata.Temporality) metricpb.AggregationTemporality {
+	switch t {
+	case metricdata.DeltaTemporality:
+		return metricpb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA
+	case metricdata.CumulativeTemporality:
+		return metricpb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE
+	}
+	return metricpb.AggregationTemporality_AGGREGATION_TEMPORALITY_UNSPECIFIED
+}
+
+func toOTLPAttributes(it attribute.Iterator) []*commonpb.KeyValue {
+	out := make([]*commonpb.KeyValue, 0, it.Len())
+	for it.Next() {
+		kv := it.Attribute()
+		out = append(out, &commonpb.KeyValue{Key: string(kv.Key), Value: toOTLPValue(kv.Value)})
+	}
+	return out
+}
+
+func toOTLPValue(v attribute.Value) *commonpb.AnyValue {
+	switch v.Type() {
+	case attribute.BOOL:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: v.AsBool()}}
+	case attribute.INT64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: v.AsInt64()}}
+	case attribute.FLOAT64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: v.AsFloat64()}}
+	case attribute.STRING:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v.AsString()}}
+	}
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v.Emit()}}
+}
@@ -4,18 +4,52 @@ import (
 	"context"
 	"sync"
 
+	"github.com/iyashjayesh/monigo/common"
 	"github.com/iyashjayesh/monigo/core"
+	"github.com/iyashjayesh/monigo/models"
+	"github.com/iyashjayesh/monigo/timeseries"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
+// collectorLabels are attached to every metric; values come from the service info
+// and the host label used by storage.
+var collectorLabels = []string{"service", "host"}
+
+// functionLabels are attached to the per-function tracing metrics.
+var functionLabels = []string{"service", "host", "function"}
+
+// statMetric maps a ServiceStats field to a Prometheus metric.
+type statMetric struct {
+	desc      *prometheus.Desc
+	valueType prometheus.ValueType
+	value     func(*models.ServiceStats) float64
+}
+
+// memStatMetric maps a RawMemStatsRecords entry to a Prometheus metric. Records are
+// stored in KB for byte values, so scale converts them back to base units.
+type memStatMetric struct {
+	desc      *prometheus.Desc
+	valueType prometheus.ValueType
+	scale     float64
+}
+
+// functionDescs describe the series exported for every traced function.
+type functionDescs struct {
+	calls          *prometheus.Desc
+	executionTime  *prometheus.Desc
+	memoryDelta    *prometheus.Desc
+	goroutineDelta *prometheus.Desc
+	errors         *prometheus.Desc
+	panics         *prometheus.Desc
+}
+
 // MonigoCollector implements the prometheus.Collector interface.
+// It exposes the same metric catalogue that StoreServiceMetrics persists,
+// plus the statistics of functions traced with core.TraceFunction*.
 type MonigoCollector struct {
-	cpuUsage    *prometheus.Desc
-	memoryUsage *prometheus.Desc
-	goroutines  *prometheus.Desc
-
-	diskReadBytes  *prometheus.Desc
-	diskWriteBytes *prometheus.Desc
+	stats     []statMetric
+	memStats  map[string]memStatMetric // keyed by RawMemStatsRecords.RecordName
+	functions functionDescs
 }
 
 var (
@@ -23,84 +57,115 @@ var (
 	collector *MonigoCollector
 )
 
+func newDesc(name, help string) *prometheus.Desc {
+	return prometheus.NewDesc(name, help, collectorLabels, nil)
+}
+
 // NewMonigoCollector returns a singleton instance of MonigoCollector.
 func NewMonigoCollector() *MonigoCollector {
 	once.Do(func() {
 		collector = &MonigoCollector{
-			cpuUsage: prometheus.NewDesc(
-				"monigo_cpu_usage_percent",
-				"Current system CPU usage percentage.",
-				nil, nil,
-			),
-			memoryUsage: prometheus.NewDesc(
-				"monigo_memory_usage_bytes",
-				"Current system memory usage in bytes.",
-				nil, nil,
-			),
-			goroutines: prometheus.NewDesc(
-				"monigo_goroutines_count",
-				"Number of goroutines running.",
-				nil, nil,
-			),
-			diskReadBytes: prometheus.NewDesc(
-				"monigo_disk_read_bytes_total",
-				"Total bytes read from disk.",
-				nil, nil,
-			),
-			diskWriteBytes: prometheus.NewDesc(
-				"monigo_disk_write_bytes_total",
-				"Total bytes written to disk.",
-				nil, nil,
-			),
+			stats:     serviceStatMetrics(),
+			memStats:  memStatMetrics(),
+			functions: newFunctionDescs(),
 		}
 	})
 	return collector
 }
 
+// serviceStatMetrics builds the descriptors of core.ServiceStatMetrics.
+func serviceStatMetrics() []statMetric {
+	catalogue := core.ServiceStatMetrics()
+	metrics := make([]statMetric, len(catalogue))
+	for i, m := range catalogue {
+		metrics[i] = statMetric{desc: newDesc(m.Name, m.Help), valueType: valueType(m.Counter), value: m.Value}
+	}
+	return metrics
+}
+
+// memStatMetrics builds the descriptors of core.MemStatMetrics.
+func memStatMetrics() map[string]memStatMetric {
+	catalogue := core.MemStatMetrics()
+	metrics := make(map[string]memStatMetric, len(catalogue))
+	for record, m := range catalogue {
+		metrics[record] = memStatMetric{desc: newDesc(m.Name, m.Help), valueType: valueType(m.Counter), scale: m.Scale}
+	}
+	return metrics
+}
+
+func valueType(counter bool) prometheus.ValueType {
+	if counter {
+		return prometheus.CounterValue
+	}
+	return prometheus.GaugeValue
+}
+
+func newFunctionDescs() functionDescs {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(name, help, functionLabels, nil)
+	}
+	return functionDescs{
+		calls:          desc(core.FunctionCallsMetric, "Number of calls of a traced function."),
+		executionTime:  desc(core.FunctionExecutionTimeMetric, "Execution time of a traced function."),
+		memoryDelta:    desc(core.FunctionMemoryDeltaMetric, "Heap bytes allocated by the last profiled call of a traced function."),
+		goroutineDelta: desc(core.FunctionGoroutineDeltaMetric, "Goroutines left running by the last call of a traced function."),
+		errors:         desc(core.FunctionErrorsMetric, "Number of calls of a traced function that returned an error or panicked."),
+		panics:         desc(core.FunctionPanicsMetric, "Number of calls of a traced function that panicked."),
+	}
+}
+
 // Describe sends the super-set of all possible descriptors of metrics
 // collected by this Collector to the provided channel.
 func (c *MonigoCollector) Describe(ch chan<- *prometheus.Desc) {
-	ch <- c.cpuUsage
-	ch <- c.memoryUsage
-	ch <- c.goroutines
-	ch <- c.diskReadBytes
-	ch <- c.diskWriteBytes
+	for _, m := range c.stats {
+		ch <- m.desc
+	}
+	for _, m := range c.memStats {
+		ch <- m.desc
+	}
+	ch <- c.functions.calls
+	ch <- c.functions.executionTime
+	ch <- c.functions.memoryDelta
+	ch <- c.functions.goroutineDelta
+	ch <- c.functions.errors
+	ch <- c.functions.panics
 }
 
 // Collect is called by the Prometheus registry when collecting metrics.
 func (c *MonigoCollector) Collect(ch chan<- prometheus.Metric) {
-	stats := core.GetServiceStats(context.Background())
-
-	// CPU Load - use raw float64 values directly, no string parsing
-	ch <- prometheus.MustNewConstMetric(
-		c.cpuUsage,
-		prometheus.GaugeValue,
-		stats.LoadStatistics.SystemCPULoadRaw,
-	)
-
-	// Memory - use raw bytes value directly
-	ch <- prometheus.MustNewConstMetric(
-		c.memoryUsage,
-		prometheus.GaugeValue,
-		stats.MemoryStatistics.MemoryUsedBySystemRaw,
-	)
-
-	// Goroutines
-	ch <- prometheus.MustNewConstMetric(
-		c.goroutines,
-		prometheus.GaugeValue,
-		float64(stats.CoreStatistics.Goroutines),
-	)
-
-	// Disk I/O
-	ch <- prometheus.MustNewConstMetric(
-		c.diskReadBytes,
-		prometheus.CounterValue,
-		float64(stats.DiskIO.ReadBytes),
-	)
-	ch <- prometheus.MustNewConstMetric(
-		c.diskWriteBytes,
-		prometheus.CounterValue,
-		float64(stats.DiskIO.WriteBytes),
-	)
+	stats := core.CachedServiceStats(context.Background())
+	service := common.GetServiceInfo().ServiceName
+	host := timeseries.GetHostLabel().Value
+
+	for _, m := range c.stats {
+		ch <- prometheus.MustNewConstMetric(m.desc, m.valueType, m.value(&stats), service, host)
+	}
+	for _, record := range stats.MemoryStatistics.RawMemStatsRecords {
+		m, ok := c.memStats[record.RecordName]
+		if !ok {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(m.desc, m.valueType, record.RecordValue*m.scale, service, host)
+	}
+	c.collectFunctions(ch, service, host)
+}
+
+// collectFunctions exports the call statistics of every traced function.
+func (c *MonigoCollector) collectFunctions(ch chan<- prometheus.Metric, service, host string) {
+	for name, m := range core.FunctionTraceDetails() {
+		ch <- prometheus.MustNewConstMetric(c.functions.calls, prometheus.CounterValue, float64(m.CallCount), service, host, name)
+
+		buckets := make(map[float64]uint64, len(core.FunctionDurationBuckets))
+		for i, bound := range core.FunctionDurationBuckets {
+			if i < len(m.ExecutionTimeBuckets) {
+				buckets[bound] = m.ExecutionTimeBuckets[i]
+			}
+		}
+		ch <- prometheus.MustNewConstHistogram(c.functions.executionTime, m.CallCount, m.TotalExecutionTime.Seconds(), buckets, service, host, name)
+
+		ch <- prometheus.MustNewConstMetric(c.functions.memoryDelta, prometheus.GaugeValue, float64(m.MemoryUsage), service, host, name)
+		ch <- prometheus.MustNewConstMetric(c.functions.goroutineDelta, prometheus.GaugeValue, float64(m.GoroutineCount), service, host, name)
+		ch <- prometheus.MustNewConstMetric(c.functions.errors, prometheus.CounterValue, float64(m.ErrorCount), service, host, name)
+		ch <- prometheus.MustNewConstMetric(c.functions.panics, prometheus.CounterValue, float64(m.PanicCount), service, host, name)
+	}
 }
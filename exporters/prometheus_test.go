@@ -1,9 +1,16 @@
 package exporters
 
 import (
+	"context"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/iyashjayesh/monigo/common"
+	"github.com/iyashjayesh/monigo/core"
 	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 )
 
 func TestNewMonigoCollector(t *testing.T) {
@@ -21,7 +28,7 @@ func TestNewMonigoCollector(t *testing.T) {
 
 func TestDescribe(t *testing.T) {
 	c := NewMonigoCollector()
-	ch := make(chan *prometheus.Desc, 10)
+	ch := make(chan *prometheus.Desc, 100)
 
 	go func() {
 		c.Describe(ch)
@@ -32,14 +39,15 @@ func TestDescribe(t *testing.T) {
 	for range ch {
 		count++
 	}
-	if count != 5 {
-		t.Errorf("expected 5 descriptors, got %d", count)
+	want := len(c.stats) + len(c.memStats) + 6
+	if count != want || count < 50 {
+		t.Errorf("expected %d descriptors, got %d", want, count)
 	}
 }
 
 func TestCollect(t *testing.T) {
 	c := NewMonigoCollector()
-	ch := make(chan prometheus.Metric, 10)
+	ch := make(chan prometheus.Metric, 100)
 
 	go func() {
 		c.Collect(ch)
@@ -50,7 +58,132 @@ func TestCollect(t *testing.T) {
 	for range ch {
 		count++
 	}
-	if count != 5 {
-		t.Errorf("expected 5 metrics, got %d", count)
+	// Every ServiceStats field and every runtime memstats record is exported,
+	// plus four series per traced function.
+	want := len(c.stats) + len(c.memStats) + 4*len(core.FunctionTraceDetails())
+	if count != want {
+		t.Errorf("expected %d metrics, got %d", want, count)
+	}
+}
+
+func TestCollectLabelsAndTypes(t *testing.T) {
+	common.SetServiceInfo("collector-test", time.Now(), runtime.Version(), 1, "7d")
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(NewMonigoCollector())
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+
+	byName := make(map[string]*dto.MetricFamily, len(families))
+	for _, f := range families {
+		byName[f.GetName()] = f
+	}
+
+	wantTypes := map[string]dto.MetricType{
+		"monigo_cpu_usage_percent":             dto.MetricType_GAUGE,
+		"monigo_memory_usage_bytes":            dto.MetricType_GAUGE,
+		"monigo_goroutines_count":              dto.MetricType_GAUGE,
+		"monigo_disk_read_bytes_total":         dto.MetricType_COUNTER,
+		"monigo_disk_write_bytes_total":        dto.MetricType_COUNTER,
+		"monigo_service_health_percent":        dto.MetricType_GAUGE,
+		"monigo_network_sent_bytes_total":      dto.MetricType_COUNTER,
+		"monigo_memstats_heap_alloc_bytes":     dto.MetricType_GAUGE,
+		"monigo_memstats_gc_completed_total":   dto.MetricType_COUNTER,
+		"monigo_memstats_alloc_bytes_total":    dto.MetricType_COUNTER,
+		"monigo_service_cpu_cores_used":        dto.MetricType_GAUGE,
+		"monigo_system_memory_load_percent":    dto.MetricType_GAUGE,
+		"monigo_memstats_last_gc_time_seconds": dto.MetricType_GAUGE,
+	}
+	for name, typ := range wantTypes {
+		f, ok := byName[name]
+		if !ok {
+			t.Errorf("missing metric %s", name)
+			continue
+		}
+		if f.GetType() != typ {
+			t.Errorf("%s: expected type %v, got %v", name, typ, f.GetType())
+		}
+	}
+
+	f := byName["monigo_goroutines_count"]
+	if f == nil {
+		t.Fatal("missing monigo_goroutines_count")
+	}
+	labels := map[string]string{}
+	for _, l := range f.GetMetric()[0].GetLabel() {
+		labels[l.GetName()] = l.GetValue()
+	}
+	if labels["service"] != "collector-test" || labels["host"] == "" {
+		t.Errorf("expected service and host labels, got %v", labels)
+	}
+
+	// Memstats byte records are stored in KB; the exported value must be in bytes.
+	heap := byName["monigo_memstats_heap_sys_bytes"]
+	if heap == nil || heap.GetMetric()[0].GetGauge().GetValue() < 1024*1024 {
+		t.Errorf("expected heap_sys in bytes, got %v", heap)
+	}
+}
+
+func TestCollectFunctionMetrics(t *testing.T) {
+	fn := func() { time.Sleep(2 * time.Millisecond) }
+	core.TraceFunction(context.Background(), fn)
+	core.TraceFunction(context.Background(), fn)
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(NewMonigoCollector())
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+
+	// findFunction returns the series of the traced closure in a family.
+	findFunction := func(name string) *dto.Metric {
+		for _, f := range families {
+			if f.GetName() != name {
+				continue
+			}
+			for _, m := range f.GetMetric() {
+				for _, l := range m.GetLabel() {
+					if l.GetName() == "function" && strings.Contains(l.GetValue(), "TestCollectFunctionMetrics") {
+						return m
+					}
+				}
+			}
+		}
+		return nil
+	}
+
+	calls := findFunction("monigo_function_calls_total")
+	if calls == nil || calls.GetCounter().GetValue() != 2 {
+		t.Errorf("expected 2 calls, got %v", calls)
+	}
+	hist := findFunction("monigo_function_execution_seconds")
+	if hist == nil {
+		t.Fatal("missing execution time histogram")
+	}
+	h := hist.GetHistogram()
+	if h.GetSampleCount() != 2 || h.GetSampleSum() < 0.004 {
+		t.Errorf("expected 2 observations of at least 2ms, got count=%d sum=%v", h.GetSampleCount(), h.GetSampleSum())
+	}
+	// Calls of at least 2ms never land in the 1ms bucket; every call lands in the 10s one.
+	for _, b := range h.GetBucket() {
+		if b.GetUpperBound() == 0.001 && b.GetCumulativeCount() != 0 {
+			t.Errorf("expected no calls under 1ms, got %d", b.GetCumulativeCount())
+		}
+		if b.GetUpperBound() == 10 && b.GetCumulativeCount() != 2 {
+			t.Errorf("expected both calls under 10s, got %d", b.GetCumulativeCount())
+		}
+	}
+	for _, name := range []string{"monigo_function_memory_delta_bytes", "monigo_function_goroutine_delta"} {
+		if findFunction(name) == nil {
+			t.Errorf("missing %s", name)
+		}
+	}
+	for _, name := range []string{"monigo_function_errors_total", "monigo_function_panics_total"} {
+		if m := findFunction(name); m == nil || m.GetCounter().GetValue() != 0 {
+			t.Errorf("expected %s of 0, got %v", name, m)
+		}
 	}
 }
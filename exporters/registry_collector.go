@@ -0,0 +1,70 @@
+package exporters
+
+import (
+	"sort"
+
+	"github.com/iyashjayesh/monigo/internal/registry"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// RegistryCollector exposes the metrics of an internal registry to Prometheus.
+// Histograms are exported as _bucket/_sum/_count series.
+type RegistryCollector struct {
+	registry *registry.Registry
+}
+
+// NewRegistryCollector returns a collector for r.
+func NewRegistryCollector(r *registry.Registry) *RegistryCollector {
+	return &RegistryCollector{registry: r}
+}
+
+// PrometheusName returns the name a registry metric is exported under.
+func PrometheusName(name string) string {
+	return sanitizeName(name, true)
+}
+
+// Describe sends nothing: the registry's metrics are only known at collection time,
+// which makes this an unchecked collector.
+func (c *RegistryCollector) Describe(chan<- *prometheus.Desc) {}
+
+// Collect is called by the Prometheus registry when collecting metrics.
+func (c *RegistryCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, m := range c.registry.GetAll() {
+		names := make([]string, 0, len(m.Labels))
+		for k := range m.Labels {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		labelNames := make([]string, len(names))
+		values := make([]string, len(names))
+		for i, k := range names {
+			labelNames[i], values[i] = sanitizeName(k, false), m.Labels[k]
+		}
+		desc := prometheus.NewDesc(sanitizeName(m.Name, true), "Recorded by monigo: "+m.Name, labelNames, nil)
+
+		var (
+			metric prometheus.Metric
+			err    error
+		)
+		switch m.Type {
+		case registry.Counter:
+			metric, err = prometheus.NewConstMetric(desc, prometheus.CounterValue, m.Value, values...)
+		case registry.Histogram:
+			if m.Histogram == nil {
+				continue
+			}
+			bounds, counts := m.Histogram.CumulativeBuckets()
+			buckets := make(map[float64]uint64, len(bounds))
+			for i, b := range bounds {
+				buckets[b] = counts[i]
+			}
+			metric, err = prometheus.NewConstHistogram(desc, m.Histogram.Count, m.Histogram.Sum, buckets, values...)
+		default:
+			metric, err = prometheus.NewConstMetric(desc, prometheus.GaugeValue, m.Value, values...)
+		}
+		if err != nil {
+			metric = prometheus.NewInvalidMetric(desc, err)
+		}
+		ch <- metric
+	}
+}
@@ -0,0 +1,50 @@
+package exporters
+
+import (
+	"testing"
+
+	"github.com/iyashjayesh/monigo/internal/registry"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestRegistryCollector(t *testing.T) {
+	r := registry.NewRegistry()
+	r.SetGauge("queue.depth", 3, map[string]string{"queue": "emails"})
+	r.IncrementCounter("orders_total", 2, nil)
+	r.ConfigureHistogram("checkout_seconds", registry.HistogramOpts{Buckets: []float64{0.5, 1}})
+	r.RecordHistogram("checkout_seconds", 0.2, map[string]string{"region": "eu"})
+	r.RecordHistogram("checkout_seconds", 0.7, map[string]string{"region": "eu"})
+	r.RecordHistogram("checkout_seconds", 3, map[string]string{"region": "eu"})
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(NewRegistryCollector(r))
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+	byName := make(map[string]*dto.MetricFamily, len(families))
+	for _, f := range families {
+		byName[f.GetName()] = f
+	}
+
+	if f := byName["queue_depth"]; f == nil || f.GetType() != dto.MetricType_GAUGE || f.GetMetric()[0].GetGauge().GetValue() != 3 {
+		t.Errorf("unexpected gauge %v", f)
+	}
+	if f := byName["orders_total"]; f == nil || f.GetType() != dto.MetricType_COUNTER {
+		t.Errorf("unexpected counter %v", f)
+	}
+
+	f := byName["checkout_seconds"]
+	if f == nil || f.GetType() != dto.MetricType_HISTOGRAM {
+		t.Fatalf("unexpected histogram %v", f)
+	}
+	h := f.GetMetric()[0].GetHistogram()
+	if h.GetSampleCount() != 3 || h.GetSampleSum() != 3.9 {
+		t.Errorf("expected count 3 and sum 3.9, got %d and %v", h.GetSampleCount(), h.GetSampleSum())
+	}
+	buckets := h.GetBucket()
+	if len(buckets) != 2 || buckets[0].GetCumulativeCount() != 1 || buckets[1].GetCumulativeCount() != 2 {
+		t.Errorf("unexpected buckets %v", buckets)
+	}
+}
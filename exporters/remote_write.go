@@ -0,0 +1,485 @@
+package exporters
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iyashjayesh/monigo/internal/logger"
+	"github.com/iyashjayesh/monigo/internal/prompb"
+	"github.com/iyashjayesh/monigo/internal/registry"
+	"github.com/klauspost/compress/snappy"
+)
+
+// pendingFileName is the file in RemoteWriteConfig.QueueDir holding samples left unsent at shutdown.
+const pendingFileName = "remote_write_pending.snappy"
+
+// RemoteWriteConfig holds configuration for the Prometheus remote write exporter.
+type RemoteWriteConfig struct {
+	URL     string            // e.g. "http://mimir:9009/api/v1/push"
+	Headers map[string]string // e.g. Authorization or X-Scope-OrgID
+
+	Timeout       time.Duration // per request; default 10s
+	FlushInterval time.Duration // how often queued samples are sent; default 5s
+	BatchSize     int           // max samples per request; default 500
+	QueueSize     int           // max queued samples, the oldest are dropped beyond it; default 10000
+	MaxRetries    int           // retries of a failed batch before it is requeued; default 3, negative disables
+	MinBackoff    time.Duration // default 100ms, doubled on every retry
+	MaxBackoff    time.Duration // default 5s
+
+	// ExternalLabels are added to every series that doesn't set them itself, e.g.
+	// service and host to tell instances apart.
+	ExternalLabels map[string]string
+
+	// QueueDir, when set, persists samples still queued at Shutdown and resends them
+	// on the next start.
+	QueueDir string
+
+	HTTPClient *http.Client // optional, defaults to a client with Timeout
+}
+
+// RemoteWriteExporter implements the internal exporter.Exporter interface and pushes
+// metrics to a Prometheus remote write endpoint (Prometheus, Mimir, Cortex, VictoriaMetrics).
+// Export only enqueues; a background loop sends snappy-compressed protobuf batches.
+type RemoteWriteExporter struct {
+	cfg    RemoteWriteConfig
+	client *http.Client
+
+	mu      sync.Mutex
+	queue   []queuedSample
+	dropped uint64
+	closed  bool
+
+	flushCh chan struct{}
+	stopCh  chan struct{}
+	wg      sync.WaitGroup
+}
+
+// queuedSample is one sample of a series, with labels already in remote write form.
+type queuedSample struct {
+	labels []prompb.Label // sorted by name, including __name__
+	sample prompb.Sample
+}
+
+// recoverableError marks a failure worth retrying (network errors, 5xx and 429 responses).
+type recoverableError struct {
+	err error
+}
+
+func (e recoverableError) Error() string { return e.err.Error() }
+func (e recoverableError) Unwrap() error { return e.err }
+
+// NewRemoteWriteExporter creates the exporter and starts its send loop.
+func NewRemoteWriteExporter(cfg RemoteWriteConfig) (*RemoteWriteExporter, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("[MoniGo] remote write URL is required")
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = 5 * time.Second
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 500
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 10000
+	}
+	if cfg.MaxRetries < 0 {
+		cfg.MaxRetries = 0
+	} else if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = 3
+	}
+	if cfg.MinBackoff <= 0 {
+		cfg.MinBackoff = 100 * time.Millisecond
+	}
+	if cfg.MaxBackoff < cfg.MinBackoff {
+		cfg.MaxBackoff = 5 * time.Second
+	}
+
+	client := cfg.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: cfg.Timeout}
+	}
+
+	r := &RemoteWriteExporter{
+		cfg:     cfg,
+		client:  client,
+		flushCh: make(chan struct{}, 1),
+		stopCh:  make(chan struct{}),
+	}
+	if cfg.QueueDir != "" {
+		if err := r.loadPending(); err != nil {
+			logger.Log.Warn("failed to load pending remote write samples", "error", err)
+		}
+	}
+
+	r.wg.Add(1)
+	go r.run()
+	return r, nil
+}
+
+// Export enqueues metrics for sending. When the queue is full the oldest samples are dropped.
+func (r *RemoteWriteExporter) Export(_ context.Context, metrics []*registry.MetricValue) error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return fmt.Errorf("[MoniGo] remote write exporter is shut down")
+	}
+	for _, m := range metrics {
+		ts := m.Timestamp
+		if ts.IsZero() {
+			ts = time.Now()
+		}
+		if len(r.cfg.ExternalLabels) > 0 {
+			cp := *m
+			cp.Labels = withExternalLabels(m.Labels, r.cfg.ExternalLabels)
+			m = &cp
+		}
+		if m.Type == registry.Histogram && m.Histogram != nil {
+			r.queue = append(r.queue, histogramSamples(m, ts.UnixMilli())...)
+			continue
+		}
+		r.queue = append(r.queue, queuedSample{
+			labels: toRemoteLabels(m.Name, m.Labels),
+			sample: prompb.Sample{Value: m.Value, Timestamp: ts.UnixMilli()},
+		})
+	}
+	r.trimLocked()
+	full := len(r.queue) >= r.cfg.BatchSize
+	r.mu.Unlock()
+
+	if full {
+		select {
+		case r.flushCh <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Name returns the exporter name.
+func (r *RemoteWriteExporter) Name() string {
+	return "prometheus-remote-write"
+}
+
+// Pending returns the number of queued samples.
+func (r *RemoteWriteExporter) Pending() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.queue)
+}
+
+// Dropped returns how many samples were discarded because the queue was full
+// or the endpoint rejected them.
+func (r *RemoteWriteExporter) Dropped() uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.dropped
+}
+
+// Flush sends every queued sample, batch by batch. A batch that still fails after all
+// retries is put back at the front of the queue and its error returned.
+func (r *RemoteWriteExporter) Flush(ctx context.Context) error {
+	for {
+		batch := r.takeBatch()
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := r.sendWithRetry(ctx, batch); err != nil {
+			var rec recoverableError
+			if errors.As(err, &rec) || ctx.Err() != nil {
+				r.requeue(batch)
+				return err
+			}
+			// The endpoint rejected the data; resending it would fail again.
+			r.mu.Lock()
+			r.dropped += uint64(len(batch))
+			r.mu.Unlock()
+			logger.Log.Error("remote write batch rejected, dropping samples", "samples", len(batch), "error", err)
+		}
+	}
+}
+
+// Shutdown stops the send loop, makes a final flush and, when QueueDir is set,
+// persists whatever could not be sent.
+func (r *RemoteWriteExporter) Shutdown(ctx context.Context) error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+	r.closed = true
+	r.mu.Unlock()
+
+	close(r.stopCh)
+	r.wg.Wait()
+
+	err := r.Flush(ctx)
+	if r.cfg.QueueDir != "" {
+		if perr := r.savePending(); perr != nil {
+			err = errors.Join(err, perr)
+		}
+	}
+	return err
+}
+
+func (r *RemoteWriteExporter) run() {
+	defer r.wg.Done()
+	ticker := time.NewTicker(r.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-r.stopCh
+		cancel()
+	}()
+
+	for {
+		select {
+		case <-r.stopCh:
+			return
+		case <-ticker.C:
+		case <-r.flushCh:
+		}
+		if err := r.Flush(ctx); err != nil && ctx.Err() == nil {
+			logger.Log.Warn("remote write failed, samples requeued", "pending", r.Pending(), "error", err)
+		}
+	}
+}
+
+// trimLocked drops the oldest samples beyond QueueSize. Callers must hold r.mu.
+func (r *RemoteWriteExporter) trimLocked() {
+	if over := len(r.queue) - r.cfg.QueueSize; over > 0 {
+		r.queue = append(r.queue[:0:0], r.queue[over:]...)
+		r.dropped += uint64(over)
+	}
+}
+
+func (r *RemoteWriteExporter) takeBatch() []queuedSample {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := min(len(r.queue), r.cfg.BatchSize)
+	batch := r.queue[:n:n]
+	r.queue = r.queue[n:]
+	return batch
+}
+
+func (r *RemoteWriteExporter) requeue(batch []queuedSample) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.queue = append(batch[:len(batch):len(batch)], r.queue...)
+	r.trimLocked()
+}
+
+func (r *RemoteWriteExporter) sendWithRetry(ctx context.Context, batch []queuedSample) error {
+	body := snappy.Encode(nil, buildWriteRequest(batch).Marshal())
+	backoff := r.cfg.MinBackoff
+	var err error
+	for attempt := 0; ; attempt++ {
+		if err = r.send(ctx, body); err == nil {
+			return nil
+		}
+		var rec recoverableError
+		if !errors.As(err, &rec) || attempt >= r.cfg.MaxRetries {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return recoverableError{ctx.Err()}
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, r.cfg.MaxBackoff)
+	}
+}
+
+func (r *RemoteWriteExporter) send(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("User-Agent", "monigo")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	for k, v := range r.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return recoverableError{err}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 == 2 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err = fmt.Errorf("remote write returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+		return recoverableError{err}
+	}
+	return err
+}
+
+func (r *RemoteWriteExporter) savePending() error {
+	r.mu.Lock()
+	pending := r.queue
+	r.queue = nil
+	r.mu.Unlock()
+
+	path := filepath.Join(r.cfg.QueueDir, pendingFileName)
+	if len(pending) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	if err := os.MkdirAll(r.cfg.QueueDir, 0o755); err != nil {
+		return err
+	}
+	data := snappy.Encode(nil, buildWriteRequest(pending).Marshal())
+	return os.WriteFile(path, data, 0o644)
+}
+
+func (r *RemoteWriteExporter) loadPending() error {
+	path := filepath.Join(r.cfg.QueueDir, pendingFileName)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer os.Remove(path)
+
+	raw, err := snappy.Decode(nil, data)
+	if err != nil {
+		return err
+	}
+	var wr prompb.WriteRequest
+	if err := wr.Unmarshal(raw); err != nil {
+		return err
+	}
+	for _, ts := range wr.Timeseries {
+		for _, s := range ts.Samples {
+			r.queue = append(r.queue, queuedSample{labels: ts.Labels, sample: s})
+		}
+	}
+	r.trimLocked()
+	return nil
+}
+
+// buildWriteRequest groups a batch by series, keeping samples in timestamp order.
+func buildWriteRequest(batch []queuedSample) *prompb.WriteRequest {
+	index := make(map[string]int)
+	wr := &prompb.WriteRequest{}
+	for _, q := range batch {
+		key := labelsKey(q.labels)
+		i, ok := index[key]
+		if !ok {
+			i = len(wr.Timeseries)
+			index[key] = i
+			wr.Timeseries = append(wr.Timeseries, prompb.TimeSeries{Labels: q.labels})
+		}
+		wr.Timeseries[i].Samples = append(wr.Timeseries[i].Samples, q.sample)
+	}
+	for i := range wr.Timeseries {
+		samples := wr.Timeseries[i].Samples
+		sort.SliceStable(samples, func(a, b int) bool { return samples[a].Timestamp < samples[b].Timestamp })
+	}
+	return wr
+}
+
+func labelsKey(labels []prompb.Label) string {
+	var b strings.Builder
+	for _, l := range labels {
+		b.WriteString(l.Name)
+		b.WriteByte(0xff)
+		b.WriteString(l.Value)
+		b.WriteByte(0xfe)
+	}
+	return b.String()
+}
+
+// histogramSamples expands a histogram into the _bucket (one per "le" bound, plus +Inf),
+// _sum and _count series of the Prometheus exposition format.
+func histogramSamples(m *registry.MetricValue, ts int64) []queuedSample {
+	bounds, counts := m.Histogram.CumulativeBuckets()
+	out := make([]queuedSample, 0, len(bounds)+3)
+	bucket := func(le string, count uint64) {
+		labels := make(map[string]string, len(m.Labels)+1)
+		for k, v := range m.Labels {
+			labels[k] = v
+		}
+		labels["le"] = le
+		out = append(out, queuedSample{
+			labels: toRemoteLabels(m.Name+"_bucket", labels),
+			sample: prompb.Sample{Value: float64(count), Timestamp: ts},
+		})
+	}
+	for i, b := range bounds {
+		bucket(strconv.FormatFloat(b, 'f', -1, 64), counts[i])
+	}
+	bucket("+Inf", m.Histogram.Count)
+
+	out = append(out,
+		queuedSample{labels: toRemoteLabels(m.Name+"_sum", m.Labels), sample: prompb.Sample{Value: m.Histogram.Sum, Timestamp: ts}},
+		queuedSample{labels: toRemoteLabels(m.Name+"_count", m.Labels), sample: prompb.Sample{Value: float64(m.Histogram.Count), Timestamp: ts}},
+	)
+	return out
+}
+
+// toRemoteLabels builds the sorted label set of a metric, sanitizing names to the
+// Prometheus charset.
+func toRemoteLabels(name string, labels map[string]string) []prompb.Label {
+	out := make([]prompb.Label, 0, len(labels)+1)
+	out = append(out, prompb.Label{Name: "__name__", Value: sanitizeName(name, true)})
+	for k, v := range labels {
+		if k == "__name__" {
+			continue
+		}
+		out = append(out, prompb.Label{Name: sanitizeName(k, false), Value: v})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// withExternalLabels returns labels plus the external labels it doesn't set.
+func withExternalLabels(labels, external map[string]string) map[string]string {
+	out := make(map[string]string, len(labels)+len(external))
+	for k, v := range external {
+		out[k] = v
+	}
+	for k, v := range labels {
+		out[k] = v
+	}
+	return out
+}
+
+// sanitizeName replaces characters invalid in Prometheus metric (or label) names with '_'.
+func sanitizeName(s string, metric bool) string {
+	b := []byte(s)
+	for i, c := range b {
+		valid := c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') ||
+			(metric && c == ':') || (i > 0 && c >= '0' && c <= '9')
+		if !valid {
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
@@ -0,0 +1,300 @@
+package exporters
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/iyashjayesh/monigo/internal/prompb"
+	"github.com/iyashjayesh/monigo/internal/registry"
+	"github.com/klauspost/compress/snappy"
+)
+
+// remoteWriteServer is a stand-in remote write endpoint that decodes every request and
+// answers with the status codes queued in statuses (204 once they run out).
+type remoteWriteServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	statuses []int
+	requests []prompb.WriteRequest
+	headers  []http.Header
+	attempts atomic.Int32
+}
+
+func newRemoteWriteServer(t *testing.T, statuses ...int) *remoteWriteServer {
+	s := &remoteWriteServer{statuses: statuses}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.attempts.Add(1)
+		s.mu.Lock()
+		status := http.StatusNoContent
+		if len(s.statuses) > 0 {
+			status, s.statuses = s.statuses[0], s.statuses[1:]
+		}
+		s.mu.Unlock()
+		if status != http.StatusNoContent {
+			w.WriteHeader(status)
+			return
+		}
+
+		compressed, _ := io.ReadAll(r.Body)
+		raw, err := snappy.Decode(nil, compressed)
+		if err != nil {
+			t.Errorf("request is not snappy-compressed: %v", err)
+		}
+		var wr prompb.WriteRequest
+		if err := wr.Unmarshal(raw); err != nil {
+			t.Errorf("failed to decode write request: %v", err)
+		}
+		s.mu.Lock()
+		s.requests = append(s.requests, wr)
+		s.headers = append(s.headers, r.Header.Clone())
+		s.mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func newTestRemoteWriteExporter(t *testing.T, cfg RemoteWriteConfig) *RemoteWriteExporter {
+	t.Helper()
+	cfg.FlushInterval = time.Hour // tests flush explicitly
+	if cfg.MinBackoff == 0 {
+		cfg.MinBackoff = time.Millisecond
+		cfg.MaxBackoff = 2 * time.Millisecond
+	}
+	e, err := NewRemoteWriteExporter(cfg)
+	if err != nil {
+		t.Fatalf("NewRemoteWriteExporter() error = %v", err)
+	}
+	t.Cleanup(func() { e.Shutdown(context.Background()) })
+	return e
+}
+
+func gauge(name string, value float64, ts time.Time) *registry.MetricValue {
+	return &registry.MetricValue{Name: name, Value: value, Labels: map[string]string{"service": "api"}, Timestamp: ts, Type: registry.Gauge}
+}
+
+func TestRemoteWriteExporterSendsBatches(t *testing.T) {
+	srv := newRemoteWriteServer(t)
+	e := newTestRemoteWriteExporter(t, RemoteWriteConfig{
+		URL:       srv.URL,
+		Headers:   map[string]string{"X-Scope-OrgID": "tenant-1"},
+		BatchSize: 2,
+	})
+
+	ts := time.UnixMilli(1700000000123)
+	err := e.Export(context.Background(), []*registry.MetricValue{
+		gauge("http.requests", 1, ts),
+		gauge("http.requests", 2, ts.Add(time.Second)),
+		gauge("cpu_load", 3, ts),
+	})
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if err := e.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if len(srv.requests) != 2 {
+		t.Fatalf("expected 2 batches of at most 2 samples, got %d", len(srv.requests))
+	}
+	first := srv.requests[0]
+	if len(first.Timeseries) != 1 || len(first.Timeseries[0].Samples) != 2 {
+		t.Fatalf("expected both http.requests samples in one series, got %+v", first)
+	}
+	labels := first.Timeseries[0].Labels
+	if labels[0].Name != "__name__" || labels[0].Value != "http_requests" || labels[1].Name != "service" {
+		t.Errorf("unexpected labels %v", labels)
+	}
+	if first.Timeseries[0].Samples[0].Timestamp != 1700000000123 {
+		t.Errorf("expected millisecond timestamp, got %d", first.Timeseries[0].Samples[0].Timestamp)
+	}
+
+	h := srv.headers[0]
+	if h.Get("Content-Encoding") != "snappy" || h.Get("Content-Type") != "application/x-protobuf" {
+		t.Errorf("unexpected content headers %v", h)
+	}
+	if h.Get("X-Prometheus-Remote-Write-Version") == "" || h.Get("X-Scope-OrgID") != "tenant-1" {
+		t.Errorf("missing protocol or custom headers %v", h)
+	}
+	if e.Pending() != 0 {
+		t.Errorf("expected empty queue, got %d", e.Pending())
+	}
+}
+
+func TestRemoteWriteExporterRetries(t *testing.T) {
+	srv := newRemoteWriteServer(t, http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	e := newTestRemoteWriteExporter(t, RemoteWriteConfig{URL: srv.URL})
+
+	e.Export(context.Background(), []*registry.MetricValue{gauge("up", 1, time.Now())})
+	if err := e.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if got := srv.attempts.Load(); got != 3 {
+		t.Errorf("expected 3 attempts, got %d", got)
+	}
+}
+
+func TestRemoteWriteExporterRequeuesAfterRetries(t *testing.T) {
+	srv := newRemoteWriteServer(t, 500, 500, 500)
+	e := newTestRemoteWriteExporter(t, RemoteWriteConfig{URL: srv.URL, MaxRetries: 1})
+
+	e.Export(context.Background(), []*registry.MetricValue{gauge("up", 1, time.Now())})
+	if err := e.Flush(context.Background()); err == nil {
+		t.Fatal("expected error after exhausting retries")
+	}
+	if got := srv.attempts.Load(); got != 2 {
+		t.Errorf("expected 2 attempts, got %d", got)
+	}
+	if e.Pending() != 1 {
+		t.Errorf("expected the sample to be requeued, got %d pending", e.Pending())
+	}
+}
+
+func TestRemoteWriteExporterDropsRejectedBatch(t *testing.T) {
+	srv := newRemoteWriteServer(t, http.StatusBadRequest)
+	e := newTestRemoteWriteExporter(t, RemoteWriteConfig{URL: srv.URL})
+
+	e.Export(context.Background(), []*registry.MetricValue{gauge("up", 1, time.Now())})
+	if err := e.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if got := srv.attempts.Load(); got != 1 {
+		t.Errorf("expected no retry for 4xx, got %d attempts", got)
+	}
+	if e.Dropped() != 1 || e.Pending() != 0 {
+		t.Errorf("expected the sample to be dropped, dropped=%d pending=%d", e.Dropped(), e.Pending())
+	}
+}
+
+func TestRemoteWriteExporterBoundedQueue(t *testing.T) {
+	srv := newRemoteWriteServer(t)
+	e := newTestRemoteWriteExporter(t, RemoteWriteConfig{URL: srv.URL, QueueSize: 3, BatchSize: 10})
+
+	now := time.Now()
+	for i := 0; i < 5; i++ {
+		e.Export(context.Background(), []*registry.MetricValue{gauge("seq", float64(i), now.Add(time.Duration(i)*time.Second))})
+	}
+	if e.Pending() != 3 || e.Dropped() != 2 {
+		t.Fatalf("expected 3 pending and 2 dropped, got %d and %d", e.Pending(), e.Dropped())
+	}
+	if err := e.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	samples := srv.requests[0].Timeseries[0].Samples
+	if len(samples) != 3 || samples[0].Value != 2 {
+		t.Errorf("expected the oldest samples to be dropped, got %v", samples)
+	}
+}
+
+func TestRemoteWriteExporterPersistsQueue(t *testing.T) {
+	dir := t.TempDir()
+	down := newRemoteWriteServer(t, 500)
+	e := newTestRemoteWriteExporter(t, RemoteWriteConfig{URL: down.URL, QueueDir: dir, MaxRetries: -1})
+
+	e.Export(context.Background(), []*registry.MetricValue{gauge("up", 1, time.Now()), gauge("up", 2, time.Now())})
+	if err := e.Shutdown(context.Background()); err == nil {
+		t.Fatal("expected Shutdown to report the failed flush")
+	}
+	if _, err := os.Stat(filepath.Join(dir, pendingFileName)); err != nil {
+		t.Fatalf("expected pending samples on disk: %v", err)
+	}
+	if err := e.Export(context.Background(), nil); err == nil {
+		t.Error("expected Export to fail after Shutdown")
+	}
+
+	up := newRemoteWriteServer(t)
+	e2 := newTestRemoteWriteExporter(t, RemoteWriteConfig{URL: up.URL, QueueDir: dir})
+	if e2.Pending() != 2 {
+		t.Fatalf("expected 2 samples restored from disk, got %d", e2.Pending())
+	}
+	if err := e2.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if err := e2.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, pendingFileName)); !os.IsNotExist(err) {
+		t.Errorf("expected pending file to be removed, got %v", err)
+	}
+}
+
+func TestRemoteWriteExporterHistogram(t *testing.T) {
+	srv := newRemoteWriteServer(t)
+	e := newTestRemoteWriteExporter(t, RemoteWriteConfig{URL: srv.URL})
+
+	r := registry.NewRegistry()
+	r.ConfigureHistogram("latency", registry.HistogramOpts{Buckets: []float64{0.1, 1}})
+	r.RecordHistogram("latency", 0.05, map[string]string{"service": "api"})
+	r.RecordHistogram("latency", 2, map[string]string{"service": "api"})
+	e.Export(context.Background(), r.GetAll())
+	if err := e.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	got := map[string]float64{}
+	for _, req := range srv.requests {
+		for _, ts := range req.Timeseries {
+			var name, le string
+			for _, l := range ts.Labels {
+				switch l.Name {
+				case "__name__":
+					name = l.Value
+				case "le":
+					le = l.Value
+				}
+			}
+			got[name+le] = ts.Samples[0].Value
+		}
+	}
+	want := map[string]float64{
+		"latency_bucket0.1": 1, "latency_bucket1": 1, "latency_bucket+Inf": 2,
+		"latency_sum": 2.05, "latency_count": 2,
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s: expected %v, got %v", k, v, got[k])
+		}
+	}
+}
+
+func TestRemoteWriteExporterExternalLabels(t *testing.T) {
+	srv := newRemoteWriteServer(t)
+	e := newTestRemoteWriteExporter(t, RemoteWriteConfig{
+		URL:            srv.URL,
+		ExternalLabels: map[string]string{"service": "default", "host": "node-1"},
+	})
+
+	e.Export(context.Background(), []*registry.MetricValue{gauge("cpu_load", 1, time.Now())})
+	if err := e.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if len(srv.requests) != 1 || len(srv.requests[0].Timeseries) != 1 {
+		t.Fatalf("expected one series, got %+v", srv.requests)
+	}
+	got := map[string]string{}
+	for _, l := range srv.requests[0].Timeseries[0].Labels {
+		got[l.Name] = l.Value
+	}
+	// The series' own service label wins over the external one.
+	if got["host"] != "node-1" || got["service"] != "api" {
+		t.Errorf("unexpected labels %v", got)
+	}
+}
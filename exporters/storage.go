@@ -0,0 +1,67 @@
+package exporters
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/iyashjayesh/monigo/internal/registry"
+	"github.com/iyashjayesh/monigo/timeseries"
+)
+
+// StorageExporter persists registry metrics into monigo's time-series storage so they
+// can be queried and charted alongside the system metrics. Series carry the host label
+// plus their own labels; histograms are stored as <name>_count and <name>_sum.
+type StorageExporter struct{}
+
+// NewStorageExporter returns an exporter writing to the storage instance.
+func NewStorageExporter() *StorageExporter {
+	return &StorageExporter{}
+}
+
+// Export inserts the current value of every series, stamped with the export time so each
+// pipeline tick adds one point per series as the system metric sync does.
+func (s *StorageExporter) Export(_ context.Context, metrics []*registry.MetricValue) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+	sto, err := timeseries.GetStorageInstance()
+	if err != nil {
+		return err
+	}
+
+	host := timeseries.GetHostLabel()
+	ts := time.Now().Unix()
+	rows := make([]timeseries.Row, 0, len(metrics))
+	for _, m := range metrics {
+		labels := StorageLabels(m.Labels, host)
+		point := func(name string, value float64) timeseries.Row {
+			return timeseries.Row{Metric: name, Labels: labels, DataPoint: timeseries.DataPoint{Timestamp: ts, Value: value}}
+		}
+		if m.Type == registry.Histogram && m.Histogram != nil {
+			rows = append(rows, point(m.Name+"_count", float64(m.Histogram.Count)), point(m.Name+"_sum", m.Histogram.Sum))
+			continue
+		}
+		rows = append(rows, point(m.Name, m.Value))
+	}
+	return sto.InsertRows(rows)
+}
+
+// Name returns the exporter name.
+func (s *StorageExporter) Name() string {
+	return "storage"
+}
+
+// StorageLabels returns the sorted label set a metric is stored under: its own labels
+// plus the host label, unless the metric sets "host" itself.
+func StorageLabels(labels map[string]string, host timeseries.Label) []timeseries.Label {
+	out := make([]timeseries.Label, 0, len(labels)+1)
+	if _, ok := labels[host.Name]; !ok {
+		out = append(out, host)
+	}
+	for k, v := range labels {
+		out = append(out, timeseries.Label{Name: k, Value: v})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
@@ -0,0 +1,58 @@
+package exporters
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/iyashjayesh/monigo/internal/registry"
+	"github.com/iyashjayesh/monigo/timeseries"
+)
+
+func TestStorageExporter(t *testing.T) {
+	timeseries.SetStorageType("memory")
+	r := registry.NewRegistry()
+	r.SetGauge("storage_test_depth", 7, map[string]string{"queue": "emails"})
+	r.RecordHistogram("storage_test_seconds", 0.5, nil)
+	r.RecordHistogram("storage_test_seconds", 1.5, nil)
+
+	if err := NewStorageExporter().Export(context.Background(), r.GetAll()); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	host := timeseries.GetHostLabel()
+	now := time.Now().Unix()
+	for _, tc := range []struct {
+		metric string
+		labels map[string]string
+		want   float64
+	}{
+		{"storage_test_depth", map[string]string{"queue": "emails"}, 7},
+		{"storage_test_seconds_count", nil, 2},
+		{"storage_test_seconds_sum", nil, 2},
+	} {
+		points, err := timeseries.GetDataPoints(tc.metric, StorageLabels(tc.labels, host), now-60, now+60)
+		if err != nil {
+			t.Fatalf("GetDataPoints(%s) error = %v", tc.metric, err)
+		}
+		if len(points) != 1 || points[0].Value != tc.want {
+			t.Errorf("%s: expected one point of %v, got %+v", tc.metric, tc.want, points)
+		}
+	}
+}
+
+func TestStorageLabels(t *testing.T) {
+	host := timeseries.Label{Name: "host", Value: "a"}
+	got := StorageLabels(map[string]string{"region": "eu", "app": "shop"}, host)
+	want := []timeseries.Label{{Name: "app", Value: "shop"}, {Name: "host", Value: "a"}, {Name: "region", Value: "eu"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("StorageLabels() = %v, want %v", got, want)
+	}
+
+	got = StorageLabels(map[string]string{"host": "b"}, host)
+	want = []timeseries.Label{{Name: "host", Value: "b"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("StorageLabels() with own host = %v, want %v", got, want)
+	}
+}
@@ -53,6 +53,16 @@ func (p *Pipeline) Start(ctx context.Context) {
 	}()
 }
 
+// Flush exports the current metrics once, outside the ticker. Call it after Stop to
+// send what was recorded since the last tick.
+func (p *Pipeline) Flush(ctx context.Context) error {
+	metrics := p.registry.GetAll()
+	if len(metrics) == 0 {
+		return nil
+	}
+	return p.exporter.Export(ctx, metrics)
+}
+
 // Stop gracefully stops the pipeline. Safe to call multiple times.
 func (p *Pipeline) Stop() {
 	p.stopOnce.Do(func() {
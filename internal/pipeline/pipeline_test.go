@@ -103,3 +103,20 @@ func TestPipelineDoubleStop(t *testing.T) {
 	// Should not panic.
 	p.Stop()
 }
+
+func TestPipelineFlush(t *testing.T) {
+	r := registry.NewRegistry()
+	exp := &mockExporter{}
+	p := NewPipeline(r, exp, time.Hour)
+
+	p.Start(context.Background())
+	r.SetGauge("test_metric", 1, nil)
+	p.Stop()
+	if err := p.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	if exp.callCount.Load() != 1 {
+		t.Errorf("expected 1 export call after flush, got %d", exp.callCount.Load())
+	}
+}
@@ -0,0 +1,351 @@
+// Package prompb encodes and decodes the subset of the Prometheus remote read/write
+// protobuf messages monigo needs. Messages are hand-coded with protowire so the full
+// Prometheus module is not required; field numbers follow prometheus/prompb.
+package prompb
+
+import (
+	"fmt"
+	"math"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Label is a name/value pair of a series.
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Sample is a value at a timestamp in milliseconds.
+type Sample struct {
+	Value     float64
+	Timestamp int64
+}
+
+// TimeSeries is a labelled series of samples.
+type TimeSeries struct {
+	Labels  []Label
+	Samples []Sample
+}
+
+// MatcherType is the comparison of a LabelMatcher.
+type MatcherType int32
+
+// Matcher types in the order of the remote read LabelMatcher.Type enum.
+const (
+	MatchEqual MatcherType = iota
+	MatchNotEqual
+	MatchRegexp
+	MatchNotRegexp
+)
+
+// LabelMatcher selects series by label in a remote read query.
+type LabelMatcher struct {
+	Type  MatcherType
+	Name  string
+	Value string
+}
+
+// Query is a single remote read query.
+type Query struct {
+	StartTimestampMs int64
+	EndTimestampMs   int64
+	Matchers         []LabelMatcher
+}
+
+// ResponseType values of ReadRequest.AcceptedResponseTypes.
+const (
+	ResponseTypeSamples           int32 = 0
+	ResponseTypeStreamedXORChunks int32 = 1
+)
+
+// ReadRequest is the body of a remote read request.
+type ReadRequest struct {
+	Queries               []Query
+	AcceptedResponseTypes []int32
+}
+
+// QueryResult holds the series answering one Query.
+type QueryResult struct {
+	Timeseries []TimeSeries
+}
+
+// ReadResponse is the body of a sampled remote read response, one result per query.
+type ReadResponse struct {
+	Results []QueryResult
+}
+
+// WriteRequest is the body of a remote write request.
+type WriteRequest struct {
+	Timeseries []TimeSeries
+}
+
+// Marshal encodes the request.
+func (r *ReadRequest) Marshal() []byte {
+	var b []byte
+	for _, q := range r.Queries {
+		b = appendMessage(b, 1, q.marshal())
+	}
+	if len(r.AcceptedResponseTypes) > 0 {
+		var packed []byte
+		for _, t := range r.AcceptedResponseTypes {
+			packed = protowire.AppendVarint(packed, uint64(t))
+		}
+		b = appendMessage(b, 2, packed)
+	}
+	return b
+}
+
+// Unmarshal decodes a request.
+func (r *ReadRequest) Unmarshal(b []byte) error {
+	*r = ReadRequest{}
+	return walk(b, func(num protowire.Number, typ protowire.Type, v []byte, x uint64) error {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			var q Query
+			if err := q.unmarshal(v); err != nil {
+				return err
+			}
+			r.Queries = append(r.Queries, q)
+		case num == 2 && typ == protowire.VarintType:
+			r.AcceptedResponseTypes = append(r.AcceptedResponseTypes, int32(x))
+		case num == 2 && typ == protowire.BytesType:
+			for len(v) > 0 {
+				t, n := protowire.ConsumeVarint(v)
+				if n < 0 {
+					return protowire.ParseError(n)
+				}
+				r.AcceptedResponseTypes = append(r.AcceptedResponseTypes, int32(t))
+				v = v[n:]
+			}
+		}
+		return nil
+	})
+}
+
+// Marshal encodes the response.
+func (r *ReadResponse) Marshal() []byte {
+	var b []byte
+	for _, res := range r.Results {
+		var rb []byte
+		for _, ts := range res.Timeseries {
+			rb = appendMessage(rb, 1, ts.marshal())
+		}
+		b = appendMessage(b, 1, rb)
+	}
+	return b
+}
+
+// Unmarshal decodes a response.
+func (r *ReadResponse) Unmarshal(b []byte) error {
+	*r = ReadResponse{}
+	return walk(b, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+		if num != 1 || typ != protowire.BytesType {
+			return nil
+		}
+		var res QueryResult
+		err := walk(v, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+			if num != 1 || typ != protowire.BytesType {
+				return nil
+			}
+			var ts TimeSeries
+			if err := ts.unmarshal(v); err != nil {
+				return err
+			}
+			res.Timeseries = append(res.Timeseries, ts)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		r.Results = append(r.Results, res)
+		return nil
+	})
+}
+
+// Marshal encodes the request.
+func (w *WriteRequest) Marshal() []byte {
+	var b []byte
+	for _, ts := range w.Timeseries {
+		b = appendMessage(b, 1, ts.marshal())
+	}
+	return b
+}
+
+// Unmarshal decodes a request.
+func (w *WriteRequest) Unmarshal(b []byte) error {
+	*w = WriteRequest{}
+	return walk(b, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+		if num != 1 || typ != protowire.BytesType {
+			return nil
+		}
+		var ts TimeSeries
+		if err := ts.unmarshal(v); err != nil {
+			return err
+		}
+		w.Timeseries = append(w.Timeseries, ts)
+		return nil
+	})
+}
+
+func (q *Query) marshal() []byte {
+	var b []byte
+	b = appendVarint(b, 1, uint64(q.StartTimestampMs))
+	b = appendVarint(b, 2, uint64(q.EndTimestampMs))
+	for _, m := range q.Matchers {
+		var mb []byte
+		mb = appendVarint(mb, 1, uint64(m.Type))
+		mb = appendString(mb, 2, m.Name)
+		mb = appendString(mb, 3, m.Value)
+		b = appendMessage(b, 3, mb)
+	}
+	return b
+}
+
+func (q *Query) unmarshal(b []byte) error {
+	return walk(b, func(num protowire.Number, typ protowire.Type, v []byte, x uint64) error {
+		switch {
+		case num == 1 && typ == protowire.VarintType:
+			q.StartTimestampMs = int64(x)
+		case num == 2 && typ == protowire.VarintType:
+			q.EndTimestampMs = int64(x)
+		case num == 3 && typ == protowire.BytesType:
+			var m LabelMatcher
+			err := walk(v, func(num protowire.Number, typ protowire.Type, v []byte, x uint64) error {
+				switch {
+				case num == 1 && typ == protowire.VarintType:
+					m.Type = MatcherType(x)
+				case num == 2 && typ == protowire.BytesType:
+					m.Name = string(v)
+				case num == 3 && typ == protowire.BytesType:
+					m.Value = string(v)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			q.Matchers = append(q.Matchers, m)
+		}
+		return nil
+	})
+}
+
+func (ts *TimeSeries) marshal() []byte {
+	var b []byte
+	for _, l := range ts.Labels {
+		var lb []byte
+		lb = appendString(lb, 1, l.Name)
+		lb = appendString(lb, 2, l.Value)
+		b = appendMessage(b, 1, lb)
+	}
+	for _, s := range ts.Samples {
+		var sb []byte
+		sb = protowire.AppendTag(sb, 1, protowire.Fixed64Type)
+		sb = protowire.AppendFixed64(sb, math.Float64bits(s.Value))
+		sb = appendVarint(sb, 2, uint64(s.Timestamp))
+		b = appendMessage(b, 2, sb)
+	}
+	return b
+}
+
+func (ts *TimeSeries) unmarshal(b []byte) error {
+	return walk(b, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+		switch num {
+		case 1:
+			var l Label
+			err := walk(v, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+				switch {
+				case num == 1 && typ == protowire.BytesType:
+					l.Name = string(v)
+				case num == 2 && typ == protowire.BytesType:
+					l.Value = string(v)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			ts.Labels = append(ts.Labels, l)
+		case 2:
+			var s Sample
+			err := walk(v, func(num protowire.Number, typ protowire.Type, _ []byte, x uint64) error {
+				switch {
+				case num == 1 && typ == protowire.Fixed64Type:
+					s.Value = math.Float64frombits(x)
+				case num == 2 && typ == protowire.VarintType:
+					s.Timestamp = int64(x)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			ts.Samples = append(ts.Samples, s)
+		}
+		return nil
+	})
+}
+
+// walk calls fn for every field of a message. Varint and fixed values are passed as x,
+// length-delimited values as v; groups are skipped.
+func walk(b []byte, fn func(num protowire.Number, typ protowire.Type, v []byte, x uint64) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return fmt.Errorf("prompb: %w", protowire.ParseError(n))
+		}
+		b = b[n:]
+
+		var (
+			v []byte
+			x uint64
+		)
+		switch typ {
+		case protowire.VarintType:
+			x, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed64Type:
+			x, n = protowire.ConsumeFixed64(b)
+		case protowire.Fixed32Type:
+			var x32 uint32
+			x32, n = protowire.ConsumeFixed32(b)
+			x = uint64(x32)
+		case protowire.BytesType:
+			v, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return fmt.Errorf("prompb: field %d: %w", num, protowire.ParseError(n))
+		}
+		b = b[n:]
+		if err := fn(num, typ, v, x); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func appendVarint(b []byte, num protowire.Number, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+func appendString(b []byte, num protowire.Number, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+func appendMessage(b []byte, num protowire.Number, msg []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, msg)
+}
@@ -0,0 +1,70 @@
+package prompb
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestReadRequestRoundTrip(t *testing.T) {
+	in := ReadRequest{
+		Queries: []Query{{
+			StartTimestampMs: 1000,
+			EndTimestampMs:   61000,
+			Matchers: []LabelMatcher{
+				{Type: MatchEqual, Name: "__name__", Value: "cpu"},
+				{Type: MatchRegexp, Name: "host", Value: "web-.*"},
+			},
+		}},
+		AcceptedResponseTypes: []int32{ResponseTypeStreamedXORChunks, ResponseTypeSamples},
+	}
+	var out ReadRequest
+	if err := out.Unmarshal(in.Marshal()); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("round trip mismatch:\n got %+v\nwant %+v", out, in)
+	}
+}
+
+func TestReadResponseRoundTrip(t *testing.T) {
+	in := ReadResponse{Results: []QueryResult{
+		{Timeseries: []TimeSeries{{
+			Labels:  []Label{{Name: "__name__", Value: "cpu"}, {Name: "host", Value: "a"}},
+			Samples: []Sample{{Value: 0, Timestamp: 1000}, {Value: -1.5, Timestamp: 2000}, {Value: math.Inf(1), Timestamp: 3000}},
+		}}},
+		{},
+	}}
+	var out ReadResponse
+	if err := out.Unmarshal(in.Marshal()); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if len(out.Results) != 2 || len(out.Results[1].Timeseries) != 0 {
+		t.Fatalf("expected 2 results with an empty second one, got %+v", out)
+	}
+	if !reflect.DeepEqual(in.Results[0], out.Results[0]) {
+		t.Errorf("round trip mismatch:\n got %+v\nwant %+v", out.Results[0], in.Results[0])
+	}
+}
+
+func TestWriteRequestRoundTrip(t *testing.T) {
+	in := WriteRequest{Timeseries: []TimeSeries{
+		{Labels: []Label{{Name: "__name__", Value: "up"}}, Samples: []Sample{{Value: 1, Timestamp: 1700000000000}}},
+		{Labels: []Label{{Name: "__name__", Value: "down"}, {Name: "job", Value: "x"}}, Samples: []Sample{{Value: 2, Timestamp: 1}}},
+	}}
+	var out WriteRequest
+	if err := out.Unmarshal(in.Marshal()); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("round trip mismatch:\n got %+v\nwant %+v", out, in)
+	}
+}
+
+func TestUnmarshalTruncated(t *testing.T) {
+	b := (&WriteRequest{Timeseries: []TimeSeries{{Labels: []Label{{Name: "a", Value: "b"}}}}}).Marshal()
+	var out WriteRequest
+	if err := out.Unmarshal(b[:len(b)-1]); err == nil {
+		t.Error("expected error for truncated message")
+	}
+}
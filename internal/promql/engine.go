@@ -0,0 +1,525 @@
+// Package promql implements a small subset of the Prometheus query language on top of
+// monigo's time series storage: selectors with label matchers, range functions such as
+// rate() and avg_over_time(), arithmetic and sum/avg/min/max/count aggregation.
+package promql
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/iyashjayesh/monigo/timeseries"
+)
+
+const (
+	// DefaultLookback is how far back an instant selector looks for the latest sample.
+	DefaultLookback = 5 * time.Minute
+	// MaxSteps bounds the number of evaluation steps of a single query.
+	MaxSteps = 11000
+)
+
+// SelectFunc loads the series of metric matching all matchers within [start, end] (Unix seconds).
+type SelectFunc func(metric string, matchers []timeseries.LabelMatcher, start, end int64) ([]timeseries.Series, error)
+
+// Series is one output series of a range query.
+type Series struct {
+	Metric map[string]string
+	Points []timeseries.DataPoint
+}
+
+// Engine evaluates queries against a storage.
+type Engine struct {
+	Select   SelectFunc
+	Lookback time.Duration
+}
+
+// NewEngine returns an engine reading from monigo's configured storage.
+func NewEngine() *Engine {
+	return &Engine{Select: timeseries.SelectMatching, Lookback: DefaultLookback}
+}
+
+// QueryRange evaluates query at every step between start and end (Unix seconds, inclusive)
+// and returns the resulting matrix, sorted by labels.
+func (e *Engine) QueryRange(query string, start, end, step int64) ([]Series, error) {
+	if step <= 0 {
+		return nil, fmt.Errorf("step must be positive")
+	}
+	if end < start {
+		return nil, fmt.Errorf("end must not be before start")
+	}
+	if (end-start)/step+1 > MaxSteps {
+		return nil, fmt.Errorf("query would return more than %d points per series, increase the step", MaxSteps)
+	}
+	expr, err := Parse(query)
+	if err != nil {
+		return nil, err
+	}
+
+	ev := &evaluator{engine: e, start: start, end: end, step: step}
+	ev.n = int((end-start)/step) + 1
+	v, err := ev.eval(expr)
+	if err != nil {
+		return nil, err
+	}
+	if v.isScalar {
+		v = value{vector: []stepSeries{{labels: map[string]string{}, values: v.scalar}}}
+	}
+
+	var result []Series
+	for _, s := range v.vector {
+		var points []timeseries.DataPoint
+		for i, val := range s.values {
+			if !math.IsNaN(val) {
+				points = append(points, timeseries.DataPoint{Timestamp: ev.ts(i), Value: val})
+			}
+		}
+		if len(points) > 0 {
+			result = append(result, Series{Metric: s.labels, Points: points})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return labelsKey(result[i].Metric, nil, false) < labelsKey(result[j].Metric, nil, false)
+	})
+	return result, nil
+}
+
+// stepSeries holds one value per evaluation step; NaN marks a step without a sample.
+type stepSeries struct {
+	labels map[string]string
+	values []float64
+}
+
+// value is the result of evaluating an expression: a scalar or an instant vector per step.
+type value struct {
+	isScalar bool
+	scalar   []float64
+	vector   []stepSeries
+}
+
+type evaluator struct {
+	engine     *Engine
+	start, end int64
+	step       int64
+	n          int
+}
+
+func (ev *evaluator) ts(i int) int64 {
+	return ev.start + int64(i)*ev.step
+}
+
+func (ev *evaluator) eval(expr Expr) (value, error) {
+	switch e := expr.(type) {
+	case *NumberLiteral:
+		values := make([]float64, ev.n)
+		for i := range values {
+			values[i] = e.Val
+		}
+		return value{isScalar: true, scalar: values}, nil
+	case *VectorSelector:
+		return ev.evalSelector(e)
+	case *MatrixSelector:
+		return value{}, fmt.Errorf("range vector must be wrapped in a function such as rate()")
+	case *Call:
+		if ms, ok := e.Arg.(*MatrixSelector); ok {
+			return ev.evalRangeFunc(e.Func, ms)
+		}
+		return ev.evalInstantFunc(e)
+	case *AggregateExpr:
+		return ev.evalAggregate(e)
+	case *UnaryExpr:
+		v, err := ev.eval(e.Expr)
+		if err != nil {
+			return value{}, err
+		}
+		return ev.binary("*", value{isScalar: true, scalar: ev.fill(-1)}, v)
+	case *BinaryExpr:
+		lhs, err := ev.eval(e.LHS)
+		if err != nil {
+			return value{}, err
+		}
+		rhs, err := ev.eval(e.RHS)
+		if err != nil {
+			return value{}, err
+		}
+		return ev.binary(e.Op, lhs, rhs)
+	}
+	return value{}, fmt.Errorf("unsupported expression %T", expr)
+}
+
+func (ev *evaluator) fill(v float64) []float64 {
+	values := make([]float64, ev.n)
+	for i := range values {
+		values[i] = v
+	}
+	return values
+}
+
+func (ev *evaluator) load(vs *VectorSelector, window time.Duration) ([]timeseries.Series, error) {
+	from := ev.start - int64(window/time.Second)
+	return ev.engine.Select(vs.Name, vs.Matchers, from, ev.end)
+}
+
+// evalSelector takes, for every step, the latest sample no older than the lookback window.
+func (ev *evaluator) evalSelector(vs *VectorSelector) (value, error) {
+	lookback := ev.engine.Lookback
+	if lookback <= 0 {
+		lookback = DefaultLookback
+	}
+	series, err := ev.load(vs, lookback)
+	if err != nil {
+		return value{}, err
+	}
+
+	window := int64(lookback / time.Second)
+	var out []stepSeries
+	for _, s := range series {
+		values := ev.fill(math.NaN())
+		j := 0
+		for i := range values {
+			t := ev.ts(i)
+			for j < len(s.Points) && s.Points[j].Timestamp <= t {
+				j++
+			}
+			if j > 0 && s.Points[j-1].Timestamp > t-window {
+				values[i] = s.Points[j-1].Value
+			}
+		}
+		out = append(out, stepSeries{labels: seriesLabels(s), values: values})
+	}
+	return value{vector: out}, nil
+}
+
+// evalRangeFunc applies fn to the samples in (t-range, t] at every step.
+func (ev *evaluator) evalRangeFunc(fn string, ms *MatrixSelector) (value, error) {
+	series, err := ev.load(ms.Vector, ms.Range)
+	if err != nil {
+		return value{}, err
+	}
+
+	window := int64(ms.Range / time.Second)
+	var out []stepSeries
+	for _, s := range series {
+		values := ev.fill(math.NaN())
+		lo, hi := 0, 0
+		for i := range values {
+			t := ev.ts(i)
+			for hi < len(s.Points) && s.Points[hi].Timestamp <= t {
+				hi++
+			}
+			for lo < hi && s.Points[lo].Timestamp <= t-window {
+				lo++
+			}
+			if v, ok := rangeFunc(fn, s.Points[lo:hi]); ok {
+				values[i] = v
+			}
+		}
+		labels := seriesLabels(s)
+		delete(labels, "__name__")
+		out = append(out, stepSeries{labels: labels, values: values})
+	}
+	return value{vector: out}, nil
+}
+
+// rangeFunc computes fn over the samples of one window. rate and increase account for
+// counter resets but, unlike Prometheus, do not extrapolate to the window boundaries.
+func rangeFunc(fn string, points []timeseries.DataPoint) (float64, bool) {
+	if len(points) == 0 {
+		return 0, false
+	}
+	switch fn {
+	case "rate", "increase":
+		if len(points) < 2 {
+			return 0, false
+		}
+		var inc float64
+		for k := 1; k < len(points); k++ {
+			if d := points[k].Value - points[k-1].Value; d >= 0 {
+				inc += d
+			} else {
+				inc += points[k].Value
+			}
+		}
+		if fn == "increase" {
+			return inc, true
+		}
+		elapsed := points[len(points)-1].Timestamp - points[0].Timestamp
+		if elapsed <= 0 {
+			return 0, false
+		}
+		return inc / float64(elapsed), true
+	case "delta":
+		if len(points) < 2 {
+			return 0, false
+		}
+		return points[len(points)-1].Value - points[0].Value, true
+	case "avg_over_time":
+		return sumPoints(points) / float64(len(points)), true
+	case "sum_over_time":
+		return sumPoints(points), true
+	case "count_over_time":
+		return float64(len(points)), true
+	case "last_over_time":
+		return points[len(points)-1].Value, true
+	case "min_over_time":
+		v := points[0].Value
+		for _, p := range points[1:] {
+			v = math.Min(v, p.Value)
+		}
+		return v, true
+	case "max_over_time":
+		v := points[0].Value
+		for _, p := range points[1:] {
+			v = math.Max(v, p.Value)
+		}
+		return v, true
+	}
+	return 0, false
+}
+
+func sumPoints(points []timeseries.DataPoint) float64 {
+	var sum float64
+	for _, p := range points {
+		sum += p.Value
+	}
+	return sum
+}
+
+func (ev *evaluator) evalInstantFunc(c *Call) (value, error) {
+	v, err := ev.eval(c.Arg)
+	if err != nil {
+		return value{}, err
+	}
+	var f func(float64) float64
+	switch c.Func {
+	case "abs":
+		f = math.Abs
+	case "ceil":
+		f = math.Ceil
+	case "floor":
+		f = math.Floor
+	case "round":
+		f = math.Round
+	case "sqrt":
+		f = math.Sqrt
+	default:
+		return value{}, fmt.Errorf("unknown function %q", c.Func)
+	}
+	apply := func(values []float64) []float64 {
+		out := make([]float64, len(values))
+		for i, x := range values {
+			out[i] = f(x)
+		}
+		return out
+	}
+	if v.isScalar {
+		return value{isScalar: true, scalar: apply(v.scalar)}, nil
+	}
+	out := make([]stepSeries, len(v.vector))
+	for i, s := range v.vector {
+		out[i] = stepSeries{labels: withoutName(s.labels), values: apply(s.values)}
+	}
+	return value{vector: out}, nil
+}
+
+// evalAggregate groups the input series by label set and reduces each group per step.
+func (ev *evaluator) evalAggregate(a *AggregateExpr) (value, error) {
+	v, err := ev.eval(a.Expr)
+	if err != nil {
+		return value{}, err
+	}
+	if v.isScalar {
+		return value{}, fmt.Errorf("%s() expects an instant vector", a.Op)
+	}
+
+	type group struct {
+		labels  map[string]string
+		members [][]float64
+	}
+	groups := map[string]*group{}
+	var order []string
+	for _, s := range v.vector {
+		labels := groupLabels(s.labels, a.Grouping, a.Without)
+		key := labelsKey(labels, nil, false)
+		g, ok := groups[key]
+		if !ok {
+			g = &group{labels: labels}
+			groups[key] = g
+			order = append(order, key)
+		}
+		g.members = append(g.members, s.values)
+	}
+
+	out := make([]stepSeries, 0, len(order))
+	for _, key := range order {
+		g := groups[key]
+		values := ev.fill(math.NaN())
+		for i := range values {
+			var acc float64
+			count := 0
+			for _, m := range g.members {
+				x := m[i]
+				if math.IsNaN(x) {
+					continue
+				}
+				switch {
+				case count == 0:
+					acc = x
+				case a.Op == "min":
+					acc = math.Min(acc, x)
+				case a.Op == "max":
+					acc = math.Max(acc, x)
+				default:
+					acc += x
+				}
+				count++
+			}
+			if count == 0 {
+				continue
+			}
+			switch a.Op {
+			case "avg":
+				acc /= float64(count)
+			case "count":
+				acc = float64(count)
+			}
+			values[i] = acc
+		}
+		out = append(out, stepSeries{labels: g.labels, values: values})
+	}
+	return value{vector: out}, nil
+}
+
+// binary applies op between two values. Vector/vector operations match series one-to-one
+// on their labels, ignoring the metric name.
+func (ev *evaluator) binary(op string, lhs, rhs value) (value, error) {
+	switch {
+	case lhs.isScalar && rhs.isScalar:
+		return value{isScalar: true, scalar: combine(op, lhs.scalar, rhs.scalar)}, nil
+	case rhs.isScalar:
+		out := make([]stepSeries, len(lhs.vector))
+		for i, s := range lhs.vector {
+			out[i] = stepSeries{labels: withoutName(s.labels), values: combine(op, s.values, rhs.scalar)}
+		}
+		return value{vector: out}, nil
+	case lhs.isScalar:
+		out := make([]stepSeries, len(rhs.vector))
+		for i, s := range rhs.vector {
+			out[i] = stepSeries{labels: withoutName(s.labels), values: combine(op, lhs.scalar, s.values)}
+		}
+		return value{vector: out}, nil
+	}
+
+	right := make(map[string]stepSeries, len(rhs.vector))
+	for _, s := range rhs.vector {
+		key := labelsKey(s.labels, []string{"__name__"}, true)
+		if _, dup := right[key]; dup {
+			return value{}, fmt.Errorf("many-to-many matching not allowed: duplicate series %s on the right-hand side", key)
+		}
+		right[key] = s
+	}
+	var out []stepSeries
+	for _, s := range lhs.vector {
+		key := labelsKey(s.labels, []string{"__name__"}, true)
+		r, ok := right[key]
+		if !ok {
+			continue
+		}
+		out = append(out, stepSeries{labels: withoutName(s.labels), values: combine(op, s.values, r.values)})
+	}
+	return value{vector: out}, nil
+}
+
+func combine(op string, a, b []float64) []float64 {
+	out := make([]float64, len(a))
+	for i := range a {
+		x, y := a[i], b[i]
+		if math.IsNaN(x) || math.IsNaN(y) {
+			out[i] = math.NaN()
+			continue
+		}
+		switch op {
+		case "+":
+			out[i] = x + y
+		case "-":
+			out[i] = x - y
+		case "*":
+			out[i] = x * y
+		case "/":
+			out[i] = x / y
+		case "%":
+			out[i] = math.Mod(x, y)
+		case "^":
+			out[i] = math.Pow(x, y)
+		}
+	}
+	return out
+}
+
+func seriesLabels(s timeseries.Series) map[string]string {
+	labels := make(map[string]string, len(s.Labels)+1)
+	for _, l := range s.Labels {
+		labels[l.Name] = l.Value
+	}
+	labels["__name__"] = s.Metric
+	return labels
+}
+
+func withoutName(labels map[string]string) map[string]string {
+	out := make(map[string]string, len(labels))
+	for k, v := range labels {
+		if k != "__name__" {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// groupLabels keeps the labels listed in grouping, or all but those with without.
+// The metric name is always dropped.
+func groupLabels(labels map[string]string, grouping []string, without bool) map[string]string {
+	out := map[string]string{}
+	if without {
+		for k, v := range labels {
+			if k != "__name__" && !contains(grouping, k) {
+				out[k] = v
+			}
+		}
+		return out
+	}
+	for _, k := range grouping {
+		if v, ok := labels[k]; ok {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// labelsKey builds a stable identity for the labels selected like groupLabels. With a
+// nil grouping and without=false it covers every label.
+func labelsKey(labels map[string]string, grouping []string, without bool) string {
+	var names []string
+	for k := range labels {
+		if grouping == nil && !without {
+			names = append(names, k)
+		} else if contains(grouping, k) != without {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, k := range names {
+		fmt.Fprintf(&b, "%s=%q,", k, labels[k])
+	}
+	return b.String()
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,138 @@
+package promql
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokDuration
+	tokLParen
+	tokRParen
+	tokLBrace
+	tokRBrace
+	tokComma
+	tokEq
+	tokNeq
+	tokRegexp
+	tokNotRegexp
+	tokAdd
+	tokSub
+	tokMul
+	tokDiv
+	tokMod
+	tokPow
+)
+
+type token struct {
+	kind tokenKind
+	val  string
+	pos  int
+}
+
+// lex splits a query into tokens. Range durations ("[5m]") are returned as a single
+// tokDuration so the parser does not need to know about duration units.
+func lex(input string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(input); {
+		c := rune(input[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case isIdentStart(c):
+			j := i + 1
+			for j < len(input) && isIdentChar(rune(input[j])) {
+				j++
+			}
+			tokens = append(tokens, token{tokIdent, input[i:j], i})
+			i = j
+		case unicode.IsDigit(c) || (c == '.' && i+1 < len(input) && unicode.IsDigit(rune(input[i+1]))):
+			j := i
+			for j < len(input) && (unicode.IsDigit(rune(input[j])) || input[j] == '.' || input[j] == 'e' || input[j] == 'E' ||
+				((input[j] == '+' || input[j] == '-') && (input[j-1] == 'e' || input[j-1] == 'E'))) {
+				j++
+			}
+			tokens = append(tokens, token{tokNumber, input[i:j], i})
+			i = j
+		case c == '"' || c == '\'':
+			j := i + 1
+			var b strings.Builder
+			for ; j < len(input) && rune(input[j]) != c; j++ {
+				if input[j] == '\\' && j+1 < len(input) {
+					j++
+				}
+				b.WriteByte(input[j])
+			}
+			if j >= len(input) {
+				return nil, fmt.Errorf("unterminated string at position %d", i)
+			}
+			tokens = append(tokens, token{tokString, b.String(), i})
+			i = j + 1
+		case c == '[':
+			j := strings.IndexByte(input[i:], ']')
+			if j < 0 {
+				return nil, fmt.Errorf("unterminated range at position %d", i)
+			}
+			tokens = append(tokens, token{tokDuration, strings.TrimSpace(input[i+1 : i+j]), i})
+			i += j + 1
+		default:
+			kind, width := tokEOF, 1
+			two := ""
+			if i+1 < len(input) {
+				two = input[i : i+2]
+			}
+			switch {
+			case two == "!=":
+				kind, width = tokNeq, 2
+			case two == "=~":
+				kind, width = tokRegexp, 2
+			case two == "!~":
+				kind, width = tokNotRegexp, 2
+			case c == '=':
+				kind = tokEq
+			case c == '(':
+				kind = tokLParen
+			case c == ')':
+				kind = tokRParen
+			case c == '{':
+				kind = tokLBrace
+			case c == '}':
+				kind = tokRBrace
+			case c == ',':
+				kind = tokComma
+			case c == '+':
+				kind = tokAdd
+			case c == '-':
+				kind = tokSub
+			case c == '*':
+				kind = tokMul
+			case c == '/':
+				kind = tokDiv
+			case c == '%':
+				kind = tokMod
+			case c == '^':
+				kind = tokPow
+			default:
+				return nil, fmt.Errorf("unexpected character %q at position %d", c, i)
+			}
+			tokens = append(tokens, token{kind, input[i : i+width], i})
+			i += width
+		}
+	}
+	return append(tokens, token{tokEOF, "", len(input)}), nil
+}
+
+func isIdentStart(c rune) bool {
+	return c == '_' || c == ':' || unicode.IsLetter(c)
+}
+
+func isIdentChar(c rune) bool {
+	return isIdentStart(c) || unicode.IsDigit(c)
+}
@@ -0,0 +1,364 @@
+package promql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iyashjayesh/monigo/common"
+	"github.com/iyashjayesh/monigo/timeseries"
+)
+
+// Expr is a node of a parsed query.
+type Expr interface {
+	expr()
+}
+
+// NumberLiteral is a constant such as 100 or 0.5.
+type NumberLiteral struct {
+	Val float64
+}
+
+// VectorSelector selects the latest sample of every matching series at each step.
+type VectorSelector struct {
+	Name     string
+	Matchers []timeseries.LabelMatcher
+}
+
+// MatrixSelector selects all samples of the matching series within Range before each step.
+type MatrixSelector struct {
+	Vector *VectorSelector
+	Range  time.Duration
+}
+
+// Call is a function applied to a single argument, e.g. rate(x[5m]) or abs(x).
+type Call struct {
+	Func string
+	Arg  Expr
+}
+
+// AggregateExpr combines series, optionally grouped by (or without) a set of labels.
+type AggregateExpr struct {
+	Op       string
+	Grouping []string
+	Without  bool
+	Expr     Expr
+}
+
+// BinaryExpr applies an arithmetic operator between scalars and/or vectors.
+type BinaryExpr struct {
+	Op  string
+	LHS Expr
+	RHS Expr
+}
+
+// UnaryExpr negates its operand.
+type UnaryExpr struct {
+	Op   string
+	Expr Expr
+}
+
+func (*NumberLiteral) expr()  {}
+func (*VectorSelector) expr() {}
+func (*MatrixSelector) expr() {}
+func (*Call) expr()           {}
+func (*AggregateExpr) expr()  {}
+func (*BinaryExpr) expr()     {}
+func (*UnaryExpr) expr()      {}
+
+// rangeFunctions take a range vector argument; instantFunctions take an instant vector.
+var (
+	rangeFunctions = map[string]bool{
+		"rate": true, "increase": true, "delta": true,
+		"avg_over_time": true, "min_over_time": true, "max_over_time": true,
+		"sum_over_time": true, "count_over_time": true, "last_over_time": true,
+	}
+	instantFunctions = map[string]bool{
+		"abs": true, "ceil": true, "floor": true, "round": true, "sqrt": true,
+	}
+	aggregateOps = map[string]bool{
+		"sum": true, "avg": true, "min": true, "max": true, "count": true,
+	}
+	binaryPrecedence = map[tokenKind]int{
+		tokAdd: 1, tokSub: 1,
+		tokMul: 2, tokDiv: 2, tokMod: 2,
+		tokPow: 3,
+	}
+)
+
+// Parse parses a query into an expression tree.
+func Parse(query string) (Expr, error) {
+	tokens, err := lex(query)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	e, err := p.parseExpr(0)
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q at position %d", t.val, t.pos)
+	}
+	if _, ok := e.(*MatrixSelector); ok {
+		return nil, fmt.Errorf("range vector must be wrapped in a function such as rate()")
+	}
+	return e, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) expect(kind tokenKind, what string) (token, error) {
+	t := p.next()
+	if t.kind != kind {
+		if t.kind == tokEOF {
+			return t, fmt.Errorf("unexpected end of query, expected %s", what)
+		}
+		return t, fmt.Errorf("unexpected %q at position %d, expected %s", t.val, t.pos, what)
+	}
+	return t, nil
+}
+
+// parseExpr parses binary expressions by precedence climbing. ^ is right-associative.
+func (p *parser) parseExpr(minPrec int) (Expr, error) {
+	lhs, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek()
+		prec, ok := binaryPrecedence[op.kind]
+		if !ok || prec <= minPrec && !(op.kind == tokPow && prec == minPrec) {
+			return lhs, nil
+		}
+		p.next()
+		rhs, err := p.parseExpr(prec)
+		if err != nil {
+			return nil, err
+		}
+		lhs = &BinaryExpr{Op: op.val, LHS: lhs, RHS: rhs}
+	}
+}
+
+// parseUnary parses a unary sign. ^ binds tighter, so -2^2 is -(2^2).
+func (p *parser) parseUnary() (Expr, error) {
+	switch p.peek().kind {
+	case tokSub:
+		p.next()
+		e, err := p.parseExpr(binaryPrecedence[tokPow])
+		if err != nil {
+			return nil, err
+		}
+		if n, ok := e.(*NumberLiteral); ok {
+			return &NumberLiteral{Val: -n.Val}, nil
+		}
+		return &UnaryExpr{Op: "-", Expr: e}, nil
+	case tokAdd:
+		p.next()
+		return p.parseExpr(binaryPrecedence[tokPow])
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Expr, error) {
+	t := p.peek()
+	switch t.kind {
+	case tokNumber:
+		p.next()
+		v, err := strconv.ParseFloat(t.val, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", t.val, t.pos)
+		}
+		return &NumberLiteral{Val: v}, nil
+	case tokLParen:
+		p.next()
+		e, err := p.parseExpr(0)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokRParen, "\")\""); err != nil {
+			return nil, err
+		}
+		return e, nil
+	case tokLBrace:
+		return p.parseSelector("")
+	case tokIdent:
+		p.next()
+		name := t.val
+		lower := strings.ToLower(name)
+		next := p.peek()
+		if aggregateOps[lower] && (next.kind == tokLParen || isGroupingKeyword(next)) {
+			return p.parseAggregate(lower)
+		}
+		if next.kind == tokLParen {
+			return p.parseCall(lower, t.pos)
+		}
+		return p.parseSelector(name)
+	case tokEOF:
+		return nil, fmt.Errorf("unexpected end of query")
+	}
+	return nil, fmt.Errorf("unexpected %q at position %d", t.val, t.pos)
+}
+
+func isGroupingKeyword(t token) bool {
+	if t.kind != tokIdent {
+		return false
+	}
+	kw := strings.ToLower(t.val)
+	return kw == "by" || kw == "without"
+}
+
+// parseSelector parses the optional {matchers} and [range] following a metric name.
+func (p *parser) parseSelector(name string) (Expr, error) {
+	vs := &VectorSelector{Name: name}
+	if p.peek().kind == tokLBrace {
+		p.next()
+		for p.peek().kind != tokRBrace {
+			m, err := p.parseMatcher()
+			if err != nil {
+				return nil, err
+			}
+			if m.Name == "__name__" && m.Type == timeseries.MatchEqual {
+				vs.Name = m.Value
+			} else {
+				vs.Matchers = append(vs.Matchers, m)
+			}
+			if p.peek().kind != tokComma {
+				break
+			}
+			p.next()
+		}
+		if _, err := p.expect(tokRBrace, "\"}\""); err != nil {
+			return nil, err
+		}
+	}
+	if vs.Name == "" {
+		return nil, fmt.Errorf("selector must specify a metric name")
+	}
+
+	if t := p.peek(); t.kind == tokDuration {
+		p.next()
+		d, err := common.ParseDuration(t.val)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid range %q at position %d", t.val, t.pos)
+		}
+		return &MatrixSelector{Vector: vs, Range: d}, nil
+	}
+	return vs, nil
+}
+
+func (p *parser) parseMatcher() (timeseries.LabelMatcher, error) {
+	name, err := p.expect(tokIdent, "label name")
+	if err != nil {
+		return timeseries.LabelMatcher{}, err
+	}
+	op := p.next()
+	var mt timeseries.MatchType
+	switch op.kind {
+	case tokEq:
+		mt = timeseries.MatchEqual
+	case tokNeq:
+		mt = timeseries.MatchNotEqual
+	case tokRegexp:
+		mt = timeseries.MatchRegexp
+	case tokNotRegexp:
+		mt = timeseries.MatchNotRegexp
+	default:
+		return timeseries.LabelMatcher{}, fmt.Errorf("unexpected %q at position %d, expected label matcher", op.val, op.pos)
+	}
+	value, err := p.expect(tokString, "label value")
+	if err != nil {
+		return timeseries.LabelMatcher{}, err
+	}
+	return timeseries.NewLabelMatcher(mt, name.val, value.val)
+}
+
+func (p *parser) parseCall(name string, pos int) (Expr, error) {
+	if !rangeFunctions[name] && !instantFunctions[name] {
+		return nil, fmt.Errorf("unknown function %q at position %d", name, pos)
+	}
+	p.next() // (
+	arg, err := p.parseExpr(0)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(tokRParen, "\")\""); err != nil {
+		return nil, err
+	}
+	_, isRange := arg.(*MatrixSelector)
+	if rangeFunctions[name] && !isRange {
+		return nil, fmt.Errorf("%s() expects a range vector, e.g. %s(metric[5m])", name, name)
+	}
+	if instantFunctions[name] && isRange {
+		return nil, fmt.Errorf("%s() expects an instant vector", name)
+	}
+	return &Call{Func: name, Arg: arg}, nil
+}
+
+// parseAggregate accepts the grouping clause either before or after the argument:
+// "sum by (host) (x)" and "sum(x) by (host)".
+func (p *parser) parseAggregate(op string) (Expr, error) {
+	agg := &AggregateExpr{Op: op}
+	if isGroupingKeyword(p.peek()) {
+		if err := p.parseGrouping(agg); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := p.expect(tokLParen, "\"(\""); err != nil {
+		return nil, err
+	}
+	e, err := p.parseExpr(0)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(tokRParen, "\")\""); err != nil {
+		return nil, err
+	}
+	if _, ok := e.(*MatrixSelector); ok {
+		return nil, fmt.Errorf("%s() expects an instant vector", op)
+	}
+	agg.Expr = e
+	if agg.Grouping == nil && !agg.Without && isGroupingKeyword(p.peek()) {
+		if err := p.parseGrouping(agg); err != nil {
+			return nil, err
+		}
+	}
+	return agg, nil
+}
+
+func (p *parser) parseGrouping(agg *AggregateExpr) error {
+	agg.Without = strings.ToLower(p.next().val) == "without"
+	if _, err := p.expect(tokLParen, "\"(\""); err != nil {
+		return err
+	}
+	agg.Grouping = []string{}
+	for p.peek().kind != tokRParen {
+		label, err := p.expect(tokIdent, "label name")
+		if err != nil {
+			return err
+		}
+		agg.Grouping = append(agg.Grouping, label.val)
+		if p.peek().kind != tokComma {
+			break
+		}
+		p.next()
+	}
+	_, err := p.expect(tokRParen, "\")\"")
+	return err
+}
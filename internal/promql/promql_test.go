@@ -0,0 +1,258 @@
+package promql
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/iyashjayesh/monigo/timeseries"
+)
+
+// fakeSelect serves a fixed set of series, applying matchers the way storage does.
+func fakeSelect(series []timeseries.Series) SelectFunc {
+	return func(metric string, matchers []timeseries.LabelMatcher, start, end int64) ([]timeseries.Series, error) {
+		var out []timeseries.Series
+	next:
+		for _, s := range series {
+			if s.Metric != metric {
+				continue
+			}
+			for _, m := range matchers {
+				var v string
+				for _, l := range s.Labels {
+					if l.Name == m.Name {
+						v = l.Value
+					}
+				}
+				if !m.Matches(v) {
+					continue next
+				}
+			}
+			var points []timeseries.DataPoint
+			for _, p := range s.Points {
+				if p.Timestamp >= start && p.Timestamp <= end {
+					points = append(points, p)
+				}
+			}
+			out = append(out, timeseries.Series{Metric: s.Metric, Labels: s.Labels, Points: points})
+		}
+		return out, nil
+	}
+}
+
+// counter returns points every 10s from 0 to 120 increasing by perSecond.
+func counter(perSecond float64) []timeseries.DataPoint {
+	var points []timeseries.DataPoint
+	for ts := int64(0); ts <= 120; ts += 10 {
+		points = append(points, timeseries.DataPoint{Timestamp: ts, Value: float64(ts) * perSecond})
+	}
+	return points
+}
+
+func testEngine() *Engine {
+	series := []timeseries.Series{
+		{Metric: "requests_total", Labels: []timeseries.Label{{Name: "host", Value: "a"}, {Name: "path", Value: "/x"}}, Points: counter(1)},
+		{Metric: "requests_total", Labels: []timeseries.Label{{Name: "host", Value: "a"}, {Name: "path", Value: "/y"}}, Points: counter(2)},
+		{Metric: "requests_total", Labels: []timeseries.Label{{Name: "host", Value: "b"}, {Name: "path", Value: "/x"}}, Points: counter(4)},
+		{Metric: "errors_total", Labels: []timeseries.Label{{Name: "host", Value: "a"}, {Name: "path", Value: "/x"}}, Points: counter(0.5)},
+	}
+	return &Engine{Select: fakeSelect(series), Lookback: DefaultLookback}
+}
+
+// lastValue returns the value of the series at the final step.
+func lastValue(t *testing.T, s Series) float64 {
+	t.Helper()
+	if len(s.Points) == 0 {
+		t.Fatalf("series %v has no points", s.Metric)
+	}
+	return s.Points[len(s.Points)-1].Value
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"rate(requests_total)",
+		"requests_total[5m]",
+		"sum(requests_total[5m])",
+		"unknown_fn(requests_total)",
+		`requests_total{host="a"`,
+		`requests_total{host=~"("}`,
+		"requests_total[five]",
+		"1 +",
+	}
+	for _, q := range tests {
+		if _, err := Parse(q); err == nil {
+			t.Errorf("Parse(%q) expected error", q)
+		}
+	}
+}
+
+func TestSelectorWithMatchers(t *testing.T) {
+	e := testEngine()
+	result, err := e.QueryRange(`requests_total{host="a",path!~"/y"}`, 60, 120, 30)
+	if err != nil {
+		t.Fatalf("QueryRange() error = %v", err)
+	}
+	if len(result) != 1 {
+		t.Fatalf("expected 1 series, got %d", len(result))
+	}
+	if result[0].Metric["__name__"] != "requests_total" || result[0].Metric["path"] != "/x" {
+		t.Errorf("unexpected labels %v", result[0].Metric)
+	}
+	if len(result[0].Points) != 3 {
+		t.Errorf("expected 3 steps, got %d", len(result[0].Points))
+	}
+	if got := lastValue(t, result[0]); got != 120 {
+		t.Errorf("expected last value 120, got %v", got)
+	}
+}
+
+func TestRateAndAvgOverTime(t *testing.T) {
+	e := testEngine()
+	result, err := e.QueryRange(`rate(requests_total{host="b"}[1m])`, 120, 120, 10)
+	if err != nil {
+		t.Fatalf("QueryRange() error = %v", err)
+	}
+	if len(result) != 1 || lastValue(t, result[0]) != 4 {
+		t.Fatalf("expected rate 4, got %+v", result)
+	}
+	if _, ok := result[0].Metric["__name__"]; ok {
+		t.Error("rate() should drop the metric name")
+	}
+
+	result, err = e.QueryRange(`avg_over_time(requests_total{host="b"}[30s])`, 120, 120, 10)
+	if err != nil {
+		t.Fatalf("QueryRange() error = %v", err)
+	}
+	// Window (90, 120] holds samples at 100, 110, 120.
+	if len(result) != 1 || lastValue(t, result[0]) != 440 {
+		t.Fatalf("expected avg 440, got %+v", result)
+	}
+}
+
+func TestRateCounterReset(t *testing.T) {
+	series := []timeseries.Series{{
+		Metric: "c",
+		Points: []timeseries.DataPoint{{Timestamp: 0, Value: 10}, {Timestamp: 10, Value: 20}, {Timestamp: 20, Value: 5}},
+	}}
+	e := &Engine{Select: fakeSelect(series)}
+	result, err := e.QueryRange("increase(c[1m])", 20, 20, 1)
+	if err != nil {
+		t.Fatalf("QueryRange() error = %v", err)
+	}
+	if len(result) != 1 || lastValue(t, result[0]) != 15 {
+		t.Fatalf("expected increase 15, got %+v", result)
+	}
+}
+
+func TestAggregationBy(t *testing.T) {
+	e := testEngine()
+	for _, q := range []string{
+		"sum by (host) (rate(requests_total[1m]))",
+		"sum(rate(requests_total[1m])) by (host)",
+	} {
+		result, err := e.QueryRange(q, 120, 120, 10)
+		if err != nil {
+			t.Fatalf("QueryRange(%q) error = %v", q, err)
+		}
+		if len(result) != 2 {
+			t.Fatalf("%q: expected 2 groups, got %d", q, len(result))
+		}
+		got := map[string]float64{}
+		for _, s := range result {
+			if len(s.Metric) != 1 {
+				t.Errorf("%q: expected only the host label, got %v", q, s.Metric)
+			}
+			got[s.Metric["host"]] = lastValue(t, s)
+		}
+		if got["a"] != 3 || got["b"] != 4 {
+			t.Errorf("%q: unexpected sums %v", q, got)
+		}
+	}
+
+	result, err := e.QueryRange("count without (path) (requests_total)", 120, 120, 10)
+	if err != nil {
+		t.Fatalf("QueryRange() error = %v", err)
+	}
+	if len(result) != 2 {
+		t.Fatalf("expected 2 groups, got %d", len(result))
+	}
+
+	result, err = e.QueryRange("max(requests_total)", 120, 120, 10)
+	if err != nil {
+		t.Fatalf("QueryRange() error = %v", err)
+	}
+	if len(result) != 1 || len(result[0].Metric) != 0 || lastValue(t, result[0]) != 480 {
+		t.Fatalf("expected a single max of 480, got %+v", result)
+	}
+}
+
+func TestBinaryOperations(t *testing.T) {
+	e := testEngine()
+	result, err := e.QueryRange("rate(errors_total[1m]) / rate(requests_total[1m]) * 100", 120, 120, 10)
+	if err != nil {
+		t.Fatalf("QueryRange() error = %v", err)
+	}
+	if len(result) != 1 {
+		t.Fatalf("expected only the series present on both sides, got %d", len(result))
+	}
+	if result[0].Metric["host"] != "a" || result[0].Metric["path"] != "/x" {
+		t.Errorf("unexpected labels %v", result[0].Metric)
+	}
+	if got := lastValue(t, result[0]); got != 50 {
+		t.Errorf("expected 50, got %v", got)
+	}
+
+	result, err = e.QueryRange("2 ^ 3 ^ 2 - -1", 0, 0, 1)
+	if err != nil {
+		t.Fatalf("QueryRange() error = %v", err)
+	}
+	if len(result) != 1 || lastValue(t, result[0]) != 513 {
+		t.Fatalf("expected 513, got %+v", result)
+	}
+
+	// ^ binds tighter than a unary minus, as in PromQL.
+	for query, want := range map[string]float64{"-2^2": -4, "-2^-1": -0.5, "(-2)^2": 4, "2^-2": 0.25} {
+		result, err := e.QueryRange(query, 0, 0, 1)
+		if err != nil {
+			t.Fatalf("%s: QueryRange() error = %v", query, err)
+		}
+		if len(result) != 1 || lastValue(t, result[0]) != want {
+			t.Errorf("%s: expected %v, got %+v", query, want, result)
+		}
+	}
+}
+
+func TestLookbackGaps(t *testing.T) {
+	series := []timeseries.Series{{Metric: "g", Points: []timeseries.DataPoint{{Timestamp: 0, Value: 1}}}}
+	e := &Engine{Select: fakeSelect(series), Lookback: time.Minute}
+	result, err := e.QueryRange("g", 0, 120, 30)
+	if err != nil {
+		t.Fatalf("QueryRange() error = %v", err)
+	}
+	// The sample is visible at 0 and 30 only; later steps are beyond the lookback.
+	if len(result) != 1 || len(result[0].Points) != 2 {
+		t.Fatalf("expected 2 points, got %+v", result)
+	}
+}
+
+func TestQueryRangeLimits(t *testing.T) {
+	e := testEngine()
+	if _, err := e.QueryRange("requests_total", 0, 10, 0); err == nil {
+		t.Error("expected error for zero step")
+	}
+	if _, err := e.QueryRange("requests_total", 10, 0, 1); err == nil {
+		t.Error("expected error for end before start")
+	}
+	if _, err := e.QueryRange("requests_total", 0, MaxSteps*2, 1); err == nil {
+		t.Error("expected error for too many steps")
+	}
+	result, err := e.QueryRange("missing_metric", 0, 10, 1)
+	if err != nil || len(result) != 0 {
+		t.Errorf("expected empty result, got %v, %v", result, err)
+	}
+	result, err = e.QueryRange("requests_total / 0", 120, 120, 1)
+	if err != nil || len(result) != 3 || !math.IsInf(lastValue(t, result[0]), 1) {
+		t.Errorf("expected +Inf for division by zero, got %v, %v", result, err)
+	}
+}
@@ -0,0 +1,179 @@
+package registry
+
+import (
+	"math"
+	"sort"
+)
+
+// DefaultBuckets are the upper bounds of histograms configured without explicit buckets.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// DefaultExponentialScale splits every power of two into 2^3 exponential buckets.
+const DefaultExponentialScale int32 = 3
+
+// Scale limits of exponential histograms, as in OpenTelemetry.
+const (
+	MinExponentialScale int32 = -10
+	MaxExponentialScale int32 = 20
+)
+
+// HistogramOpts configures the buckets of a histogram metric.
+type HistogramOpts struct {
+	// Buckets are explicit, ascending upper bounds; DefaultBuckets is used when empty.
+	Buckets []float64
+	// Exponential selects base-2 exponential (native) buckets instead, with boundaries
+	// at powers of 2^(2^-Scale). A zero Scale means DefaultExponentialScale.
+	Exponential bool
+	Scale       int32
+}
+
+// Bucket holds the observations that fell into one histogram bucket.
+type Bucket struct {
+	Count uint64
+	Sum   float64
+}
+
+// HistogramData is the state of a histogram series.
+type HistogramData struct {
+	Count uint64
+	Sum   float64
+	Min   float64
+	Max   float64
+
+	// Explicit buckets: Buckets[i] holds observations in (Bounds[i-1], Bounds[i]];
+	// the final entry is the +Inf bucket.
+	Bounds  []float64
+	Buckets []Bucket
+
+	// Exponential buckets: index i holds absolute values in (base^i, base^(i+1)]
+	// with base = 2^(2^-Scale). Zero observations are counted in ZeroCount.
+	Exponential bool
+	Scale       int32
+	ZeroCount   uint64
+	Positive    map[int32]Bucket
+	Negative    map[int32]Bucket
+}
+
+func newHistogramData(opts HistogramOpts) *HistogramData {
+	if opts.Exponential {
+		scale := opts.Scale
+		if scale == 0 {
+			scale = DefaultExponentialScale
+		}
+		scale = max(MinExponentialScale, min(MaxExponentialScale, scale))
+		return &HistogramData{
+			Exponential: true,
+			Scale:       scale,
+			Positive:    make(map[int32]Bucket),
+			Negative:    make(map[int32]Bucket),
+		}
+	}
+
+	bounds := opts.Buckets
+	if len(bounds) == 0 {
+		bounds = DefaultBuckets
+	}
+	bounds = append([]float64(nil), bounds...)
+	sort.Float64s(bounds)
+	return &HistogramData{
+		Bounds:  bounds,
+		Buckets: make([]Bucket, len(bounds)+1),
+	}
+}
+
+func (h *HistogramData) observe(v float64) {
+	if math.IsNaN(v) {
+		return
+	}
+	if h.Count == 0 || v < h.Min {
+		h.Min = v
+	}
+	if h.Count == 0 || v > h.Max {
+		h.Max = v
+	}
+	h.Count++
+	h.Sum += v
+
+	if !h.Exponential {
+		i := sort.SearchFloat64s(h.Bounds, v)
+		h.Buckets[i] = Bucket{Count: h.Buckets[i].Count + 1, Sum: h.Buckets[i].Sum + v}
+		return
+	}
+
+	buckets := h.Positive
+	if v == 0 {
+		h.ZeroCount++
+		return
+	} else if v < 0 {
+		buckets = h.Negative
+	}
+	i := ExponentialIndex(h.Scale, math.Abs(v))
+	buckets[i] = Bucket{Count: buckets[i].Count + 1, Sum: buckets[i].Sum + v}
+}
+
+func (h *HistogramData) clone() *HistogramData {
+	cp := *h
+	cp.Bounds = append([]float64(nil), h.Bounds...)
+	cp.Buckets = append([]Bucket(nil), h.Buckets...)
+	if h.Exponential {
+		cp.Positive = make(map[int32]Bucket, len(h.Positive))
+		for i, b := range h.Positive {
+			cp.Positive[i] = b
+		}
+		cp.Negative = make(map[int32]Bucket, len(h.Negative))
+		for i, b := range h.Negative {
+			cp.Negative[i] = b
+		}
+	}
+	return &cp
+}
+
+// CumulativeBuckets returns ascending upper bounds with the number of observations less
+// than or equal to each, in the form of Prometheus "le" buckets; +Inf is implied by Count.
+// Exponential buckets are reported at their boundaries.
+func (h *HistogramData) CumulativeBuckets() ([]float64, []uint64) {
+	if !h.Exponential {
+		counts := make([]uint64, len(h.Bounds))
+		var total uint64
+		for i := range h.Bounds {
+			total += h.Buckets[i].Count
+			counts[i] = total
+		}
+		return append([]float64(nil), h.Bounds...), counts
+	}
+
+	type point struct {
+		bound float64
+		count uint64
+	}
+	points := make([]point, 0, len(h.Positive)+len(h.Negative)+1)
+	for i, b := range h.Negative {
+		points = append(points, point{-ExponentialBound(h.Scale, i-1), b.Count})
+	}
+	if h.ZeroCount > 0 || len(h.Negative) > 0 {
+		points = append(points, point{0, h.ZeroCount})
+	}
+	for i, b := range h.Positive {
+		points = append(points, point{ExponentialBound(h.Scale, i), b.Count})
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].bound < points[j].bound })
+
+	bounds := make([]float64, len(points))
+	counts := make([]uint64, len(points))
+	var total uint64
+	for i, p := range points {
+		total += p.count
+		bounds[i], counts[i] = p.bound, total
+	}
+	return bounds, counts
+}
+
+// ExponentialIndex returns the index of the exponential bucket holding the positive value v.
+func ExponentialIndex(scale int32, v float64) int32 {
+	return int32(math.Ceil(math.Ldexp(math.Log2(v), int(scale)))) - 1
+}
+
+// ExponentialBound returns the upper boundary of the exponential bucket at index.
+func ExponentialBound(scale, index int32) float64 {
+	return math.Exp2(math.Ldexp(float64(index+1), -int(scale)))
+}
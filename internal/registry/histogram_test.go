@@ -0,0 +1,99 @@
+package registry
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHistogramExplicitBuckets(t *testing.T) {
+	r := NewRegistry()
+	r.ConfigureHistogram("latency", HistogramOpts{Buckets: []float64{1, 0.1}})
+	for _, v := range []float64{0.05, 0.1, 0.5, 2, math.NaN()} {
+		r.RecordHistogram("latency", v, nil)
+	}
+
+	h := r.GetAll()[0].Histogram
+	if h == nil {
+		t.Fatal("expected histogram data")
+	}
+	if h.Count != 4 || h.Sum != 2.65 || h.Min != 0.05 || h.Max != 2 {
+		t.Errorf("unexpected totals count=%d sum=%v min=%v max=%v", h.Count, h.Sum, h.Min, h.Max)
+	}
+	if len(h.Bounds) != 2 || h.Bounds[0] != 0.1 {
+		t.Fatalf("expected sorted bounds, got %v", h.Bounds)
+	}
+	// Upper bounds are inclusive: 0.1 belongs to the first bucket.
+	if h.Buckets[0].Count != 2 || h.Buckets[1].Count != 1 || h.Buckets[2].Count != 1 {
+		t.Errorf("unexpected bucket counts %+v", h.Buckets)
+	}
+	bounds, counts := h.CumulativeBuckets()
+	if len(bounds) != 2 || counts[0] != 2 || counts[1] != 3 {
+		t.Errorf("unexpected cumulative buckets %v %v", bounds, counts)
+	}
+}
+
+func TestHistogramDefaultBuckets(t *testing.T) {
+	r := NewRegistry()
+	r.RecordHistogram("latency", 0.3, map[string]string{"route": "/a"})
+	r.RecordHistogram("latency", 0.3, map[string]string{"route": "/b"})
+
+	metrics := r.GetAll()
+	if len(metrics) != 2 {
+		t.Fatalf("expected a histogram per label set, got %d", len(metrics))
+	}
+	if got := len(metrics[0].Histogram.Bounds); got != len(DefaultBuckets) {
+		t.Errorf("expected %d default bounds, got %d", len(DefaultBuckets), got)
+	}
+}
+
+func TestHistogramExponentialBuckets(t *testing.T) {
+	r := NewRegistry()
+	r.ConfigureHistogram("size", HistogramOpts{Exponential: true, Scale: 0})
+	for _, v := range []float64{0, 1, 2, 3, 4, -3} {
+		r.RecordHistogram("size", v, nil)
+	}
+
+	h := r.GetAll()[0].Histogram
+	if !h.Exponential || h.Scale != DefaultExponentialScale {
+		t.Fatalf("expected exponential buckets at the default scale, got %+v", h)
+	}
+
+	r.ConfigureHistogram("base2", HistogramOpts{Exponential: true, Scale: MinExponentialScale - 5})
+	r.RecordHistogram("base2", 1, nil)
+	for _, m := range r.GetAll() {
+		if m.Name == "base2" && m.Histogram.Scale != MinExponentialScale {
+			t.Errorf("expected the scale to be clamped to %d, got %d", MinExponentialScale, m.Histogram.Scale)
+		}
+	}
+
+	// At scale 0 the base is 2: (0.5,1] is index -1, (1,2] index 0, (2,4] index 1.
+	for v, want := range map[float64]int32{1: -1, 2: 0, 3: 1, 4: 1, 4.5: 2} {
+		if got := ExponentialIndex(0, v); got != want {
+			t.Errorf("ExponentialIndex(0, %v) = %d, want %d", v, got, want)
+		}
+	}
+	if got := ExponentialBound(0, 1); got != 4 {
+		t.Errorf("ExponentialBound(0, 1) = %v, want 4", got)
+	}
+
+	bounds, counts := h.CumulativeBuckets()
+	if counts[len(counts)-1] != h.Count {
+		t.Errorf("expected the last cumulative count to cover every observation, got %v", counts)
+	}
+	for i := 1; i < len(bounds); i++ {
+		if bounds[i] <= bounds[i-1] || counts[i] < counts[i-1] {
+			t.Fatalf("expected ascending buckets, got %v %v", bounds, counts)
+		}
+	}
+}
+
+func TestHistogramSnapshotIsolated(t *testing.T) {
+	r := NewRegistry()
+	r.RecordHistogram("latency", 1, nil)
+	snap := r.GetAll()[0].Histogram
+	r.RecordHistogram("latency", 1, nil)
+
+	if snap.Count != 1 {
+		t.Errorf("expected the snapshot to keep count 1, got %d", snap.Count)
+	}
+}
@@ -1,6 +1,8 @@
 package registry
 
 import (
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
@@ -13,67 +15,193 @@ const (
 	Histogram
 )
 
+func (t MetricType) String() string {
+	switch t {
+	case Counter:
+		return "counter"
+	case Histogram:
+		return "histogram"
+	default:
+		return "gauge"
+	}
+}
+
 type MetricValue struct {
 	Name      string
 	Value     float64
 	Labels    map[string]string
 	Timestamp time.Time
 	Type      MetricType
+	Histogram *HistogramData // set for Histogram metrics; Value holds the last observation
 }
 
 type Registry struct {
-	mu      sync.RWMutex
-	metrics map[string]*MetricValue
+	mu         sync.RWMutex
+	metrics    map[string]*MetricValue // keyed by SeriesKey
+	histograms map[string]HistogramOpts
+	limits     map[string]int // maximum series per metric name, set by SetSeriesLimit
+	series     map[string]int // current series per metric name
 }
 
 func NewRegistry() *Registry {
 	return &Registry{
-		metrics: make(map[string]*MetricValue),
+		metrics:    make(map[string]*MetricValue),
+		histograms: make(map[string]HistogramOpts),
+		limits:     make(map[string]int),
+		series:     make(map[string]int),
 	}
 }
 
+// SetGauge sets a gauge. Series beyond the limit of the metric are dropped.
 func (r *Registry) SetGauge(name string, value float64, labels map[string]string) {
+	_ = r.setGauge(name, value, labels)
+}
+
+func (r *Registry) setGauge(name string, value float64, labels map[string]string) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	r.metrics[name] = &MetricValue{
+	key := SeriesKey(name, labels)
+	if !r.admitLocked(key, name) {
+		return ErrCardinalityLimit
+	}
+	r.storeLocked(key, &MetricValue{
 		Name:      name,
 		Value:     value,
 		Labels:    labels,
 		Timestamp: time.Now(),
 		Type:      Gauge,
-	}
+	})
+	return nil
 }
 
 // IncrementCounter atomically increments a counter metric.
+// Series beyond the limit of the metric are dropped.
 func (r *Registry) IncrementCounter(name string, delta float64, labels map[string]string) {
+	_ = r.incrementCounter(name, delta, labels)
+}
+
+func (r *Registry) incrementCounter(name string, delta float64, labels map[string]string) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	if m, ok := r.metrics[name]; ok && m.Type == Counter {
+	key := SeriesKey(name, labels)
+	if m, ok := r.metrics[key]; ok && m.Type == Counter {
 		m.Value += delta
 		m.Timestamp = time.Now()
-	} else {
-		r.metrics[name] = &MetricValue{
-			Name:      name,
-			Value:     delta,
-			Labels:    labels,
-			Timestamp: time.Now(),
-			Type:      Counter,
-		}
+		return nil
+	}
+	if !r.admitLocked(key, name) {
+		return ErrCardinalityLimit
 	}
+	r.storeLocked(key, &MetricValue{
+		Name:      name,
+		Value:     delta,
+		Labels:    labels,
+		Timestamp: time.Now(),
+		Type:      Counter,
+	})
+	return nil
 }
 
-// RecordHistogram records a histogram observation.
-// For the OTel exporter this is a no-op placeholder; values are exported as gauges.
-func (r *Registry) RecordHistogram(name string, value float64, labels map[string]string) {
+// SetCounter sets a counter to a cumulative total kept elsewhere, such as a runtime
+// statistic. Series beyond the limit of the metric are dropped.
+func (r *Registry) SetCounter(name string, value float64, labels map[string]string) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	r.metrics[name] = &MetricValue{
+	key := SeriesKey(name, labels)
+	if !r.admitLocked(key, name) {
+		return
+	}
+	r.storeLocked(key, &MetricValue{
 		Name:      name,
 		Value:     value,
 		Labels:    labels,
 		Timestamp: time.Now(),
-		Type:      Histogram,
+		Type:      Counter,
+	})
+}
+
+// ConfigureHistogram sets the buckets used by series of the histogram name created
+// after the call. Histograms default to DefaultBuckets.
+func (r *Registry) ConfigureHistogram(name string, opts HistogramOpts) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.histograms[name] = opts
+}
+
+// RecordHistogram records a histogram observation.
+// Series beyond the limit of the metric are dropped.
+func (r *Registry) RecordHistogram(name string, value float64, labels map[string]string) {
+	_ = r.recordHistogram(name, value, labels)
+}
+
+func (r *Registry) recordHistogram(name string, value float64, labels map[string]string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := SeriesKey(name, labels)
+	m, ok := r.metrics[key]
+	if !ok || m.Type != Histogram {
+		if !r.admitLocked(key, name) {
+			return ErrCardinalityLimit
+		}
+		m = &MetricValue{
+			Name:      name,
+			Labels:    labels,
+			Type:      Histogram,
+			Histogram: newHistogramData(r.histograms[name]),
+		}
+		r.storeLocked(key, m)
 	}
+	m.Value = value
+	m.Timestamp = time.Now()
+	m.Histogram.observe(value)
+	return nil
+}
+
+// SetSeriesLimit caps the number of label combinations of the metric name; new series
+// beyond it are dropped. Existing series are kept. A limit <= 0 removes the cap.
+func (r *Registry) SetSeriesLimit(name string, limit int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if limit <= 0 {
+		delete(r.limits, name)
+		return
+	}
+	r.limits[name] = limit
+}
+
+// SeriesCount returns the number of series of the metric name.
+func (r *Registry) SeriesCount(name string) int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.series[name]
+}
+
+// admitLocked reports whether the series key of name exists or may be created.
+func (r *Registry) admitLocked(key, name string) bool {
+	if _, ok := r.metrics[key]; ok {
+		return true
+	}
+	limit, ok := r.limits[name]
+	return !ok || r.series[name] < limit
+}
+
+func (r *Registry) storeLocked(key string, m *MetricValue) {
+	if _, ok := r.metrics[key]; !ok {
+		r.series[m.Name]++
+	}
+	r.metrics[key] = m
+}
+
+func (r *Registry) deleteLocked(key string) bool {
+	m, ok := r.metrics[key]
+	if !ok {
+		return false
+	}
+	delete(r.metrics, key)
+	if r.series[m.Name]--; r.series[m.Name] <= 0 {
+		delete(r.series, m.Name)
+	}
+	return true
 }
 
 // GetAll returns a snapshot copy of all metrics.
@@ -84,14 +212,50 @@ func (r *Registry) GetAll() []*MetricValue {
 	values := make([]*MetricValue, 0, len(r.metrics))
 	for _, v := range r.metrics {
 		cp := *v
+		if v.Histogram != nil {
+			cp.Histogram = v.Histogram.clone()
+		}
 		values = append(values, &cp)
 	}
 	return values
 }
 
-// Delete removes a metric by name.
+// Delete removes every series of a metric.
 func (r *Registry) Delete(name string) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	delete(r.metrics, name)
+	for key, m := range r.metrics {
+		if m.Name == name {
+			r.deleteLocked(key)
+		}
+	}
+}
+
+// DeleteSeries removes the series name{labels} and reports whether it existed.
+func (r *Registry) DeleteSeries(name string, labels map[string]string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.deleteLocked(SeriesKey(name, labels))
+}
+
+// SeriesKey identifies a series by its name and label set, independent of map order.
+func SeriesKey(name string, labels map[string]string) string {
+	if len(labels) == 0 {
+		return name
+	}
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(name)
+	for _, k := range keys {
+		b.WriteByte('\xff')
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(labels[k])
+	}
+	return b.String()
 }
@@ -58,6 +58,51 @@ func TestIncrementCounter(t *testing.T) {
 	}
 }
 
+func TestSetCounter(t *testing.T) {
+	r := NewRegistry()
+	r.SetCounter("gc_total", 3, nil)
+	r.SetCounter("gc_total", 5, nil)
+
+	metrics := r.GetAll()
+	if len(metrics) != 1 {
+		t.Fatalf("expected 1 metric, got %d", len(metrics))
+	}
+	if metrics[0].Value != 5 || metrics[0].Type != Counter {
+		t.Errorf("expected counter of 5, got %+v", metrics[0])
+	}
+}
+
+func TestSeriesIdentityIncludesLabels(t *testing.T) {
+	r := NewRegistry()
+	r.IncrementCounter("calls", 1, map[string]string{"function": "a"})
+	r.IncrementCounter("calls", 1, map[string]string{"function": "b"})
+	r.IncrementCounter("calls", 2, map[string]string{"function": "a"})
+
+	got := map[string]float64{}
+	for _, m := range r.GetAll() {
+		got[m.Labels["function"]] = m.Value
+	}
+	if len(got) != 2 || got["a"] != 3 || got["b"] != 1 {
+		t.Errorf("expected separate series a=3 b=1, got %v", got)
+	}
+
+	r.Delete("calls")
+	if n := len(r.GetAll()); n != 0 {
+		t.Errorf("expected Delete to remove every series, got %d left", n)
+	}
+}
+
+func TestSeriesKeyIgnoresLabelOrder(t *testing.T) {
+	a := SeriesKey("m", map[string]string{"x": "1", "y": "2"})
+	b := SeriesKey("m", map[string]string{"y": "2", "x": "1"})
+	if a != b {
+		t.Errorf("expected equal keys, got %q and %q", a, b)
+	}
+	if SeriesKey("m", nil) == SeriesKey("m", map[string]string{"x": ""}) {
+		t.Error("expected an empty label value to produce a distinct key")
+	}
+}
+
 func TestRecordHistogram(t *testing.T) {
 	r := NewRegistry()
 	r.RecordHistogram("latency", 0.123, nil)
@@ -82,6 +127,22 @@ func TestDelete(t *testing.T) {
 	}
 }
 
+func TestDeleteSeries(t *testing.T) {
+	r := NewRegistry()
+	r.SetGauge("cpu", 1, map[string]string{"core": "0"})
+	r.SetGauge("cpu", 2, map[string]string{"core": "1"})
+
+	if !r.DeleteSeries("cpu", map[string]string{"core": "0"}) {
+		t.Error("expected the series deleted")
+	}
+	if r.DeleteSeries("cpu", map[string]string{"core": "0"}) {
+		t.Error("expected a missing series reported")
+	}
+	if metrics := r.GetAll(); len(metrics) != 1 || metrics[0].Labels["core"] != "1" || r.SeriesCount("cpu") != 1 {
+		t.Fatalf("expected only the other series kept, got %+v", metrics)
+	}
+}
+
 func TestGetAllReturnsSnapshot(t *testing.T) {
 	r := NewRegistry()
 	r.SetGauge("cpu", 42, nil)
@@ -0,0 +1,115 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+)
+
+// DefaultMaxSeries caps the label combinations of a vector created with maxSeries <= 0.
+const DefaultMaxSeries = 1000
+
+// ErrCardinalityLimit is returned when recording would create a series beyond the
+// limit of its metric.
+var ErrCardinalityLimit = errors.New("registry: series limit reached")
+
+// vec holds what GaugeVec, CounterVec and HistogramVec share: a metric name, a fixed
+// set of label names, and the registry enforcing their series limit.
+type vec struct {
+	registry   *Registry
+	name       string
+	labelNames []string
+}
+
+func newVec(r *Registry, name string, labelNames []string, maxSeries int) vec {
+	if maxSeries <= 0 {
+		maxSeries = DefaultMaxSeries
+	}
+	r.SetSeriesLimit(name, maxSeries)
+	return vec{registry: r, name: name, labelNames: append([]string(nil), labelNames...)}
+}
+
+// labels pairs the label names with values given in the same order.
+func (v vec) labels(values []string) (map[string]string, error) {
+	if len(values) != len(v.labelNames) {
+		return nil, fmt.Errorf("registry: %s expects %d label values, got %d", v.name, len(v.labelNames), len(values))
+	}
+	labels := make(map[string]string, len(values))
+	for i, name := range v.labelNames {
+		labels[name] = values[i]
+	}
+	return labels, nil
+}
+
+// Delete removes the series with the given label values, freeing room under the limit.
+func (v vec) Delete(labelValues ...string) bool {
+	labels, err := v.labels(labelValues)
+	if err != nil {
+		return false
+	}
+	v.registry.mu.Lock()
+	defer v.registry.mu.Unlock()
+	return v.registry.deleteLocked(SeriesKey(v.name, labels))
+}
+
+// GaugeVec is a gauge broken down by a fixed set of labels.
+type GaugeVec struct{ vec }
+
+// NewGaugeVec returns a gauge keyed by labelNames with at most maxSeries label
+// combinations (DefaultMaxSeries when maxSeries <= 0).
+func (r *Registry) NewGaugeVec(name string, labelNames []string, maxSeries int) *GaugeVec {
+	return &GaugeVec{newVec(r, name, labelNames, maxSeries)}
+}
+
+// Set sets the gauge of the given label values.
+func (g *GaugeVec) Set(value float64, labelValues ...string) error {
+	labels, err := g.labels(labelValues)
+	if err != nil {
+		return err
+	}
+	return g.registry.setGauge(g.name, value, labels)
+}
+
+// CounterVec is a counter broken down by a fixed set of labels.
+type CounterVec struct{ vec }
+
+// NewCounterVec returns a counter keyed by labelNames with at most maxSeries label
+// combinations (DefaultMaxSeries when maxSeries <= 0).
+func (r *Registry) NewCounterVec(name string, labelNames []string, maxSeries int) *CounterVec {
+	return &CounterVec{newVec(r, name, labelNames, maxSeries)}
+}
+
+// Add increments the counter of the given label values by delta.
+func (c *CounterVec) Add(delta float64, labelValues ...string) error {
+	if delta < 0 {
+		return fmt.Errorf("registry: counter %s cannot decrease", c.name)
+	}
+	labels, err := c.labels(labelValues)
+	if err != nil {
+		return err
+	}
+	return c.registry.incrementCounter(c.name, delta, labels)
+}
+
+// Inc increments the counter of the given label values by one.
+func (c *CounterVec) Inc(labelValues ...string) error {
+	return c.Add(1, labelValues...)
+}
+
+// HistogramVec is a histogram broken down by a fixed set of labels.
+type HistogramVec struct{ vec }
+
+// NewHistogramVec returns a histogram with the given buckets, keyed by labelNames with
+// at most maxSeries label combinations (DefaultMaxSeries when maxSeries <= 0).
+func (r *Registry) NewHistogramVec(name string, opts HistogramOpts, labelNames []string, maxSeries int) *HistogramVec {
+	r.ConfigureHistogram(name, opts)
+	return &HistogramVec{newVec(r, name, labelNames, maxSeries)}
+}
+
+// Observe records value in the histogram of the given label values.
+func (h *HistogramVec) Observe(value float64, labelValues ...string) error {
+	labels, err := h.labels(labelValues)
+	if err != nil {
+		return err
+	}
+	return h.registry.recordHistogram(h.name, value, labels)
+}
@@ -0,0 +1,84 @@
+package registry
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGaugeVec(t *testing.T) {
+	r := NewRegistry()
+	g := r.NewGaugeVec("http_requests_in_flight", []string{"route"}, 0)
+
+	if err := g.Set(1, "/a"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := g.Set(2, "/b"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := g.Set(3, "/a", "extra"); err == nil {
+		t.Error("expected an error for the wrong number of label values")
+	}
+
+	got := map[string]float64{}
+	for _, m := range r.GetAll() {
+		got[m.Labels["route"]] = m.Value
+	}
+	if len(got) != 2 || got["/a"] != 1 || got["/b"] != 2 {
+		t.Errorf("expected a series per route, got %v", got)
+	}
+}
+
+func TestCounterVecCardinalityLimit(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounterVec("http_requests_total", []string{"method", "route"}, 2)
+
+	for _, route := range []string{"/a", "/b"} {
+		if err := c.Inc("GET", route); err != nil {
+			t.Fatalf("Inc() error = %v", err)
+		}
+	}
+	if err := c.Inc("GET", "/c"); !errors.Is(err, ErrCardinalityLimit) {
+		t.Fatalf("expected ErrCardinalityLimit, got %v", err)
+	}
+	// Existing series keep counting at the limit.
+	if err := c.Add(2, "GET", "/a"); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := c.Add(-1, "GET", "/a"); err == nil {
+		t.Error("expected an error for a negative delta")
+	}
+	// The plain API is capped too.
+	r.IncrementCounter("http_requests_total", 1, map[string]string{"method": "POST", "route": "/a"})
+	if n := r.SeriesCount("http_requests_total"); n != 2 {
+		t.Fatalf("expected 2 series, got %d", n)
+	}
+
+	if !c.Delete("GET", "/b") {
+		t.Fatal("expected Delete to remove the series")
+	}
+	if err := c.Inc("GET", "/c"); err != nil {
+		t.Errorf("expected room after Delete, got %v", err)
+	}
+
+	for _, m := range r.GetAll() {
+		if m.Labels["route"] == "/a" && m.Value != 3 {
+			t.Errorf("expected /a to count 3, got %v", m.Value)
+		}
+	}
+}
+
+func TestHistogramVec(t *testing.T) {
+	r := NewRegistry()
+	h := r.NewHistogramVec("latency", HistogramOpts{Buckets: []float64{1}}, []string{"route"}, 1)
+
+	if err := h.Observe(0.5, "/a"); err != nil {
+		t.Fatalf("Observe() error = %v", err)
+	}
+	if err := h.Observe(0.5, "/b"); !errors.Is(err, ErrCardinalityLimit) {
+		t.Errorf("expected ErrCardinalityLimit, got %v", err)
+	}
+	m := r.GetAll()[0]
+	if m.Histogram.Count != 1 || len(m.Histogram.Bounds) != 1 {
+		t.Errorf("unexpected histogram %+v", m.Histogram)
+	}
+}
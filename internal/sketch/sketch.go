@@ -0,0 +1,136 @@
+// Package sketch implements a streaming quantile sketch with bounded relative error.
+package sketch
+
+import (
+	"math"
+	"sort"
+)
+
+// DefaultRelativeAccuracy bounds the relative error of quantiles estimated by New(0).
+const DefaultRelativeAccuracy = 0.01
+
+// minIndexable is the smallest value given its own bucket; smaller values, zero and
+// negatives included, are counted together as zero.
+const minIndexable = 1e-9
+
+// Sketch estimates quantiles of positive values in the manner of DDSketch: bucket i holds
+// the values in (gamma^(i-1), gamma^i], so any quantile is returned within the relative
+// accuracy of the true value. Sketches of the same accuracy can be merged. A Sketch is
+// not safe for concurrent use.
+type Sketch struct {
+	gamma    float64
+	logGamma float64
+	bins     map[int]uint64
+	zero     uint64
+
+	count uint64
+	sum   float64
+	min   float64
+	max   float64
+}
+
+// New returns an empty sketch with the given relative accuracy, in (0, 1);
+// DefaultRelativeAccuracy when out of range.
+func New(relativeAccuracy float64) *Sketch {
+	if relativeAccuracy <= 0 || relativeAccuracy >= 1 {
+		relativeAccuracy = DefaultRelativeAccuracy
+	}
+	gamma := (1 + relativeAccuracy) / (1 - relativeAccuracy)
+	return &Sketch{gamma: gamma, logGamma: math.Log(gamma), bins: make(map[int]uint64)}
+}
+
+// Add records v. NaN is ignored.
+func (s *Sketch) Add(v float64) {
+	if math.IsNaN(v) {
+		return
+	}
+	if s.count == 0 || v < s.min {
+		s.min = v
+	}
+	if s.count == 0 || v > s.max {
+		s.max = v
+	}
+	s.count++
+	s.sum += v
+
+	if v < minIndexable {
+		s.zero++
+		return
+	}
+	s.bins[int(math.Ceil(math.Log(v)/s.logGamma))]++
+}
+
+// Merge adds the values recorded in o, which must have the same relative accuracy.
+func (s *Sketch) Merge(o *Sketch) {
+	if o == nil || o.count == 0 {
+		return
+	}
+	if s.count == 0 || o.min < s.min {
+		s.min = o.min
+	}
+	if s.count == 0 || o.max > s.max {
+		s.max = o.max
+	}
+	s.count += o.count
+	s.sum += o.sum
+	s.zero += o.zero
+	for i, n := range o.bins {
+		s.bins[i] += n
+	}
+}
+
+// Reset empties the sketch, keeping its accuracy.
+func (s *Sketch) Reset() {
+	clear(s.bins)
+	s.zero, s.count, s.sum, s.min, s.max = 0, 0, 0, 0, 0
+}
+
+// Quantile returns the estimated q-quantile, q in [0, 1], or 0 for an empty sketch.
+func (s *Sketch) Quantile(q float64) float64 {
+	if s.count == 0 {
+		return 0
+	}
+	q = max(0, min(1, q))
+	rank := uint64(q * float64(s.count-1))
+
+	if rank < s.zero {
+		return max(s.min, min(0, s.max))
+	}
+	indexes := make([]int, 0, len(s.bins))
+	for i := range s.bins {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+
+	seen := s.zero
+	for _, i := range indexes {
+		seen += s.bins[i]
+		if seen > rank {
+			// The midpoint of the bucket, in relative terms, is within the accuracy of
+			// every value in it.
+			v := 2 * math.Pow(s.gamma, float64(i)) / (s.gamma + 1)
+			return max(s.min, min(s.max, v))
+		}
+	}
+	return s.max
+}
+
+// Count returns the number of recorded values.
+func (s *Sketch) Count() uint64 { return s.count }
+
+// Sum returns the sum of the recorded values.
+func (s *Sketch) Sum() float64 { return s.sum }
+
+// Min returns the smallest recorded value, or 0 for an empty sketch.
+func (s *Sketch) Min() float64 { return s.min }
+
+// Max returns the largest recorded value, or 0 for an empty sketch.
+func (s *Sketch) Max() float64 { return s.max }
+
+// Mean returns the mean of the recorded values, or 0 for an empty sketch.
+func (s *Sketch) Mean() float64 {
+	if s.count == 0 {
+		return 0
+	}
+	return s.sum / float64(s.count)
+}
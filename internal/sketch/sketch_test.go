@@ -0,0 +1,78 @@
+package sketch
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestSketchQuantiles(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	s := New(0.01)
+	values := make([]float64, 10000)
+	for i := range values {
+		values[i] = math.Exp(rng.NormFloat64()*2 - 5) // log-normal latencies around 7ms
+		s.Add(values[i])
+	}
+	sort.Float64s(values)
+
+	for _, q := range []float64{0, 0.5, 0.9, 0.99, 1} {
+		want := values[int(q*float64(len(values)-1))]
+		got := s.Quantile(q)
+		if math.Abs(got-want)/want > 0.01 {
+			t.Errorf("q%v: expected %v within 1%%, got %v", q, want, got)
+		}
+	}
+	if s.Count() != 10000 || s.Min() != values[0] || s.Max() != values[len(values)-1] {
+		t.Errorf("expected count, min and max of the values, got %d %v %v", s.Count(), s.Min(), s.Max())
+	}
+}
+
+func TestSketchMerge(t *testing.T) {
+	a, b, all := New(0), New(0), New(0)
+	for i := 1; i <= 100; i++ {
+		v := float64(i)
+		all.Add(v)
+		if i%2 == 0 {
+			a.Add(v)
+		} else {
+			b.Add(v)
+		}
+	}
+	a.Merge(b)
+
+	if a.Count() != all.Count() || a.Sum() != all.Sum() || a.Min() != 1 || a.Max() != 100 {
+		t.Errorf("expected merged totals of all values, got count=%d sum=%v min=%v max=%v", a.Count(), a.Sum(), a.Min(), a.Max())
+	}
+	for _, q := range []float64{0.5, 0.9, 0.99} {
+		if a.Quantile(q) != all.Quantile(q) {
+			t.Errorf("q%v: expected %v, got %v", q, all.Quantile(q), a.Quantile(q))
+		}
+	}
+}
+
+func TestSketchZeroAndEmpty(t *testing.T) {
+	s := New(0)
+	if s.Quantile(0.5) != 0 || s.Mean() != 0 {
+		t.Error("expected zero quantile and mean of an empty sketch")
+	}
+	s.Add(0)
+	s.Add(0)
+	s.Add(math.NaN())
+	s.Add(2)
+	if s.Count() != 3 {
+		t.Errorf("expected NaN to be ignored, got count %d", s.Count())
+	}
+	if got := s.Quantile(0.5); got != 0 {
+		t.Errorf("expected a median of 0, got %v", got)
+	}
+	if got := s.Quantile(1); math.Abs(got-2)/2 > 0.01 {
+		t.Errorf("expected a maximum of 2, got %v", got)
+	}
+
+	s.Reset()
+	if s.Count() != 0 || s.Quantile(0.99) != 0 {
+		t.Error("expected an empty sketch after Reset")
+	}
+}
@@ -0,0 +1,131 @@
+package monigo
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/iyashjayesh/monigo/core"
+	"github.com/iyashjayesh/monigo/exporters"
+	"github.com/iyashjayesh/monigo/internal/logger"
+	"github.com/iyashjayesh/monigo/internal/registry"
+)
+
+// customMetricKinds holds the type and label names of every custom metric, by exported
+// name: Prometheus can't export one name with two of them.
+var (
+	customMetricKindsMu sync.Mutex
+	customMetricKinds   = make(map[string]string)
+)
+
+// Counter is a custom metric that only goes up, such as orders placed.
+type Counter struct {
+	name   string
+	labels map[string]string
+	valid  bool
+}
+
+// Gauge is a custom metric that can go up and down, such as queue depth.
+type Gauge struct {
+	name   string
+	labels map[string]string
+	valid  bool
+}
+
+// Histogram is a custom metric recording the distribution of observations,
+// such as payment amounts or job durations.
+type Histogram struct {
+	name   string
+	labels map[string]string
+	valid  bool
+}
+
+// NewCounter returns the counter series name{labels}. Custom metrics are stored with the
+// system metrics, shown on the dashboard and exported through /metrics and OTel.
+// A metric keeps at most registry.DefaultMaxSeries label combinations; observations
+// of further combinations are dropped.
+//
+// Every series of a metric must have the same type and label names, and names starting
+// with "monigo_" are reserved; other series are logged and record nothing.
+func NewCounter(name string, labels map[string]string) *Counter {
+	labels, valid := customSeries(name, registry.Counter, labels)
+	return &Counter{name: name, labels: labels, valid: valid}
+}
+
+// NewGauge returns the gauge series name{labels}.
+func NewGauge(name string, labels map[string]string) *Gauge {
+	labels, valid := customSeries(name, registry.Gauge, labels)
+	return &Gauge{name: name, labels: labels, valid: valid}
+}
+
+// NewHistogram returns the histogram series name{labels}. Buckets are the upper bounds used
+// when the metric records its first observation; registry.DefaultBuckets when omitted.
+func NewHistogram(name string, labels map[string]string, buckets ...float64) *Histogram {
+	labels, valid := customSeries(name, registry.Histogram, labels)
+	if valid && len(buckets) > 0 {
+		core.CustomMetrics().ConfigureHistogram(name, registry.HistogramOpts{Buckets: buckets})
+	}
+	return &Histogram{name: name, labels: labels, valid: valid}
+}
+
+// customSeries applies the series limit of name and copies labels so later changes
+// by the caller don't move the series. It reports false for a series that can't be
+// exported next to the others of its name.
+func customSeries(name string, t registry.MetricType, labels map[string]string) (map[string]string, bool) {
+	exported := exporters.PrometheusName(name)
+	if strings.HasPrefix(exported, "monigo_") {
+		logger.Log.Warn("custom metric names starting with monigo_ are reserved, ignoring", "metric", name)
+		return nil, false
+	}
+
+	kind := t.String() + "{" + strings.Join(slices.Sorted(maps.Keys(labels)), ",") + "}"
+	customMetricKindsMu.Lock()
+	first, ok := customMetricKinds[exported]
+	if !ok {
+		first = kind
+		customMetricKinds[exported] = kind
+	}
+	customMetricKindsMu.Unlock()
+	if kind != first {
+		logger.Log.Warn("custom metric already has another type or label names, ignoring",
+			"metric", name, "expected", first, "got", kind)
+		return nil, false
+	}
+
+	core.CustomMetrics().SetSeriesLimit(name, registry.DefaultMaxSeries)
+	return maps.Clone(labels), true
+}
+
+// Inc increments the counter by one.
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Add increments the counter by delta. Negative deltas are ignored.
+func (c *Counter) Add(delta float64) {
+	if !c.valid {
+		return
+	}
+	if delta < 0 {
+		logger.Log.Warn("counter cannot decrease, ignoring", "metric", c.name, "delta", delta)
+		return
+	}
+	core.CustomMetrics().IncrementCounter(c.name, delta, c.labels)
+}
+
+// Set sets the gauge to value.
+func (g *Gauge) Set(value float64) {
+	if !g.valid {
+		return
+	}
+	core.CustomMetrics().SetGauge(g.name, value, g.labels)
+}
+
+// Observe records value in the histogram.
+func (h *Histogram) Observe(value float64) {
+	if !h.valid {
+		return
+	}
+	core.CustomMetrics().RecordHistogram(h.name, value, h.labels)
+}
@@ -0,0 +1,75 @@
+package monigo
+
+import (
+	"testing"
+
+	"github.com/iyashjayesh/monigo/core"
+	"github.com/iyashjayesh/monigo/internal/registry"
+)
+
+func customMetric(t *testing.T, name string, labels map[string]string) *registry.MetricValue {
+	t.Helper()
+	key := registry.SeriesKey(name, labels)
+	for _, m := range core.CustomMetrics().GetAll() {
+		if registry.SeriesKey(m.Name, m.Labels) == key {
+			return m
+		}
+	}
+	t.Fatalf("series %s%v not recorded", name, labels)
+	return nil
+}
+
+func TestCustomMetrics(t *testing.T) {
+	defer core.CustomMetrics().Delete("test_orders_total")
+	defer core.CustomMetrics().Delete("test_queue_depth")
+	defer core.CustomMetrics().Delete("test_payment_amount")
+
+	labels := map[string]string{"region": "eu"}
+	orders := NewCounter("test_orders_total", labels)
+	labels["region"] = "us" // the series keeps the labels it was created with
+	orders.Inc()
+	orders.Add(2)
+	orders.Add(-1)
+	if got := customMetric(t, "test_orders_total", map[string]string{"region": "eu"}); got.Type != registry.Counter || got.Value != 3 {
+		t.Errorf("expected counter of 3, got %+v", got)
+	}
+
+	depth := NewGauge("test_queue_depth", nil)
+	depth.Set(5)
+	depth.Set(2)
+	if got := customMetric(t, "test_queue_depth", nil); got.Type != registry.Gauge || got.Value != 2 {
+		t.Errorf("expected gauge of 2, got %+v", got)
+	}
+
+	amount := NewHistogram("test_payment_amount", nil, 10, 100)
+	amount.Observe(5)
+	amount.Observe(50)
+	amount.Observe(500)
+	got := customMetric(t, "test_payment_amount", nil)
+	if got.Type != registry.Histogram || got.Histogram.Count != 3 || got.Histogram.Sum != 555 {
+		t.Fatalf("unexpected histogram %+v", got)
+	}
+	bounds, counts := got.Histogram.CumulativeBuckets()
+	if len(bounds) != 2 || bounds[1] != 100 || counts[0] != 1 || counts[1] != 2 {
+		t.Errorf("expected buckets [10 100] with counts [1 2], got %v %v", bounds, counts)
+	}
+}
+
+func TestCustomMetricsRejectInconsistentSeries(t *testing.T) {
+	defer core.CustomMetrics().Delete("test_jobs_total")
+
+	NewCounter("test_jobs_total", map[string]string{"queue": "emails"}).Inc()
+	NewCounter("test_jobs_total", map[string]string{"queue": "sms", "region": "eu"}).Inc()
+	NewGauge("test_jobs_total", map[string]string{"queue": "push"}).Set(1)
+	NewGauge("monigo_cpu_cores", nil).Set(1)
+
+	for _, mv := range core.CustomMetrics().GetAll() {
+		switch {
+		case mv.Name == "monigo_cpu_cores":
+			t.Error("expected the reserved name rejected")
+		case mv.Name == "test_jobs_total" && mv.Labels["queue"] != "emails":
+			t.Errorf("expected only the first label names and type kept, got %+v", mv)
+		}
+	}
+	customMetric(t, "test_jobs_total", map[string]string{"queue": "emails"})
+}
@@ -10,6 +10,7 @@ type ServiceStats struct {
 	LoadStatistics   LoadStatistics   `json:"load_statistics"`   // Load Statistics
 	CPUStatistics    CPUStatistics    `json:"cpu_statistics"`    // CPU Statistics
 	MemoryStatistics MemoryStatistics `json:"memory_statistics"` // Memory Statistics
+	SampledAt        time.Time        `json:"sampled_at"`        // When the statistics were collected
 
 	// Additional Metrics
 	HeapAllocByService  string `json:"heap_alloc_by_service"`
@@ -136,6 +137,10 @@ type FunctionTraceDetails struct {
 	FunctionName      string   `json:"function_name"`
 	CoreProfile       Profiles `json:"core_profile"`
 	FunctionCodeTrace string   `json:"function_code_trace"`
+
+	// The profiles rendered as data; nil when a profile is missing or unreadable.
+	CPUReport *ProfileReport `json:"cpu_report,omitempty"`
+	MemReport *ProfileReport `json:"mem_report,omitempty"`
 }
 
 // Profiles represents the profiles.
@@ -144,12 +149,176 @@ type Profiles struct {
 	Mem string `json:"mem_profile"`
 }
 
+// ProfileReport is a pprof profile rendered in-process. Values are in Unit, such as
+// nanoseconds of CPU or bytes in use.
+type ProfileReport struct {
+	SampleType string        `json:"sample_type"`
+	Unit       string        `json:"unit"`
+	Total      int64         `json:"total"`
+	Duration   time.Duration `json:"duration"` // of the profile; zero for heap profiles
+	Functions  int           `json:"functions"`
+
+	Top        []ProfileEntry    `json:"top"`        // functions by flat value
+	Cumulative []ProfileEntry    `json:"cumulative"` // functions by cumulative value
+	Listing    []FunctionListing `json:"listing,omitempty"`
+	Graph      ProfileGraph      `json:"graph"`
+}
+
+// ProfileEntry is the share of a function in a profile: Flat in the function itself,
+// Cum including the functions it calls.
+type ProfileEntry struct {
+	Function    string  `json:"function"`
+	File        string  `json:"file,omitempty"`
+	Flat        int64   `json:"flat"`
+	FlatPercent float64 `json:"flat_percent"`
+	Cum         int64   `json:"cum"`
+	CumPercent  float64 `json:"cum_percent"`
+}
+
+// FunctionListing attributes the samples of a function to its source lines.
+type FunctionListing struct {
+	Function string        `json:"function"`
+	File     string        `json:"file"`
+	Flat     int64         `json:"flat"`
+	Cum      int64         `json:"cum"`
+	Lines    []ListingLine `json:"lines"`
+}
+
+// ListingLine is a source line of a listed function. Source is empty when the file isn't
+// available where monigo runs.
+type ListingLine struct {
+	Line   int64  `json:"line"`
+	Flat   int64  `json:"flat"`
+	Cum    int64  `json:"cum"`
+	Source string `json:"source,omitempty"`
+}
+
+// ProfileGraph is the call graph between the functions with the largest cumulative values.
+type ProfileGraph struct {
+	Nodes []ProfileEntry `json:"nodes"`
+	Edges []ProfileEdge  `json:"edges"`
+}
+
+// ProfileEdge is the value of the samples where Caller calls Callee.
+type ProfileEdge struct {
+	Caller string `json:"caller"`
+	Callee string `json:"callee"`
+	Value  int64  `json:"value"`
+}
+
+// FlameNode is a frame of a flame graph in the JSON of d3-flame-graph. Value includes
+// the frames it calls.
+type FlameNode struct {
+	Name     string       `json:"name"`
+	Value    int64        `json:"value"`
+	Children []*FlameNode `json:"children,omitempty"`
+}
+
+// SpeedscopeFile is a profile in the file format of speedscope,
+// https://www.speedscope.app/file-format-schema.json.
+type SpeedscopeFile struct {
+	Schema             string              `json:"$schema"`
+	Name               string              `json:"name"`
+	Exporter           string              `json:"exporter"`
+	ActiveProfileIndex int                 `json:"activeProfileIndex"`
+	Shared             SpeedscopeShared    `json:"shared"`
+	Profiles           []SpeedscopeProfile `json:"profiles"`
+}
+
+// SpeedscopeShared holds the frames the samples of every profile refer to.
+type SpeedscopeShared struct {
+	Frames []SpeedscopeFrame `json:"frames"`
+}
+
+// SpeedscopeFrame is a function of a speedscope profile.
+type SpeedscopeFrame struct {
+	Name string `json:"name"`
+	File string `json:"file,omitempty"`
+	Line int64  `json:"line,omitempty"`
+}
+
+// SpeedscopeProfile is a sampled speedscope profile: Samples are stacks of indices in
+// the shared frames, root first, weighted by Weights.
+type SpeedscopeProfile struct {
+	Type       string  `json:"type"`
+	Name       string  `json:"name"`
+	Unit       string  `json:"unit"`
+	StartValue int64   `json:"startValue"`
+	EndValue   int64   `json:"endValue"`
+	Samples    [][]int `json:"samples"`
+	Weights    []int64 `json:"weights"`
+}
+
 // FunctionMetrics represents the function metrics.
 type FunctionMetrics struct {
+	// The last call; memory and profiles are those of the last profiled call.
 	FunctionLastRanAt  time.Time     `json:"function_last_ran_at"`
 	CPUProfileFilePath string        `json:"cpu_profile_file_path"`
 	MemProfileFilePath string        `json:"mem_profile_file_path"`
 	MemoryUsage        uint64        `json:"memory_usage"`
 	GoroutineCount     int           `json:"goroutine_count"`
 	ExecutionTime      time.Duration `json:"execution_time"`
+
+	// Cumulative statistics over every traced call, not only the sampled ones.
+	CallCount            uint64        `json:"call_count"`
+	TotalExecutionTime   time.Duration `json:"total_execution_time"`
+	ExecutionTimeBuckets []uint64      `json:"execution_time_buckets"` // cumulative counts per core.FunctionDurationBuckets bound
+
+	// Failed calls: those returning a non-nil trailing error or panicking.
+	ErrorCount     uint64  `json:"error_count"`
+	PanicCount     uint64  `json:"panic_count"`
+	ErrorRate      float64 `json:"error_rate"` // ErrorCount / CallCount
+	LastError      string  `json:"last_error,omitempty"`
+	LastPanicStack string  `json:"last_panic_stack,omitempty"`
+
+	// Aggregates over every call; quantiles are estimated within 1%.
+	MinExecutionTime  time.Duration `json:"min_execution_time"`
+	MaxExecutionTime  time.Duration `json:"max_execution_time"`
+	MeanExecutionTime time.Duration `json:"mean_execution_time"`
+	P50ExecutionTime  time.Duration `json:"p50_execution_time"`
+	P90ExecutionTime  time.Duration `json:"p90_execution_time"`
+	P99ExecutionTime  time.Duration `json:"p99_execution_time"`
+	MaxGoroutineCount int           `json:"max_goroutine_count"`
+	MeanMemoryUsage   uint64        `json:"mean_memory_usage"` // over profiled calls
+
+	// Window covers the calls of the last core.FunctionStatsWindow.
+	Window FunctionWindowStats `json:"window"`
+}
+
+// ProfileRecord describes the profiles kept for one sampled call of a traced function.
+type ProfileRecord struct {
+	ID                 string        `json:"id"`
+	FunctionName       string        `json:"function_name"`
+	CapturedAt         time.Time     `json:"captured_at"`
+	CPUProfileFilePath string        `json:"cpu_profile_file_path,omitempty"` // empty when another profile covered the call
+	MemProfileFilePath string        `json:"mem_profile_file_path"`
+	SizeBytes          int64         `json:"size_bytes"`
+	ExecutionTime      time.Duration `json:"execution_time"`
+	MemoryUsage        uint64        `json:"memory_usage"`
+	GoroutineCount     int           `json:"goroutine_count"`
+	Error              string        `json:"error,omitempty"`
+	Panicked           bool          `json:"panicked,omitempty"`
+}
+
+// ProcessProfileRecord describes a profile of the whole process captured on demand.
+type ProcessProfileRecord struct {
+	ID         string        `json:"id"`
+	Kind       string        `json:"kind"` // cpu, heap, allocs, goroutine, block, mutex or trace
+	CapturedAt time.Time     `json:"captured_at"`
+	Duration   time.Duration `json:"duration,omitempty"` // zero for snapshots
+	FilePath   string        `json:"file_path"`
+	SizeBytes  int64         `json:"size_bytes"`
+}
+
+// FunctionWindowStats summarises the calls of a traced function in a rolling window.
+type FunctionWindowStats struct {
+	Duration          time.Duration `json:"duration"`
+	CallCount         uint64        `json:"call_count"`
+	ErrorCount        uint64        `json:"error_count"`
+	ErrorRate         float64       `json:"error_rate"`
+	MeanExecutionTime time.Duration `json:"mean_execution_time"`
+	MaxExecutionTime  time.Duration `json:"max_execution_time"`
+	P50ExecutionTime  time.Duration `json:"p50_execution_time"`
+	P90ExecutionTime  time.Duration `json:"p90_execution_time"`
+	P99ExecutionTime  time.Duration `json:"p99_execution_time"`
 }
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"math"
+	"strconv"
 	"time"
 )
 
@@ -21,9 +23,12 @@ type ServiceHealthThresholds struct {
 
 // FetchDataPoints is the struct to fetch the data points from the storage
 type FetchDataPoints struct {
-	FieldName []string `json:"field_name"`
-	StartTime string   `json:"start_time"` // "2006-01-02T15:04:05Z07:00"
-	EndTime   string   `json:"end_time"`   // "2006-01-02T15:04:05Z07:00"
+	FieldName   []string `json:"field_name"`
+	StartTime   string   `json:"start_time"`            // "2006-01-02T15:04:05Z07:00"
+	EndTime     string   `json:"end_time"`              // "2006-01-02T15:04:05Z07:00"
+	TimeFrame   string   `json:"time_frame,omitempty"`  // e.g. "1h", "7d"; derives a step when Step is empty
+	Step        string   `json:"step,omitempty"`        // bucket size, e.g. "1m"; empty with no TimeFrame returns raw points
+	Aggregation string   `json:"aggregation,omitempty"` // avg (default), min, max, sum, last, p50, p95, p99, rate
 }
 
 // DataPointsInfo is the struct to store the data points information
@@ -34,10 +39,61 @@ type DataPointsInfo struct {
 
 // ReportsRequest is the struct to store the reports request
 type ReportsRequest struct {
-	Topic     string `json:"topic"`
-	StartTime string `json:"start_time"` // "2006-01-02T15:04:05Z07:00"
-	EndTime   string `json:"end_time"`   // "2006-01-02T15:04:05Z07:00"
-	TimeFrame string `json:"time_frame"`
+	Topic       string `json:"topic"`
+	StartTime   string `json:"start_time"` // "2006-01-02T15:04:05Z07:00"
+	EndTime     string `json:"end_time"`   // "2006-01-02T15:04:05Z07:00"
+	TimeFrame   string `json:"time_frame"`
+	Step        string `json:"step,omitempty"`
+	Aggregation string `json:"aggregation,omitempty"`
+}
+
+// QueryRequest is the struct to store an expression query request.
+// Start and End accept RFC3339 or Unix seconds; Step accepts a duration ("30s") or seconds.
+type QueryRequest struct {
+	Query string `json:"query"`
+	Start string `json:"start,omitempty"` // defaults to one hour before End
+	End   string `json:"end,omitempty"`   // defaults to now
+	Step  string `json:"step,omitempty"`  // defaults to a step yielding ~300 points
+}
+
+// QueryResponse is the Prometheus-compatible envelope returned by the query endpoint
+type QueryResponse struct {
+	Status    string     `json:"status"` // "success" or "error"
+	Data      *QueryData `json:"data,omitempty"`
+	ErrorType string     `json:"errorType,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// QueryData holds the result of a range query
+type QueryData struct {
+	ResultType string        `json:"resultType"` // always "matrix"
+	Result     []QuerySeries `json:"result"`
+}
+
+// QuerySeries is one series of a matrix result
+type QuerySeries struct {
+	Metric map[string]string `json:"metric"`
+	Values []QuerySample     `json:"values"`
+}
+
+// QuerySample is a single value, encoded as [<unix seconds>, "<value>"] like Prometheus
+type QuerySample struct {
+	Timestamp int64
+	Value     float64
+}
+
+// MarshalJSON encodes the sample as a [timestamp, "value"] pair
+func (s QuerySample) MarshalJSON() ([]byte, error) {
+	var value string
+	switch {
+	case math.IsInf(s.Value, 1):
+		value = "+Inf"
+	case math.IsInf(s.Value, -1):
+		value = "-Inf"
+	default:
+		value = strconv.FormatFloat(s.Value, 'f', -1, 64) // NaN formats as "NaN"
+	}
+	return []byte("[" + strconv.FormatInt(s.Timestamp, 10) + "," + strconv.Quote(value) + "]"), nil
 }
 
 // SystemHealthInPercent is the struct to store the system health in percentage
@@ -51,3 +107,15 @@ type HealthFields struct {
 	AllowedByUser float64 `json:"allowed_by_user"`
 	Message       string  `json:"message"`
 }
+
+// CustomMetric is a series recorded through the Monigo Counter, Gauge and Histogram API.
+type CustomMetric struct {
+	Name      string            `json:"name"`
+	Type      string            `json:"type"` // counter, gauge or histogram
+	Labels    map[string]string `json:"labels"`
+	Value     float64           `json:"value"`           // last observation for histograms
+	Count     uint64            `json:"count,omitempty"` // histograms only
+	Sum       float64           `json:"sum,omitempty"`   // histograms only
+	UpdatedAt time.Time         `json:"updated_at"`
+	Query     string            `json:"query"` // expression charting the stored history through the query endpoint
+}
@@ -21,7 +21,10 @@ import (
 	"github.com/iyashjayesh/monigo/common"
 	"github.com/iyashjayesh/monigo/core"
 	"github.com/iyashjayesh/monigo/exporters"
+	"github.com/iyashjayesh/monigo/internal/exporter"
 	"github.com/iyashjayesh/monigo/internal/logger"
+	"github.com/iyashjayesh/monigo/internal/pipeline"
+	"github.com/iyashjayesh/monigo/internal/registry"
 	"github.com/iyashjayesh/monigo/models"
 	"github.com/iyashjayesh/monigo/timeseries"
 )
@@ -69,18 +72,49 @@ type Monigo struct {
 	Headless                bool      `json:"headless"`
 	SamplingRate            int       `json:"sampling_rate"`
 	StorageType             string    `json:"storage_type"`
+	StatsSampleInterval     string    `json:"stats_sample_interval,omitempty"`
+
+	// In-memory storage limits (only used with StorageType "memory"); zero means unbounded.
+	MemoryMaxPointsPerSeries int   `json:"memory_max_points_per_series,omitempty"`
+	MemoryMaxBytes           int64 `json:"memory_max_bytes,omitempty"`
+
+	// Profile store limits: profiled calls kept per function and total disk budget;
+	// zero selects core.DefaultMaxProfilesPerFunction and core.DefaultMaxProfileStoreBytes.
+	MaxProfilesPerFunction int   `json:"max_profiles_per_function,omitempty"`
+	MaxProfileStorageBytes int64 `json:"max_profile_storage_bytes,omitempty"`
+
+	// ContinuousProfileCycle enables continuous CPU profiling in cycles of this length,
+	// such as "10s", one a minute by default; function CPU reports then filter its
+	// samples by label.
+	ContinuousProfileCycle string `json:"continuous_profile_cycle,omitempty"`
+
+	// ContinuousProfileInterval starts a continuous cycle this often, such as "5m", instead
+	// of once a minute; no longer than the cycle runs them back to back. It enables
+	// continuous profiling on its own too.
+	ContinuousProfileInterval string `json:"continuous_profile_interval,omitempty"`
 
 	// OpenTelemetry Configuration
 	OTelEndpoint string            `json:"otel_endpoint,omitempty"`
 	OTelHeaders  map[string]string `json:"-"`
+	// OTelOptions selects the transport, TLS and intervals; its Endpoint and Headers
+	// default to the fields above. Without it OTel uses plaintext gRPC.
+	OTelOptions *exporters.OTelConfig `json:"-"`
+
+	// Prometheus remote write configuration (e.g. Mimir, Cortex, VictoriaMetrics)
+	RemoteWriteURL     string            `json:"remote_write_url,omitempty"`
+	RemoteWriteHeaders map[string]string `json:"-"`
 
 	// Security and Middleware Configuration
 	DashboardMiddleware []func(http.Handler) http.Handler `json:"-"`
 	APIMiddleware       []func(http.Handler) http.Handler `json:"-"`
 	AuthFunction        func(*http.Request) bool          `json:"-"`
 
-	// Holds a reference so we can shut down cleanly.
-	otelExporter *exporters.OTelExporter
+	// Holds references so we can shut down cleanly.
+	otelExporter        *exporters.OTelExporter
+	otelTraceExporter   *exporters.OTelTraceExporter
+	remoteWriteExporter *exporters.RemoteWriteExporter
+	systemMetrics       *registry.Registry   // service stats and traced functions, fed to the exporters
+	pipelines           []*pipeline.Pipeline // flushed and stopped by Shutdown
 }
 
 // MonigoInt is the interface to start the monigo service
@@ -182,10 +216,6 @@ func (m *Monigo) setup() error {
 		return fmt.Errorf("[MoniGo] service_name is required, please provide the service name")
 	}
 
-	if err := timeseries.SetDataPointsSyncFrequency(m.DataPointsSyncFrequency); err != nil {
-		return fmt.Errorf("[MoniGo] failed to set data points sync frequency: %v", err)
-	}
-
 	m.ProcessId = common.GetProcessId()
 	m.GoVersion = runtime.Version()
 
@@ -214,9 +244,12 @@ func (m *Monigo) setup() error {
 		m.DataRetentionPeriod,
 	)
 
+	// Storage type and limits must be set before the storage is first initialized.
 	if m.StorageType != "" {
 		timeseries.SetStorageType(m.StorageType)
 	}
+	timeseries.SetMemoryLimits(m.MemoryMaxPointsPerSeries, m.MemoryMaxBytes)
+	core.SetProfileStoreLimits(m.MaxProfilesPerFunction, m.MaxProfileStorageBytes)
 	if m.SamplingRate > 0 {
 		core.SetSamplingRate(m.SamplingRate)
 	}
@@ -227,31 +260,162 @@ func (m *Monigo) setup() error {
 		return fmt.Errorf("failed to initialize storage: %w", err)
 	}
 
-	if m.OTelEndpoint != "" {
-		otelExp, otelErr := exporters.NewOTelExporter(context.Background(), exporters.OTelConfig{
-			Endpoint: m.OTelEndpoint,
-			Headers:  m.OTelHeaders,
-			Insecure: true,
-		})
+	// Service stats are sampled in the background; the API, /metrics and the sync loop read the snapshot.
+	sampleInterval := core.DefaultStatsSampleInterval
+	if m.StatsSampleInterval != "" {
+		interval, err := time.ParseDuration(m.StatsSampleInterval)
+		if err != nil || interval <= 0 {
+			return fmt.Errorf("[MoniGo] invalid stats sample interval %q", m.StatsSampleInterval)
+		}
+		sampleInterval = interval
+	}
+	core.StartStatsSampler(context.Background(), sampleInterval)
+
+	if m.ContinuousProfileCycle != "" || m.ContinuousProfileInterval != "" {
+		var cycle, interval time.Duration
+		if m.ContinuousProfileCycle != "" {
+			d, err := time.ParseDuration(m.ContinuousProfileCycle)
+			if err != nil || d <= 0 {
+				return fmt.Errorf("[MoniGo] invalid continuous profile cycle %q", m.ContinuousProfileCycle)
+			}
+			cycle = d
+		}
+		if m.ContinuousProfileInterval != "" {
+			d, err := time.ParseDuration(m.ContinuousProfileInterval)
+			if err != nil || d <= 0 {
+				return fmt.Errorf("[MoniGo] invalid continuous profile interval %q", m.ContinuousProfileInterval)
+			}
+			interval = d
+		}
+		core.StartContinuousProfiler(context.Background(), cycle, interval)
+	}
+
+	if err := timeseries.SetDataPointsSyncFrequency(m.DataPointsSyncFrequency); err != nil {
+		return fmt.Errorf("[MoniGo] failed to set data points sync frequency: %v", err)
+	}
+
+	if m.OTelEndpoint != "" || m.OTelOptions != nil {
+		otelCfg := exporters.OTelConfig{Insecure: true}
+		if m.OTelOptions != nil {
+			otelCfg = *m.OTelOptions
+		}
+		if otelCfg.Endpoint == "" {
+			otelCfg.Endpoint = m.OTelEndpoint
+		}
+		if otelCfg.Headers == nil {
+			otelCfg.Headers = m.OTelHeaders
+		}
+		otelExp, otelErr := exporters.NewOTelExporter(context.Background(), otelCfg)
 		if otelErr != nil {
 			logger.Log.Error("failed to initialize OTel exporter", "error", otelErr)
 		} else {
 			m.otelExporter = otelExp
-			logger.Log.Info("OTel exporter initialized", "endpoint", m.OTelEndpoint)
+			logger.Log.Info("OTel exporter initialized", "endpoint", otelCfg.Endpoint, "protocol", common.DefaultIfEmpty(otelCfg.Protocol, exporters.OTelProtocolGRPC))
+		}
+
+		traceExp, traceErr := exporters.NewOTelTraceExporter(context.Background(), otelCfg)
+		if traceErr != nil {
+			logger.Log.Error("failed to initialize OTel trace exporter", "error", traceErr)
+		} else {
+			m.otelTraceExporter = traceExp
+			core.SetTracerProvider(traceExp.TracerProvider())
 		}
 	}
 
+	if m.RemoteWriteURL != "" {
+		host := timeseries.GetHostLabel()
+		rwExp, rwErr := exporters.NewRemoteWriteExporter(exporters.RemoteWriteConfig{
+			URL:            m.RemoteWriteURL,
+			Headers:        m.RemoteWriteHeaders,
+			ExternalLabels: map[string]string{"service": m.ServiceName, host.Name: host.Value},
+			QueueDir:       BasePath + "/remote_write",
+		})
+		if rwErr != nil {
+			logger.Log.Error("failed to initialize remote write exporter", "error", rwErr)
+		} else {
+			m.remoteWriteExporter = rwExp
+			logger.Log.Info("remote write exporter initialized", "url", m.RemoteWriteURL)
+		}
+	}
+
+	m.startPipelines(sampleInterval)
 	return nil
 }
 
+// startPipelines persists the custom metrics into storage at the data points sync
+// frequency and, when OTel or remote write is configured, pushes the system and custom
+// metrics to those exporters every exportInterval.
+func (m *Monigo) startPipelines(exportInterval time.Duration) {
+	syncInterval, err := time.ParseDuration(m.DataPointsSyncFrequency)
+	if err != nil || syncInterval <= 0 {
+		syncInterval = 5 * time.Minute
+	}
+	storage := exporters.NewStorageExporter()
+	m.pipelines = append(m.pipelines,
+		pipeline.NewPipeline(core.CustomMetrics(), storage, syncInterval),
+		pipeline.NewPipeline(core.FunctionStats(), storage, syncInterval),
+	)
+
+	var exps []exporter.Exporter
+	if m.otelExporter != nil {
+		exps = append(exps, m.otelExporter)
+	}
+	if m.remoteWriteExporter != nil {
+		exps = append(exps, m.remoteWriteExporter)
+	}
+	if len(exps) > 0 {
+		// The registry is only populated when something consumes it.
+		m.systemMetrics = registry.NewRegistry()
+		core.SetStatsRegistry(m.systemMetrics)
+		core.SetFunctionRegistry(m.systemMetrics)
+
+		fanOut := exporter.NewMultiExporter(exps...)
+		m.pipelines = append(m.pipelines,
+			pipeline.NewPipeline(m.systemMetrics, fanOut, exportInterval),
+			pipeline.NewPipeline(core.CustomMetrics(), fanOut, exportInterval),
+			pipeline.NewPipeline(core.FunctionStats(), fanOut, exportInterval),
+		)
+	}
+
+	for _, p := range m.pipelines {
+		p.Start(context.Background())
+	}
+}
+
 // Shutdown performs a graceful cleanup of resources (OTel provider, storage, etc.).
 func (m *Monigo) Shutdown(ctx context.Context) error {
+	core.StopStatsSampler()
+	core.StopContinuousProfiler()
+
 	var errs []error
+	// Flush what was recorded since the last tick before the exporters close.
+	for _, p := range m.pipelines {
+		p.Stop()
+		if err := p.Flush(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("pipeline flush: %w", err))
+		}
+	}
+	m.pipelines = nil
+	if m.systemMetrics != nil {
+		core.SetStatsRegistry(nil)
+		core.SetFunctionRegistry(nil)
+	}
 	if m.otelExporter != nil {
 		if err := m.otelExporter.Shutdown(ctx); err != nil {
 			errs = append(errs, fmt.Errorf("otel shutdown: %w", err))
 		}
 	}
+	if m.otelTraceExporter != nil {
+		core.SetTracerProvider(nil)
+		if err := m.otelTraceExporter.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("otel trace shutdown: %w", err))
+		}
+	}
+	if m.remoteWriteExporter != nil {
+		if err := m.remoteWriteExporter.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("remote write shutdown: %w", err))
+		}
+	}
 	if err := timeseries.CloseStorage(); err != nil {
 		errs = append(errs, fmt.Errorf("storage close: %w", err))
 	}
@@ -315,6 +479,32 @@ func TraceFunctionWithReturns(ctx context.Context, f interface{}, args ...interf
 	return core.TraceFunctionWithReturns(ctx, f, args...)
 }
 
+// Trace traces fn under an explicit name and returns its results, with no reflection or
+// type assertions. fn receives the context of the call's span; a non-nil error counts
+// as a failed call.
+//
+//	total, err := monigo.Trace(ctx, "checkout.total", func(ctx context.Context) (float64, error) {
+//		return calculateTotal(ctx, items)
+//	})
+func Trace[T any](ctx context.Context, name string, fn func(context.Context) (T, error)) (T, error) {
+	var result T
+	err := core.TraceNamed(ctx, name, func(ctx context.Context) error {
+		var err error
+		result, err = fn(ctx)
+		return err
+	})
+	return result, err
+}
+
+// Start starts a traced call of the given name, recorded when the returned function is
+// called. Defer it so panics are recorded too:
+//
+//	done := monigo.Start(ctx, "checkout")
+//	defer done()
+func Start(ctx context.Context, name string) func() {
+	return core.StartFunction(ctx, name)
+}
+
 // StartDashboard starts the dashboard on the specified port
 func StartDashboard(port int) error {
 	m := &Monigo{}
@@ -414,7 +604,15 @@ func registerAPIEndpoints(mux *http.ServeMux, apiPath string) {
 	mux.HandleFunc(fmt.Sprintf("%s/function", apiPath), api.GetFunctionTraceDetails)
 	mux.HandleFunc(fmt.Sprintf("%s/function-details", apiPath), api.ViewFunctionMetrics)
 	mux.HandleFunc("/metrics", api.PrometheusMetricsHandler)
+	mux.HandleFunc("/api/v1/read", api.RemoteReadHandler)
 	mux.HandleFunc(fmt.Sprintf("%s/reports", apiPath), api.GetReportData)
+	mux.HandleFunc(fmt.Sprintf("%s/query", apiPath), api.QueryMetrics)
+	mux.HandleFunc(fmt.Sprintf("%s/custom-metrics", apiPath), api.GetCustomMetrics)
+	mux.HandleFunc(fmt.Sprintf("%s/profiles", apiPath), api.GetStoredProfiles)
+	mux.HandleFunc(fmt.Sprintf("%s/flamegraph", apiPath), api.GetFlameGraph)
+	mux.HandleFunc(fmt.Sprintf("%s/process-profiles", apiPath), api.ProcessProfiles)
+	mux.HandleFunc(fmt.Sprintf("%s/process-profiles/download", apiPath), api.DownloadProcessProfile)
+	mux.HandleFunc(fmt.Sprintf("%s/continuous-profiles", apiPath), api.ContinuousProfiles)
 }
 
 // RegisterDashboardHandlers registers all dashboard handlers to the provided HTTP mux
@@ -471,8 +669,16 @@ func GetAPIHandlers(customBaseAPIPath ...string) map[string]http.HandlerFunc {
 		fmt.Sprintf("%s/go-routines-stats", apiPath): api.GetGoRoutinesStats,
 		fmt.Sprintf("%s/function", apiPath):          api.GetFunctionTraceDetails,
 		fmt.Sprintf("%s/function-details", apiPath):  api.ViewFunctionMetrics,
-		"/metrics":                                   api.PrometheusMetricsHandler,
-		fmt.Sprintf("%s/reports", apiPath):           api.GetReportData,
+		"/metrics":                                           api.PrometheusMetricsHandler,
+		"/api/v1/read":                                       api.RemoteReadHandler,
+		fmt.Sprintf("%s/reports", apiPath):                   api.GetReportData,
+		fmt.Sprintf("%s/query", apiPath):                     api.QueryMetrics,
+		fmt.Sprintf("%s/custom-metrics", apiPath):            api.GetCustomMetrics,
+		fmt.Sprintf("%s/profiles", apiPath):                  api.GetStoredProfiles,
+		fmt.Sprintf("%s/flamegraph", apiPath):                api.GetFlameGraph,
+		fmt.Sprintf("%s/process-profiles", apiPath):          api.ProcessProfiles,
+		fmt.Sprintf("%s/process-profiles/download", apiPath): api.DownloadProcessProfile,
+		fmt.Sprintf("%s/continuous-profiles", apiPath):       api.ContinuousProfiles,
 	}
 }
 
@@ -545,8 +751,16 @@ func GetSecuredAPIHandlers(m *Monigo, customBaseAPIPath ...string) map[string]ht
 		fmt.Sprintf("%s/go-routines-stats", apiPath): api.GetGoRoutinesStats,
 		fmt.Sprintf("%s/function", apiPath):          api.GetFunctionTraceDetails,
 		fmt.Sprintf("%s/function-details", apiPath):  api.ViewFunctionMetrics,
-		"/metrics":                                   api.PrometheusMetricsHandler,
-		fmt.Sprintf("%s/reports", apiPath):           api.GetReportData,
+		"/metrics":                                           api.PrometheusMetricsHandler,
+		"/api/v1/read":                                       api.RemoteReadHandler,
+		fmt.Sprintf("%s/reports", apiPath):                   api.GetReportData,
+		fmt.Sprintf("%s/query", apiPath):                     api.QueryMetrics,
+		fmt.Sprintf("%s/custom-metrics", apiPath):            api.GetCustomMetrics,
+		fmt.Sprintf("%s/profiles", apiPath):                  api.GetStoredProfiles,
+		fmt.Sprintf("%s/flamegraph", apiPath):                api.GetFlameGraph,
+		fmt.Sprintf("%s/process-profiles", apiPath):          api.ProcessProfiles,
+		fmt.Sprintf("%s/process-profiles/download", apiPath): api.DownloadProcessProfile,
+		fmt.Sprintf("%s/continuous-profiles", apiPath):       api.ContinuousProfiles,
 	}
 
 	securedHandlers := make(map[string]http.HandlerFunc)
@@ -606,6 +820,20 @@ func routeToAPIHandler(w http.ResponseWriter, r *http.Request, apiPath string) {
 		api.ViewFunctionMetrics(w, r)
 	case path == fmt.Sprintf("%s/reports", apiPath):
 		api.GetReportData(w, r)
+	case path == fmt.Sprintf("%s/query", apiPath):
+		api.QueryMetrics(w, r)
+	case path == fmt.Sprintf("%s/custom-metrics", apiPath):
+		api.GetCustomMetrics(w, r)
+	case path == fmt.Sprintf("%s/profiles", apiPath):
+		api.GetStoredProfiles(w, r)
+	case path == fmt.Sprintf("%s/flamegraph", apiPath):
+		api.GetFlameGraph(w, r)
+	case path == fmt.Sprintf("%s/process-profiles", apiPath):
+		api.ProcessProfiles(w, r)
+	case path == fmt.Sprintf("%s/process-profiles/download", apiPath):
+		api.DownloadProcessProfile(w, r)
+	case path == fmt.Sprintf("%s/continuous-profiles", apiPath):
+		api.ContinuousProfiles(w, r)
 	default:
 		http.NotFound(w, r)
 	}
@@ -627,6 +855,20 @@ func routeToFiberAPIHandler(c *fiber.Ctx, path, apiPath string) error {
 		return handleFiberAPI(c, api.ViewFunctionMetrics)
 	case path == fmt.Sprintf("%s/reports", apiPath):
 		return handleFiberAPI(c, api.GetReportData)
+	case path == fmt.Sprintf("%s/query", apiPath):
+		return handleFiberAPI(c, api.QueryMetrics)
+	case path == fmt.Sprintf("%s/custom-metrics", apiPath):
+		return handleFiberAPI(c, api.GetCustomMetrics)
+	case path == fmt.Sprintf("%s/profiles", apiPath):
+		return handleFiberAPI(c, api.GetStoredProfiles)
+	case path == fmt.Sprintf("%s/flamegraph", apiPath):
+		return handleFiberAPI(c, api.GetFlameGraph)
+	case path == fmt.Sprintf("%s/process-profiles", apiPath):
+		return handleFiberAPI(c, api.ProcessProfiles)
+	case path == fmt.Sprintf("%s/process-profiles/download", apiPath):
+		return handleFiberAPI(c, api.DownloadProcessProfile)
+	case path == fmt.Sprintf("%s/continuous-profiles", apiPath):
+		return handleFiberAPI(c, api.ContinuousProfiles)
 	default:
 		c.Status(404).SendString("Not Found")
 		return nil
@@ -639,7 +881,7 @@ func handleFiberAPI(c *fiber.Ctx, handler func(http.ResponseWriter, *http.Reques
 
 	req, err := http.NewRequest(
 		string(c.Request().Header.Method()),
-		"http://localhost"+string(c.Request().URI().Path()),
+		"http://localhost"+string(c.Request().URI().RequestURI()),
 		strings.NewReader(string(body)),
 	)
 	if err != nil {
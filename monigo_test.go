@@ -0,0 +1,107 @@
+package monigo
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/iyashjayesh/monigo/core"
+	"github.com/iyashjayesh/monigo/exporters"
+	"github.com/iyashjayesh/monigo/internal/prompb"
+	"github.com/iyashjayesh/monigo/timeseries"
+	"github.com/klauspost/compress/snappy"
+)
+
+func TestPipelinesExportSystemAndCustomMetrics(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		names = map[string]bool{}
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		compressed, _ := io.ReadAll(r.Body)
+		raw, _ := snappy.Decode(nil, compressed)
+		var wr prompb.WriteRequest
+		if err := wr.Unmarshal(raw); err != nil {
+			t.Errorf("failed to decode write request: %v", err)
+		}
+		mu.Lock()
+		for _, ts := range wr.Timeseries {
+			for _, l := range ts.Labels {
+				if l.Name == "__name__" {
+					names[l.Value] = true
+				}
+			}
+		}
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	rw, err := exporters.NewRemoteWriteExporter(exporters.RemoteWriteConfig{URL: srv.URL})
+	if err != nil {
+		t.Fatalf("NewRemoteWriteExporter() error = %v", err)
+	}
+	timeseries.SetStorageType("memory")
+	m := &Monigo{ServiceName: "test-service", DataPointsSyncFrequency: "1h", remoteWriteExporter: rw}
+	m.startPipelines(10 * time.Millisecond)
+	core.StartStatsSampler(context.Background(), 10*time.Millisecond)
+
+	NewCounter("test_pipeline_orders_total", nil).Inc()
+	defer core.CustomMetrics().Delete("test_pipeline_orders_total")
+	core.TraceFunction(context.Background(), func() {})
+
+	deadline := time.Now().Add(10 * time.Second)
+	for m.systemMetrics.SeriesCount("monigo_goroutines_count") == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := m.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, name := range []string{"monigo_goroutines_count", "monigo_function_calls_total", "test_pipeline_orders_total"} {
+		if !names[name] {
+			t.Errorf("expected %s to be remote-written, got %v", name, names)
+		}
+	}
+}
+
+func TestTrace(t *testing.T) {
+	total, err := Trace(context.Background(), "test.trace.total", func(context.Context) (float64, error) {
+		return 42.5, nil
+	})
+	if err != nil || total != 42.5 {
+		t.Errorf("expected 42.5, got %v, %v", total, err)
+	}
+
+	_, err = Trace(context.Background(), "test.trace.total", func(context.Context) (string, error) {
+		return "", errors.New("no items")
+	})
+	if err == nil || err.Error() != "no items" {
+		t.Errorf("expected the function's error, got %v", err)
+	}
+
+	m := core.FunctionTraceDetails()["test.trace.total"]
+	if m == nil || m.CallCount != 2 || m.ErrorCount != 1 {
+		t.Errorf("expected two calls with one error, got %+v", m)
+	}
+}
+
+func TestStart(t *testing.T) {
+	func() {
+		done := Start(context.Background(), "test.start")
+		defer done()
+		time.Sleep(time.Millisecond)
+	}()
+
+	m := core.FunctionTraceDetails()["test.start"]
+	if m == nil || m.CallCount != 1 || m.ExecutionTime < time.Millisecond {
+		t.Errorf("expected one call of at least 1ms, got %+v", m)
+	}
+}
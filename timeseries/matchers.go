@@ -0,0 +1,135 @@
+package timeseries
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// MatchType is the comparison a LabelMatcher applies to a label value.
+type MatchType int
+
+// Supported label match types, mirroring Prometheus selectors (=, !=, =~, !~).
+const (
+	MatchEqual MatchType = iota
+	MatchNotEqual
+	MatchRegexp
+	MatchNotRegexp
+)
+
+// String returns the selector operator for the match type.
+func (t MatchType) String() string {
+	switch t {
+	case MatchNotEqual:
+		return "!="
+	case MatchRegexp:
+		return "=~"
+	case MatchNotRegexp:
+		return "!~"
+	default:
+		return "="
+	}
+}
+
+// LabelMatcher selects series by comparing one label against a value or anchored regexp.
+// A label that is not present on a series is treated as having an empty value.
+type LabelMatcher struct {
+	Type  MatchType
+	Name  string
+	Value string
+	re    *regexp.Regexp
+}
+
+// NewLabelMatcher builds a matcher, compiling Value for the regexp match types.
+func NewLabelMatcher(t MatchType, name, value string) (LabelMatcher, error) {
+	m := LabelMatcher{Type: t, Name: name, Value: value}
+	if t == MatchRegexp || t == MatchNotRegexp {
+		re, err := regexp.Compile("^(?:" + value + ")$")
+		if err != nil {
+			return LabelMatcher{}, fmt.Errorf("invalid regexp for label %q: %w", name, err)
+		}
+		m.re = re
+	}
+	return m, nil
+}
+
+// Matches reports whether the label value v satisfies the matcher.
+func (m LabelMatcher) Matches(v string) bool {
+	switch m.Type {
+	case MatchNotEqual:
+		return v != m.Value
+	case MatchRegexp:
+		return m.re != nil && m.re.MatchString(v)
+	case MatchNotRegexp:
+		return m.re == nil || !m.re.MatchString(v)
+	default:
+		return v == m.Value
+	}
+}
+
+// String formats the matcher as it would appear in a selector.
+func (m LabelMatcher) String() string {
+	return fmt.Sprintf("%s%s%q", m.Name, m.Type, m.Value)
+}
+
+// SelectMatching returns every series of metric within [start, end] whose labels satisfy
+// all matchers. Storages implementing SeriesSelector, both built-in backends included,
+// are asked for all candidate series; other backends can only be looked up by an exact
+// label set, so the equality matchers - or the host label when there are none - are used
+// as that set.
+func SelectMatching(metric string, matchers []LabelMatcher, start, end int64) ([]Series, error) {
+	sto, err := GetStorageInstance()
+	if err != nil {
+		return nil, fmt.Errorf("error getting storage instance: %w", err)
+	}
+
+	var equal []Label
+	for _, m := range matchers {
+		if m.Type == MatchEqual && m.Value != "" {
+			equal = append(equal, Label{Name: m.Name, Value: m.Value})
+		}
+	}
+
+	var candidates []Series
+	if ss, ok := sto.(SeriesSelector); ok {
+		candidates, err = ss.SelectSeries(metric, equal, start, end)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		if len(equal) == 0 {
+			equal = []Label{GetHostLabel()}
+		}
+		points, err := sto.Select(metric, equal, start, end)
+		if err != nil {
+			return nil, err
+		}
+		if len(points) > 0 {
+			candidates = []Series{{Metric: metric, Labels: canonicalLabels(equal), Points: points}}
+		}
+	}
+
+	result := candidates[:0]
+	for _, s := range candidates {
+		if seriesMatches(s.Labels, matchers) {
+			result = append(result, s)
+		}
+	}
+	return result, nil
+}
+
+// seriesMatches reports whether labels satisfy every matcher.
+func seriesMatches(labels []Label, matchers []LabelMatcher) bool {
+	for _, m := range matchers {
+		var value string
+		for _, l := range labels {
+			if l.Name == m.Name {
+				value = l.Value
+				break
+			}
+		}
+		if !m.Matches(value) {
+			return false
+		}
+	}
+	return true
+}
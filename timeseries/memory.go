@@ -0,0 +1,303 @@
+package timeseries
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// bytesPerPoint is the approximate in-memory footprint of a DataPoint (int64 + float64).
+const bytesPerPoint = 16
+
+// InMemoryOption configures an InMemoryStorage.
+type InMemoryOption func(*InMemoryStorage)
+
+// WithMemoryRetention drops points older than the given period on every compaction.
+func WithMemoryRetention(retention time.Duration) InMemoryOption {
+	return func(s *InMemoryStorage) {
+		s.retention = retention
+	}
+}
+
+// WithMaxPointsPerSeries caps every series at n points, evicting the oldest first.
+func WithMaxPointsPerSeries(n int) InMemoryOption {
+	return func(s *InMemoryStorage) {
+		s.maxPointsPerSeries = n
+	}
+}
+
+// WithMaxBytes caps the approximate size of all stored points, evicting the oldest points first.
+func WithMaxBytes(n int64) InMemoryOption {
+	return func(s *InMemoryStorage) {
+		s.maxBytes = n
+	}
+}
+
+// memSeries holds the points of a single series, kept sorted by timestamp.
+type memSeries struct {
+	metric string
+	labels []Label
+	points []DataPoint
+}
+
+// InMemoryStorage provides an in-memory implementation of the Storage interface.
+// Series are identified by metric name plus their canonical label set.
+// Timestamps are Unix seconds, matching what StoreServiceMetrics writes.
+type InMemoryStorage struct {
+	mu          sync.RWMutex
+	series      map[string]*memSeries   // keyed by seriesKey(metric, labels)
+	byMetric    map[string][]*memSeries // index of series per metric name
+	totalPoints int
+
+	retention          time.Duration
+	maxPointsPerSeries int
+	maxBytes           int64
+}
+
+// NewInMemoryStorage creates an empty in-memory storage. Without options it is unbounded.
+func NewInMemoryStorage(opts ...InMemoryOption) *InMemoryStorage {
+	s := &InMemoryStorage{
+		series:   make(map[string]*memSeries),
+		byMetric: make(map[string][]*memSeries),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// InsertRows inserts rows, creating a new series for every unseen metric/label combination.
+func (s *InMemoryStorage) InsertRows(rows []Row) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, row := range rows {
+		labels := canonicalLabels(row.Labels)
+		key := seriesKey(row.Metric, labels)
+		ser, ok := s.series[key]
+		if !ok {
+			ser = &memSeries{metric: row.Metric, labels: labels}
+			s.series[key] = ser
+			s.byMetric[row.Metric] = append(s.byMetric[row.Metric], ser)
+		}
+		ser.insert(row.DataPoint)
+		s.totalPoints++
+
+		if s.maxPointsPerSeries > 0 && len(ser.points) > s.maxPointsPerSeries {
+			s.dropOldest(ser, len(ser.points)-s.maxPointsPerSeries)
+		}
+	}
+	s.enforceMaxBytes()
+	return nil
+}
+
+// Select returns the points of every series of metric whose labels contain all of
+// the given labels, merged and sorted by timestamp. A nil label set matches all series.
+func (s *InMemoryStorage) Select(metric string, labels []Label, start, end int64) ([]DataPoint, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []DataPoint
+	matched := 0
+	for _, ser := range s.byMetric[metric] {
+		if !matchLabels(ser.labels, labels) {
+			continue
+		}
+		result = append(result, ser.rangeOf(start, end)...)
+		matched++
+	}
+	if matched > 1 {
+		sort.SliceStable(result, func(i, j int) bool { return result[i].Timestamp < result[j].Timestamp })
+	}
+	return result, nil
+}
+
+// SelectSeries is like Select but keeps every matching series separate, along with its labels.
+func (s *InMemoryStorage) SelectSeries(metric string, labels []Label, start, end int64) ([]Series, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []Series
+	for _, ser := range s.byMetric[metric] {
+		if !matchLabels(ser.labels, labels) {
+			continue
+		}
+		points := ser.rangeOf(start, end)
+		if len(points) == 0 {
+			continue
+		}
+		result = append(result, Series{
+			Metric: ser.metric,
+			Labels: append([]Label(nil), ser.labels...),
+			Points: points,
+		})
+	}
+	return result, nil
+}
+
+// Close is a no-op for the in-memory storage.
+func (s *InMemoryStorage) Close() error {
+	return nil
+}
+
+// Compact removes points that fall outside the retention period and returns how many were dropped.
+func (s *InMemoryStorage) Compact(now time.Time) int {
+	if s.retention <= 0 {
+		return 0
+	}
+	cutoff := now.Add(-s.retention).Unix()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := 0
+	for _, ser := range s.series {
+		n := sort.Search(len(ser.points), func(i int) bool { return ser.points[i].Timestamp >= cutoff })
+		if n > 0 {
+			s.dropOldest(ser, n)
+			removed += n
+		}
+	}
+	return removed
+}
+
+// StartCompaction runs Compact every interval until ctx is cancelled.
+func (s *InMemoryStorage) StartCompaction(ctx context.Context, interval time.Duration) {
+	if s.retention <= 0 || interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				s.Compact(now)
+			}
+		}
+	}()
+}
+
+// Stats returns the number of series and points currently held.
+func (s *InMemoryStorage) Stats() (series, points int) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.series), s.totalPoints
+}
+
+// enforceMaxBytes evicts the globally oldest points until the byte budget is met.
+// Callers must hold s.mu.
+func (s *InMemoryStorage) enforceMaxBytes() {
+	if s.maxBytes <= 0 {
+		return
+	}
+	for int64(s.totalPoints)*bytesPerPoint > s.maxBytes {
+		var oldest *memSeries
+		for _, ser := range s.series {
+			if oldest == nil || ser.points[0].Timestamp < oldest.points[0].Timestamp {
+				oldest = ser
+			}
+		}
+		if oldest == nil {
+			return
+		}
+		s.dropOldest(oldest, 1)
+	}
+}
+
+// dropOldest removes the first n points of ser, deleting the series once it is empty.
+// Callers must hold s.mu.
+func (s *InMemoryStorage) dropOldest(ser *memSeries, n int) {
+	if n >= len(ser.points) {
+		s.totalPoints -= len(ser.points)
+		s.removeSeries(ser)
+		return
+	}
+	ser.points = ser.points[n:]
+	s.totalPoints -= n
+}
+
+// removeSeries unlinks ser from both indexes. Callers must hold s.mu.
+func (s *InMemoryStorage) removeSeries(ser *memSeries) {
+	delete(s.series, seriesKey(ser.metric, ser.labels))
+	list := s.byMetric[ser.metric]
+	for i, other := range list {
+		if other == ser {
+			list = append(list[:i], list[i+1:]...)
+			break
+		}
+	}
+	if len(list) == 0 {
+		delete(s.byMetric, ser.metric)
+	} else {
+		s.byMetric[ser.metric] = list
+	}
+}
+
+// insert adds p keeping points sorted; in-order appends take the fast path.
+func (ser *memSeries) insert(p DataPoint) {
+	n := len(ser.points)
+	if n == 0 || ser.points[n-1].Timestamp <= p.Timestamp {
+		ser.points = append(ser.points, p)
+		return
+	}
+	i := sort.Search(n, func(i int) bool { return ser.points[i].Timestamp > p.Timestamp })
+	ser.points = append(ser.points, DataPoint{})
+	copy(ser.points[i+1:], ser.points[i:])
+	ser.points[i] = p
+}
+
+// rangeOf returns a copy of the points within [start, end] using binary search.
+func (ser *memSeries) rangeOf(start, end int64) []DataPoint {
+	lo := sort.Search(len(ser.points), func(i int) bool { return ser.points[i].Timestamp >= start })
+	hi := sort.Search(len(ser.points), func(i int) bool { return ser.points[i].Timestamp > end })
+	if lo >= hi {
+		return nil
+	}
+	return append([]DataPoint(nil), ser.points[lo:hi]...)
+}
+
+// canonicalLabels returns a copy of labels sorted by name so equal sets compare equal.
+func canonicalLabels(labels []Label) []Label {
+	out := append([]Label(nil), labels...)
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// seriesKey builds the identity of a series from its metric and canonical labels.
+func seriesKey(metric string, labels []Label) string {
+	var b strings.Builder
+	b.WriteString(metric)
+	b.WriteByte('{')
+	for i, l := range labels {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(l.Name)
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(l.Value))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// matchLabels reports whether have contains every label in want.
+func matchLabels(have, want []Label) bool {
+	for _, w := range want {
+		found := false
+		for _, h := range have {
+			if h.Name == w.Name {
+				found = h.Value == w.Value
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -2,10 +2,12 @@ package timeseries
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -23,67 +25,171 @@ type Storage interface {
 	Close() error
 }
 
-// InMemoryStorage provides an in-memory implementation of the Storage interface.
-type InMemoryStorage struct {
-	mu   sync.RWMutex
-	data map[string][]DataPoint
+// SeriesSelector is implemented by storages that can return each matching series
+// separately together with its full label set.
+type SeriesSelector interface {
+	SelectSeries(metric string, labels []Label, start, end int64) ([]Series, error)
 }
 
-func NewInMemoryStorage() *InMemoryStorage {
-	return &InMemoryStorage{
-		data: make(map[string][]DataPoint),
+// seriesIndexFile, in the data directory, lists the label sets of the series stored on
+// disk: tstorage can only look a series up by its exact labels.
+const seriesIndexFile = "series.json"
+
+// StorageWrapper wraps the tstorage.Storage to implement the Storage interface.
+type StorageWrapper struct {
+	storage tstorage.Storage
+	closed  bool
+	mu      sync.Mutex
+
+	indexMu   sync.RWMutex
+	series    map[string]map[string][]Label // label sets by metric, keyed by seriesKey
+	indexPath string
+}
+
+// newStorageWrapper wraps storage, loading the series index kept at indexPath.
+func newStorageWrapper(storage tstorage.Storage, indexPath string) *StorageWrapper {
+	s := &StorageWrapper{
+		storage:   storage,
+		series:    make(map[string]map[string][]Label),
+		indexPath: indexPath,
 	}
+	s.loadIndex()
+	return s
 }
 
-func (s *InMemoryStorage) InsertRows(rows []Row) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	for _, row := range rows {
-		s.data[row.Metric] = append(s.data[row.Metric], row.DataPoint)
+// InsertRows inserts rows into the storage, converting monigo types to tstorage types.
+func (s *StorageWrapper) InsertRows(rows []Row) error {
+	if err := s.storage.InsertRows(toTStorageRows(rows)); err != nil {
+		return err
 	}
+	s.indexRows(rows)
 	return nil
 }
 
-func (s *InMemoryStorage) Select(metric string, labels []Label, start, end int64) ([]DataPoint, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	points, ok := s.data[metric]
-	if !ok {
-		return nil, nil
+// SelectSeries returns every indexed series of metric carrying all the given labels.
+// Metrics written before the index existed are looked up by labels as an exact set.
+func (s *StorageWrapper) SelectSeries(metric string, labels []Label, start, end int64) ([]Series, error) {
+	s.indexMu.RLock()
+	var candidates [][]Label
+	for _, ls := range s.series[metric] {
+		if matchLabels(ls, labels) {
+			candidates = append(candidates, ls)
+		}
 	}
+	indexed := len(s.series[metric]) > 0
+	s.indexMu.RUnlock()
 
-	var result []DataPoint
-	for _, p := range points {
-		if p.Timestamp >= start && p.Timestamp <= end {
-			result = append(result, p)
+	if !indexed {
+		if len(labels) == 0 {
+			labels = []Label{GetHostLabel()}
+		}
+		candidates = [][]Label{indexLabels(labels)}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return seriesKey(metric, candidates[i]) < seriesKey(metric, candidates[j])
+	})
+
+	var result []Series
+	for _, ls := range candidates {
+		points, err := s.Select(metric, ls, start, end)
+		if errors.Is(err, tstorage.ErrNoDataPoints) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(points) > 0 {
+			result = append(result, Series{Metric: metric, Labels: append([]Label(nil), ls...), Points: points})
 		}
 	}
 	return result, nil
 }
 
-func (s *InMemoryStorage) Close() error {
-	return nil
+// Select retrieves data points from the storage, converting tstorage types to monigo types.
+func (s *StorageWrapper) Select(metric string, labels []Label, start, end int64) ([]DataPoint, error) {
+	points, err := s.storage.Select(metric, toTStorageLabels(labels), start, end)
+	if err != nil {
+		return nil, err
+	}
+	return fromTStorageDataPoints(points), nil
 }
 
-// StorageWrapper wraps the tstorage.Storage to implement the Storage interface.
-type StorageWrapper struct {
-	storage tstorage.Storage
-	closed  bool
-	mu      sync.Mutex
+// indexLabels returns labels as tstorage identifies a series: sorted by name, without
+// the labels with an empty name or value.
+func indexLabels(labels []Label) []Label {
+	out := make([]Label, 0, len(labels))
+	for _, l := range labels {
+		if l.Name != "" && l.Value != "" {
+			out = append(out, l)
+		}
+	}
+	return canonicalLabels(out)
 }
 
-// InsertRows inserts rows into the storage, converting monigo types to tstorage types.
-func (s *StorageWrapper) InsertRows(rows []Row) error {
-	return s.storage.InsertRows(toTStorageRows(rows))
+// indexRows adds the series of rows to the index, saving it when one is new.
+func (s *StorageWrapper) indexRows(rows []Row) {
+	s.indexMu.Lock()
+	defer s.indexMu.Unlock()
+
+	added := false
+	for _, row := range rows {
+		labels := indexLabels(row.Labels)
+		key := seriesKey(row.Metric, labels)
+		bySeries := s.series[row.Metric]
+		if bySeries == nil {
+			bySeries = make(map[string][]Label)
+			s.series[row.Metric] = bySeries
+		}
+		if _, ok := bySeries[key]; !ok {
+			bySeries[key] = labels
+			added = true
+		}
+	}
+	if added {
+		s.saveIndexLocked()
+	}
 }
 
-// Select retrieves data points from the storage, converting tstorage types to monigo types.
-func (s *StorageWrapper) Select(metric string, labels []Label, start, end int64) ([]DataPoint, error) {
-	points, err := s.storage.Select(metric, toTStorageLabels(labels), start, end)
+func (s *StorageWrapper) loadIndex() {
+	data, err := os.ReadFile(s.indexPath)
 	if err != nil {
-		return nil, err
+		if !errors.Is(err, os.ErrNotExist) {
+			logger.Log.Warn("failed to read series index", "error", err)
+		}
+		return
+	}
+	var index map[string][][]Label
+	if err := json.Unmarshal(data, &index); err != nil {
+		logger.Log.Warn("failed to parse series index", "error", err)
+		return
+	}
+	for metric, sets := range index {
+		bySeries := make(map[string][]Label, len(sets))
+		for _, labels := range sets {
+			labels = indexLabels(labels)
+			bySeries[seriesKey(metric, labels)] = labels
+		}
+		s.series[metric] = bySeries
+	}
+}
+
+func (s *StorageWrapper) saveIndexLocked() {
+	index := make(map[string][][]Label, len(s.series))
+	for metric, bySeries := range s.series {
+		for _, labels := range bySeries {
+			index[metric] = append(index[metric], labels)
+		}
+	}
+	data, err := json.Marshal(index)
+	if err == nil {
+		tmp := s.indexPath + ".tmp"
+		if err = os.WriteFile(tmp, data, 0o644); err == nil {
+			err = os.Rename(tmp, s.indexPath)
+		}
+	}
+	if err != nil {
+		logger.Log.Warn("failed to write series index", "error", err)
 	}
-	return fromTStorageDataPoints(points), nil
 }
 
 // Close closes the storage connection.
@@ -108,9 +214,16 @@ type storageManager struct {
 	mu        sync.Mutex
 }
 
+// memoryCompactionInterval is how often the in-memory backend enforces retention.
+const memoryCompactionInterval = time.Minute
+
 var (
 	manager     = &storageManager{}
 	storageType = "disk" // "disk" or "memory"
+
+	// Optional caps for the in-memory backend; zero means unbounded.
+	memoryMaxPointsPerSeries int
+	memoryMaxBytes           int64
 )
 
 // SetStorageType sets the storage type
@@ -118,13 +231,27 @@ func SetStorageType(t string) {
 	storageType = t
 }
 
+// SetMemoryLimits sets the per-series point cap and total byte budget used by the
+// "memory" storage type. Must be called before the storage is initialized.
+func SetMemoryLimits(maxPointsPerSeries int, maxBytes int64) {
+	memoryMaxPointsPerSeries = maxPointsPerSeries
+	memoryMaxBytes = maxBytes
+}
+
 // GetStorageInstance initializes and returns a Storage instance.
 func GetStorageInstance() (Storage, error) {
 	var err error
 	manager.once.Do(func() {
 		if storageType == "memory" {
-			manager.storage = NewInMemoryStorage()
+			retention := common.GetDataRetentionPeriod()
+			memStorage := NewInMemoryStorage(
+				WithMemoryRetention(retention),
+				WithMaxPointsPerSeries(memoryMaxPointsPerSeries),
+				WithMaxBytes(memoryMaxBytes),
+			)
+			manager.storage = memStorage
 			manager.ctx, manager.cancel = context.WithCancel(context.Background())
+			memStorage.StartCompaction(manager.ctx, min(memoryCompactionInterval, retention))
 			return
 		}
 
@@ -138,7 +265,7 @@ func GetStorageInstance() (Storage, error) {
 			logger.Log.Error("initializing storage", "error", err)
 			return
 		}
-		manager.storage = &StorageWrapper{storage: storageInstance}
+		manager.storage = newStorageWrapper(storageInstance, filepath.Join(basePath, "data", seriesIndexFile))
 		// Initialize context and cancel function for goroutines
 		manager.ctx, manager.cancel = context.WithCancel(context.Background())
 	})
@@ -204,7 +331,7 @@ func SetDataPointsSyncFrequency(frequency ...string) error {
 	}
 
 	// Initializing service metrics once
-	serviceMetrics := core.GetServiceStats(context.Background())
+	serviceMetrics := core.CachedServiceStats(context.Background())
 	if err := StoreServiceMetrics(&serviceMetrics); err != nil {
 		return errors.New("[MoniGo] error storing service metrics, err: " + err.Error())
 	}
@@ -217,7 +344,7 @@ func SetDataPointsSyncFrequency(frequency ...string) error {
 			case <-manager.ctx.Done():
 				return
 			case <-ticker.C:
-				serviceMetrics := core.GetServiceStats(manager.ctx)
+				serviceMetrics := core.CachedServiceStats(manager.ctx)
 				if err := StoreServiceMetrics(&serviceMetrics); err != nil {
 					logger.Log.Error("storing service metrics", "error", err)
 				}
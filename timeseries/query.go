@@ -0,0 +1,191 @@
+package timeseries
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Aggregation reduces the points of a bucket to a single value.
+type Aggregation string
+
+// Supported aggregations for Downsample and QueryRange.
+const (
+	AggAvg  Aggregation = "avg"
+	AggMin  Aggregation = "min"
+	AggMax  Aggregation = "max"
+	AggSum  Aggregation = "sum"
+	AggLast Aggregation = "last"
+	AggP50  Aggregation = "p50"
+	AggP95  Aggregation = "p95"
+	AggP99  Aggregation = "p99"
+	AggRate Aggregation = "rate" // per-second increase of a counter, tolerating resets
+)
+
+// DefaultMaxPoints is the number of buckets StepForRange aims for when no step is given.
+const DefaultMaxPoints = 300
+
+// ParseAggregation validates an aggregation name. An empty name defaults to AggAvg.
+func ParseAggregation(name string) (Aggregation, error) {
+	agg := Aggregation(strings.ToLower(strings.TrimSpace(name)))
+	switch agg {
+	case "":
+		return AggAvg, nil
+	case AggAvg, AggMin, AggMax, AggSum, AggLast, AggP50, AggP95, AggP99, AggRate:
+		return agg, nil
+	}
+	return "", fmt.Errorf("unknown aggregation %q", name)
+}
+
+// StepForRange returns a step (in seconds) that splits [start, end] into at most maxPoints buckets.
+func StepForRange(start, end int64, maxPoints int) int64 {
+	if maxPoints <= 0 {
+		maxPoints = DefaultMaxPoints
+	}
+	span := end - start
+	if span <= 0 {
+		return 1
+	}
+	step := int64(math.Ceil(float64(span) / float64(maxPoints)))
+	if step < 1 {
+		step = 1
+	}
+	return step
+}
+
+// QueryRange selects the points of metric within [start, end] and downsamples them into
+// step-sized buckets aligned to the Unix epoch, so repeated queries over a sliding
+// window produce stable buckets. A step <= 0 returns the raw points.
+func QueryRange(metric string, labels []Label, start, end, step int64, agg Aggregation) ([]DataPoint, error) {
+	points, err := GetDataPoints(metric, labels, start, end)
+	if err != nil {
+		return nil, err
+	}
+	if step <= 0 {
+		return points, nil
+	}
+	return Downsample(points, 0, step, agg), nil
+}
+
+// Downsample groups sorted points into buckets of step seconds aligned to origin and returns
+// one point per non-empty bucket, stamped with the bucket start time.
+func Downsample(points []DataPoint, origin, step int64, agg Aggregation) []DataPoint {
+	if step <= 0 || len(points) == 0 {
+		return points
+	}
+
+	var (
+		result []DataPoint
+		prev   *DataPoint // last point of the previous bucket, used by rate
+	)
+	for i := 0; i < len(points); {
+		bucket := bucketStart(points[i].Timestamp, origin, step)
+		j := i
+		for j < len(points) && bucketStart(points[j].Timestamp, origin, step) == bucket {
+			j++
+		}
+
+		if value, ok := aggregate(points[i:j], prev, agg); ok {
+			result = append(result, DataPoint{Timestamp: bucket, Value: value})
+		}
+		prev = &points[j-1]
+		i = j
+	}
+	return result
+}
+
+// bucketStart returns the start of the step-sized bucket containing ts.
+func bucketStart(ts, origin, step int64) int64 {
+	offset := ts - origin
+	if offset < 0 {
+		// Floor division for points before origin.
+		return origin + ((offset-step+1)/step)*step
+	}
+	return origin + (offset/step)*step
+}
+
+// aggregate applies agg to a non-empty bucket. It reports false when no value can be derived.
+func aggregate(points []DataPoint, prev *DataPoint, agg Aggregation) (float64, bool) {
+	switch agg {
+	case AggMin:
+		v := points[0].Value
+		for _, p := range points[1:] {
+			v = math.Min(v, p.Value)
+		}
+		return v, true
+	case AggMax:
+		v := points[0].Value
+		for _, p := range points[1:] {
+			v = math.Max(v, p.Value)
+		}
+		return v, true
+	case AggSum:
+		var v float64
+		for _, p := range points {
+			v += p.Value
+		}
+		return v, true
+	case AggLast:
+		return points[len(points)-1].Value, true
+	case AggP50:
+		return percentile(points, 0.50), true
+	case AggP95:
+		return percentile(points, 0.95), true
+	case AggP99:
+		return percentile(points, 0.99), true
+	case AggRate:
+		return rate(points, prev)
+	default:
+		var v float64
+		for _, p := range points {
+			v += p.Value
+		}
+		return v / float64(len(points)), true
+	}
+}
+
+// percentile returns the q-quantile of the bucket values using linear interpolation.
+func percentile(points []DataPoint, q float64) float64 {
+	values := make([]float64, len(points))
+	for i, p := range points {
+		values[i] = p.Value
+	}
+	sort.Float64s(values)
+
+	rank := q * float64(len(values)-1)
+	lo := int(math.Floor(rank))
+	hi := int(math.Ceil(rank))
+	if lo == hi {
+		return values[lo]
+	}
+	return values[lo] + (values[hi]-values[lo])*(rank-float64(lo))
+}
+
+// rate returns the per-second increase across the bucket, starting from the previous
+// bucket's last point when available. A decrease is treated as a counter reset.
+func rate(points []DataPoint, prev *DataPoint) (float64, bool) {
+	first := points[0]
+	rest := points[1:]
+	if prev != nil {
+		first = *prev
+		rest = points
+	}
+
+	var increase float64
+	last := first
+	for _, p := range rest {
+		if p.Value >= last.Value {
+			increase += p.Value - last.Value
+		} else {
+			increase += p.Value
+		}
+		last = p
+	}
+
+	elapsed := last.Timestamp - first.Timestamp
+	if elapsed <= 0 {
+		return 0, false
+	}
+	return increase / float64(elapsed), true
+}
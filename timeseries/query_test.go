@@ -0,0 +1,114 @@
+package timeseries
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseAggregation(t *testing.T) {
+	if agg, err := ParseAggregation(""); err != nil || agg != AggAvg {
+		t.Errorf("expected empty aggregation to default to avg, got %q, %v", agg, err)
+	}
+	if agg, err := ParseAggregation("P95"); err != nil || agg != AggP95 {
+		t.Errorf("expected p95, got %q, %v", agg, err)
+	}
+	if _, err := ParseAggregation("median"); err == nil {
+		t.Error("expected error for unknown aggregation")
+	}
+}
+
+func TestStepForRange(t *testing.T) {
+	if step := StepForRange(0, 7*24*3600, 300); step != 2016 {
+		t.Errorf("expected 2016s step for 7d, got %d", step)
+	}
+	if step := StepForRange(0, 60, 300); step != 1 {
+		t.Errorf("expected minimum step of 1s, got %d", step)
+	}
+}
+
+func TestDownsample(t *testing.T) {
+	points := []DataPoint{
+		{Timestamp: 0, Value: 1},
+		{Timestamp: 5, Value: 3},
+		{Timestamp: 10, Value: 10},
+		{Timestamp: 12, Value: 20},
+		{Timestamp: 19, Value: 30},
+		{Timestamp: 25, Value: 7},
+	}
+
+	tests := []struct {
+		agg  Aggregation
+		want []float64
+	}{
+		{AggAvg, []float64{2, 20, 7}},
+		{AggMin, []float64{1, 10, 7}},
+		{AggMax, []float64{3, 30, 7}},
+		{AggSum, []float64{4, 60, 7}},
+		{AggLast, []float64{3, 30, 7}},
+		{AggP50, []float64{2, 20, 7}},
+	}
+
+	for _, tt := range tests {
+		got := Downsample(points, 0, 10, tt.agg)
+		if len(got) != len(tt.want) {
+			t.Fatalf("%s: expected %d buckets, got %v", tt.agg, len(tt.want), got)
+		}
+		for i, p := range got {
+			if p.Timestamp != int64(i*10) {
+				t.Errorf("%s: bucket %d expected timestamp %d, got %d", tt.agg, i, i*10, p.Timestamp)
+			}
+			if math.Abs(p.Value-tt.want[i]) > 1e-9 {
+				t.Errorf("%s: bucket %d expected %f, got %f", tt.agg, i, tt.want[i], p.Value)
+			}
+		}
+	}
+}
+
+func TestDownsampleRateHandlesCounterReset(t *testing.T) {
+	points := []DataPoint{
+		{Timestamp: 0, Value: 100},
+		{Timestamp: 10, Value: 200},
+		{Timestamp: 20, Value: 50}, // reset
+		{Timestamp: 30, Value: 150},
+	}
+
+	got := Downsample(points, 0, 20, AggRate)
+	if len(got) != 2 {
+		t.Fatalf("expected 2 buckets, got %v", got)
+	}
+	// Bucket [0,20): 100 -> 200 over 10s.
+	if got[0].Value != 10 {
+		t.Errorf("expected rate 10/s, got %f", got[0].Value)
+	}
+	// Bucket [20,40) continues from 200: reset adds 50, then +100, over 20s.
+	if got[1].Value != 7.5 {
+		t.Errorf("expected rate 7.5/s, got %f", got[1].Value)
+	}
+}
+
+func TestQueryRangeDownsamplesStoredPoints(t *testing.T) {
+	SetStorageType("memory")
+	manager = &storageManager{}
+	defer CloseStorage()
+
+	sto, err := GetStorageInstance()
+	if err != nil {
+		t.Fatalf("GetStorageInstance error: %v", err)
+	}
+	for ts := int64(0); ts < 60; ts++ {
+		sto.InsertRows([]Row{{Metric: "m", DataPoint: DataPoint{Timestamp: ts, Value: float64(ts)}}})
+	}
+
+	points, err := QueryRange("m", nil, 0, 59, 30, AggMax)
+	if err != nil {
+		t.Fatalf("QueryRange error: %v", err)
+	}
+	if len(points) != 2 || points[0].Value != 29 || points[1].Value != 59 {
+		t.Errorf("expected two max buckets [29, 59], got %v", points)
+	}
+
+	raw, _ := QueryRange("m", nil, 0, 59, 0, AggAvg)
+	if len(raw) != 60 {
+		t.Errorf("expected raw points with step 0, got %d", len(raw))
+	}
+}
@@ -1,12 +1,14 @@
 package timeseries
 
 import (
+	"path/filepath"
 	"runtime"
 	"testing"
 	"time"
 
 	"github.com/iyashjayesh/monigo/common"
 	"github.com/iyashjayesh/monigo/models"
+	"github.com/nakabonne/tstorage"
 )
 
 func init() {
@@ -55,6 +57,124 @@ func TestInMemoryStorage_InsertAndSelect(t *testing.T) {
 	}
 }
 
+func TestInMemoryStorage_LabelsSeparateSeries(t *testing.T) {
+	s := NewInMemoryStorage()
+
+	now := time.Now().Unix()
+	rows := []Row{
+		{Metric: "cpu_load", DataPoint: DataPoint{Timestamp: now, Value: 10}, Labels: []Label{{Name: "host", Value: "a"}}},
+		{Metric: "cpu_load", DataPoint: DataPoint{Timestamp: now, Value: 20}, Labels: []Label{{Name: "host", Value: "b"}}},
+		{Metric: "cpu_load", DataPoint: DataPoint{Timestamp: now + 1, Value: 30}, Labels: []Label{{Name: "zone", Value: "eu"}, {Name: "host", Value: "a"}}},
+	}
+	if err := s.InsertRows(rows); err != nil {
+		t.Fatalf("InsertRows error: %v", err)
+	}
+
+	points, _ := s.Select("cpu_load", []Label{{Name: "host", Value: "b"}}, now-1, now+10)
+	if len(points) != 1 || points[0].Value != 20 {
+		t.Errorf("expected only host=b point, got %v", points)
+	}
+
+	// host=a matches both the plain series and the one with an extra zone label.
+	points, _ = s.Select("cpu_load", []Label{{Name: "host", Value: "a"}}, now-1, now+10)
+	if len(points) != 2 {
+		t.Errorf("expected 2 host=a points, got %v", points)
+	}
+
+	series, err := s.SelectSeries("cpu_load", nil, now-1, now+10)
+	if err != nil {
+		t.Fatalf("SelectSeries error: %v", err)
+	}
+	if len(series) != 3 {
+		t.Fatalf("expected 3 series, got %d", len(series))
+	}
+
+	// Label order must not create a new series.
+	s.InsertRows([]Row{{Metric: "cpu_load", DataPoint: DataPoint{Timestamp: now + 2, Value: 40}, Labels: []Label{{Name: "host", Value: "a"}, {Name: "zone", Value: "eu"}}}})
+	series, _ = s.SelectSeries("cpu_load", []Label{{Name: "zone", Value: "eu"}}, now-1, now+10)
+	if len(series) != 1 || len(series[0].Points) != 2 {
+		t.Errorf("expected a single zone=eu series with 2 points, got %v", series)
+	}
+
+	points, _ = s.Select("cpu_load", []Label{{Name: "host", Value: "missing"}}, now-1, now+10)
+	if len(points) != 0 {
+		t.Errorf("expected no points for unmatched label, got %v", points)
+	}
+}
+
+func TestInMemoryStorage_OutOfOrderInsert(t *testing.T) {
+	s := NewInMemoryStorage()
+	for _, ts := range []int64{50, 10, 30, 20, 40} {
+		s.InsertRows([]Row{{Metric: "m", DataPoint: DataPoint{Timestamp: ts, Value: float64(ts)}}})
+	}
+
+	points, _ := s.Select("m", nil, 15, 45)
+	want := []int64{20, 30, 40}
+	if len(points) != len(want) {
+		t.Fatalf("expected %d points, got %v", len(want), points)
+	}
+	for i, p := range points {
+		if p.Timestamp != want[i] {
+			t.Errorf("point %d: expected timestamp %d, got %d", i, want[i], p.Timestamp)
+		}
+	}
+}
+
+func TestInMemoryStorage_Retention(t *testing.T) {
+	s := NewInMemoryStorage(WithMemoryRetention(time.Hour))
+	now := time.Now()
+
+	s.InsertRows([]Row{
+		{Metric: "m", DataPoint: DataPoint{Timestamp: now.Add(-2 * time.Hour).Unix(), Value: 1}, Labels: []Label{{Name: "host", Value: "old"}}},
+		{Metric: "m", DataPoint: DataPoint{Timestamp: now.Add(-2 * time.Hour).Unix(), Value: 2}},
+		{Metric: "m", DataPoint: DataPoint{Timestamp: now.Add(-time.Minute).Unix(), Value: 3}},
+	})
+
+	if removed := s.Compact(now); removed != 2 {
+		t.Errorf("expected 2 points removed, got %d", removed)
+	}
+	series, points := s.Stats()
+	if series != 1 || points != 1 {
+		t.Errorf("expected 1 series with 1 point after compaction, got %d series, %d points", series, points)
+	}
+}
+
+func TestInMemoryStorage_MaxPointsPerSeries(t *testing.T) {
+	s := NewInMemoryStorage(WithMaxPointsPerSeries(3))
+	for ts := int64(1); ts <= 5; ts++ {
+		s.InsertRows([]Row{{Metric: "m", DataPoint: DataPoint{Timestamp: ts, Value: float64(ts)}}})
+	}
+
+	points, _ := s.Select("m", nil, 0, 10)
+	if len(points) != 3 || points[0].Timestamp != 3 {
+		t.Errorf("expected the 3 newest points, got %v", points)
+	}
+}
+
+func TestInMemoryStorage_MaxBytesEvictsOldestFirst(t *testing.T) {
+	s := NewInMemoryStorage(WithMaxBytes(4 * bytesPerPoint))
+	s.InsertRows([]Row{
+		{Metric: "a", DataPoint: DataPoint{Timestamp: 1, Value: 1}},
+		{Metric: "b", DataPoint: DataPoint{Timestamp: 2, Value: 2}},
+		{Metric: "a", DataPoint: DataPoint{Timestamp: 3, Value: 3}},
+		{Metric: "b", DataPoint: DataPoint{Timestamp: 4, Value: 4}},
+		{Metric: "b", DataPoint: DataPoint{Timestamp: 5, Value: 5}},
+		{Metric: "b", DataPoint: DataPoint{Timestamp: 6, Value: 6}},
+	})
+
+	if _, points := s.Stats(); points != 4 {
+		t.Fatalf("expected 4 points within budget, got %d", points)
+	}
+	a, _ := s.Select("a", nil, 0, 10)
+	if len(a) != 1 || a[0].Timestamp != 3 {
+		t.Errorf("expected only the newest 'a' point to survive, got %v", a)
+	}
+	b, _ := s.Select("b", nil, 0, 10)
+	if len(b) != 3 || b[0].Timestamp != 4 {
+		t.Errorf("expected 'b' points 4-6 to survive, got %v", b)
+	}
+}
+
 func TestInMemoryStorage_Close(t *testing.T) {
 	s := NewInMemoryStorage()
 	if err := s.Close(); err != nil {
@@ -149,3 +269,93 @@ func TestStoreAndRetrieveMetrics(t *testing.T) {
 	// Cleanup
 	CloseStorage()
 }
+
+func TestSelectMatching(t *testing.T) {
+	SetStorageType("memory")
+	manager = &storageManager{} // Reset singleton
+	defer CloseStorage()
+
+	sto, err := GetStorageInstance()
+	if err != nil {
+		t.Fatalf("GetStorageInstance error: %v", err)
+	}
+	now := time.Now().Unix()
+	sto.InsertRows([]Row{
+		{Metric: "http_requests", DataPoint: DataPoint{Timestamp: now, Value: 1}, Labels: []Label{{Name: "path", Value: "/api/users"}, {Name: "code", Value: "200"}}},
+		{Metric: "http_requests", DataPoint: DataPoint{Timestamp: now, Value: 2}, Labels: []Label{{Name: "path", Value: "/api/orders"}, {Name: "code", Value: "500"}}},
+		{Metric: "http_requests", DataPoint: DataPoint{Timestamp: now, Value: 3}, Labels: []Label{{Name: "path", Value: "/health"}}},
+	})
+
+	apiPaths, err := NewLabelMatcher(MatchRegexp, "path", "/api/.*")
+	if err != nil {
+		t.Fatalf("NewLabelMatcher error: %v", err)
+	}
+	notOK, _ := NewLabelMatcher(MatchNotEqual, "code", "200")
+
+	series, err := SelectMatching("http_requests", []LabelMatcher{apiPaths}, now-1, now+1)
+	if err != nil {
+		t.Fatalf("SelectMatching error: %v", err)
+	}
+	if len(series) != 2 {
+		t.Errorf("expected 2 /api series, got %d", len(series))
+	}
+
+	// A missing label counts as empty, so /health also satisfies code!="200".
+	series, _ = SelectMatching("http_requests", []LabelMatcher{notOK}, now-1, now+1)
+	if len(series) != 2 {
+		t.Errorf("expected 2 non-200 series, got %d", len(series))
+	}
+
+	series, _ = SelectMatching("http_requests", []LabelMatcher{apiPaths, notOK}, now-1, now+1)
+	if len(series) != 1 || series[0].Points[0].Value != 2 {
+		t.Errorf("expected only the /api/orders series, got %v", series)
+	}
+
+	if _, err := NewLabelMatcher(MatchRegexp, "path", "("); err == nil {
+		t.Error("expected error for invalid regexp")
+	}
+}
+
+func TestStorageWrapper_SelectSeries(t *testing.T) {
+	dir := t.TempDir()
+	open := func() *StorageWrapper {
+		t.Helper()
+		ts, err := tstorage.NewStorage(tstorage.WithDataPath(dir))
+		if err != nil {
+			t.Fatalf("NewStorage error: %v", err)
+		}
+		return newStorageWrapper(ts, filepath.Join(dir, seriesIndexFile))
+	}
+
+	sto := open()
+	now := time.Now().Unix()
+	if err := sto.InsertRows([]Row{
+		{Metric: "http_requests", DataPoint: DataPoint{Timestamp: now, Value: 1}, Labels: []Label{{Name: "path", Value: "/api/users"}, {Name: "code", Value: "200"}}},
+		{Metric: "http_requests", DataPoint: DataPoint{Timestamp: now, Value: 2}, Labels: []Label{{Name: "path", Value: "/api/orders"}, {Name: "code", Value: "500"}}},
+		{Metric: "http_requests", DataPoint: DataPoint{Timestamp: now, Value: 3}, Labels: []Label{{Name: "path", Value: "/health"}, {Name: "code", Value: ""}}},
+	}); err != nil {
+		t.Fatalf("InsertRows error: %v", err)
+	}
+
+	series, err := sto.SelectSeries("http_requests", nil, now-1, now+1)
+	if err != nil {
+		t.Fatalf("SelectSeries error: %v", err)
+	}
+	if len(series) != 3 {
+		t.Fatalf("expected every series of the metric, got %v", series)
+	}
+	series, _ = sto.SelectSeries("http_requests", []Label{{Name: "code", Value: "500"}}, now-1, now+1)
+	if len(series) != 1 || series[0].Points[0].Value != 2 || len(series[0].Labels) != 2 {
+		t.Errorf("expected only the /api/orders series, got %v", series)
+	}
+	if err := sto.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+
+	// The index outlives the process.
+	sto = open()
+	defer sto.Close()
+	if len(sto.series["http_requests"]) != 3 {
+		t.Errorf("expected the index reloaded, got %v", sto.series)
+	}
+}
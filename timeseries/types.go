@@ -21,6 +21,13 @@ type Row struct {
 	DataPoint DataPoint
 }
 
+// Series is a set of data points sharing a metric name and label set.
+type Series struct {
+	Metric string
+	Labels []Label
+	Points []DataPoint
+}
+
 // toTStorageLabels converts monigo Labels to tstorage Labels.
 func toTStorageLabels(labels []Label) []tstorage.Label {
 	out := make([]tstorage.Label, len(labels))
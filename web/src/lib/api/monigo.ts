@@ -39,6 +39,9 @@ export async function fetchServiceMetrics(data: {
 	timerange: string;
 	start_time: string;
 	end_time: string;
+	time_frame?: string;
+	step?: string;
+	aggregation?: string;
 }) {
 	const res = await fetch(getUrl('/service-metrics'), {
 		method: 'POST',
@@ -60,6 +63,8 @@ export async function fetchReports(data: {
 	start_time: string;
 	end_time: string;
 	time_frame: string;
+	step?: string;
+	aggregation?: string;
 }) {
 	const res = await fetch(getUrl('/reports'), {
 		method: 'POST',
@@ -76,10 +81,76 @@ export async function fetchFunctionTrace() {
 	return res.json();
 }
 
-export async function fetchFunctionDetails(name: string, reportType = 'text') {
-	const res = await fetch(getUrl(`/function-details?name=${encodeURIComponent(name)}&reportType=${reportType}`), {
+export async function fetchFunctionDetails(name: string, reportType = 'text', profile = '') {
+	const query = `name=${encodeURIComponent(name)}&reportType=${reportType}` + (profile ? `&profile=${encodeURIComponent(profile)}` : '');
+	const res = await fetch(getUrl(`/function-details?${query}`), {
 		headers: getAuthHeaders()
 	});
 	if (!res.ok) throw new Error(`Fetch failed: ${res.status}`);
 	return res.json();
 }
+
+export async function fetchStoredProfiles(name = '') {
+	const query = name ? `?name=${encodeURIComponent(name)}` : '';
+	const res = await fetch(getUrl(`/profiles${query}`), { headers: getAuthHeaders() });
+	if (!res.ok) throw new Error(`Fetch failed: ${res.status}`);
+	return res.json();
+}
+
+export async function fetchFlameGraph(params: {
+	name?: string;
+	type?: string;
+	profile?: string;
+	start?: string;
+	end?: string;
+	sample_type?: string;
+	format?: string;
+}) {
+	const query = new URLSearchParams();
+	for (const [k, v] of Object.entries(params)) {
+		if (v !== undefined && v !== '') query.set(k, String(v));
+	}
+	const res = await fetch(getUrl(`/flamegraph?${query}`), { headers: getAuthHeaders() });
+	if (!res.ok) throw new Error(`Fetch failed: ${res.status}`);
+	return params.format === 'collapsed' ? res.text() : res.json();
+}
+
+export async function fetchProcessProfiles() {
+	const res = await fetch(getUrl('/process-profiles'), { headers: getAuthHeaders() });
+	if (!res.ok) throw new Error(`Fetch failed: ${res.status}`);
+	return res.json();
+}
+
+export async function captureProcessProfile(type: string, seconds?: number) {
+	const query = `type=${encodeURIComponent(type)}` + (seconds ? `&seconds=${seconds}` : '');
+	const res = await fetch(getUrl(`/process-profiles?${query}`), {
+		method: 'POST',
+		headers: getAuthHeaders()
+	});
+	if (!res.ok) throw new Error(res.status === 409 ? 'The profiler is already in use' : `Capture failed: ${res.status}`);
+	return res.json();
+}
+
+export async function downloadProcessProfile(id: string) {
+	const res = await fetch(getUrl(`/process-profiles/download?id=${encodeURIComponent(id)}`), {
+		headers: getAuthHeaders()
+	});
+	if (!res.ok) throw new Error(`Download failed: ${res.status}`);
+	return res.blob();
+}
+
+export async function fetchCustomMetrics() {
+	const res = await fetch(getUrl('/custom-metrics'), { headers: getAuthHeaders() });
+	if (!res.ok) throw new Error(`Fetch failed: ${res.status}`);
+	return res.json();
+}
+
+export async function fetchQuery(data: { query: string; start?: string; end?: string; step?: string }) {
+	const res = await fetch(getUrl('/query'), {
+		method: 'POST',
+		headers: { 'Content-Type': 'application/json', ...getAuthHeaders() },
+		body: JSON.stringify(data)
+	});
+	if (!res.ok) throw new Error(`Fetch failed: ${res.status}`);
+	return res.json();
+}